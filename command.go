@@ -2,8 +2,14 @@ package ff
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+
+	"github.com/peterbourgon/ff/v4/ffval"
 )
 
 // Command is a declarative structure that combines a main function with a flag
@@ -16,6 +22,13 @@ type Command struct {
 	// Required.
 	Name string
 
+	// Aliases are alternative names for the command, which are also used for
+	// subcommand matching, e.g. "rm" for a command named "remove". Aliases are
+	// typically shown next to the command name in help text.
+	//
+	// Optional.
+	Aliases []string
+
 	// Usage is a single line string which should describe the syntax of the
 	// command, including flags and arguments. It's typically printed at the top
 	// of the help text for the command. For example,
@@ -50,6 +63,16 @@ type Command struct {
 	// Optional.
 	LongHelp string
 
+	// Footer is a string, usually a single line, which is printed at the end
+	// of the help text for the command, after all other sections. For example,
+	// "See https://example.com/docs for more information."
+	//
+	// Subcommands without a footer use the footer of their nearest ancestor,
+	// so a footer set on the root command applies to the whole command tree.
+	//
+	// Optional.
+	Footer string
+
 	// Flags is the set of flags associated with, and parsed by, this command.
 	//
 	// When building a command tree, it's often useful to allow flags defined by
@@ -63,16 +86,115 @@ type Command struct {
 
 	// Subcommands which are available underneath (i.e. after) this command.
 	// Selecting a subcommand is done via a case-insensitive comparison of the
-	// first post-parse argument to this command, against the name of each
-	// subcommand.
+	// first post-parse argument to this command, against the name and aliases
+	// of each subcommand. See MatchFunc to customize the comparison. Parse
+	// returns [ErrDuplicateCommand] if two subcommands share a name or alias.
 	//
 	// Optional.
 	Subcommands []*Command
 
-	isParsed bool
-	selected *Command
-	parent   *Command
-	args     []string
+	// MatchFunc reports whether the given name, typically the first post-parse
+	// argument to this command, selects the subcommand with the given candidate
+	// name. It's used to select one of this command's subcommands, and doesn't
+	// apply to the subcommands of those subcommands.
+	//
+	// Optional. If not provided, [strings.EqualFold] is used.
+	MatchFunc func(name, candidate string) bool
+
+	// SuggestDistance is the maximum edit distance between an unknown
+	// subcommand and the name or alias of a subcommand, for that name or alias
+	// to be suggested in the resulting error. If this command has subcommands
+	// but no Exec function, Parse returns an error when the first post-parse
+	// argument doesn't select a subcommand, which includes the closest
+	// suggestions, e.g. "did you mean status?".
+	//
+	// Optional. If zero, roughly one edit per three characters is allowed, with
+	// a minimum of 1 and a maximum of 3. If negative, no suggestions are made.
+	SuggestDistance int
+
+	// Hidden commands can be selected and run like any other command, but are
+	// omitted from help text by default. This can be useful for e.g. debug or
+	// internal commands which shouldn't be advertised to users.
+	//
+	// Optional.
+	Hidden bool
+
+	// RequireSubcommand indicates the command is only a group for its
+	// subcommands, and can't be run directly. If the command is selected as the
+	// terminal command during the parse phase, i.e. no subcommand was selected,
+	// then Run returns [ErrNoSubcommand], with a list of the available
+	// subcommands, rather than calling Exec.
+	//
+	// Optional.
+	RequireSubcommand bool
+
+	// DeprecatedBy is the name of a sibling command which replaces this one.
+	// If set, and this command is selected during the parse phase, a warning
+	// is written to the writer provided by [WithDeprecationWriter], which is
+	// os.Stderr by default.
+	//
+	// Optional.
+	DeprecatedBy string
+
+	// ForwardDeprecated tells the parent command to forward a deprecated
+	// command to its replacement. If set, and this command is selected, then
+	// the command named by DeprecatedBy is parsed and selected instead, with
+	// the same args. It's an error if the replacement doesn't exist.
+	//
+	// Optional. Only meaningful if DeprecatedBy is set.
+	ForwardDeprecated bool
+
+	// PrintUsageOnError tells Parse to write usage text for the selected
+	// command to UsageWriter when parsing fails with any error other than
+	// [ErrHelp]. It's only checked on the command whose Parse method is called,
+	// typically the root command.
+	//
+	// Optional.
+	PrintUsageOnError bool
+
+	// UsageFunc produces the usage text written when PrintUsageOnError is set.
+	// It's called with the selected command. Package ffhelp can be used to
+	// produce complete help text, e.g. via ffhelp.Command(cmd).String().
+	//
+	// Optional. If not provided, only the Usage string of the selected command
+	// is written, or its name if Usage is empty.
+	UsageFunc func(cmd *Command) string
+
+	// UsageWriter is where usage text is written when PrintUsageOnError is set.
+	//
+	// Optional. If not provided, os.Stderr is used.
+	UsageWriter io.Writer
+
+	// Version of the program, e.g. "v1.2.3". If set, and the command's flag set
+	// is a [FlagSet] without a flag named version, a --version flag is added to
+	// that flag set. If the command has subcommands, a version subcommand is
+	// also recognized, unless one of them already matches that name. If either
+	// is given, Parse writes the version to VersionWriter, and returns
+	// [ErrVersion]. Defining a --version flag explicitly overrides the built-in
+	// flag.
+	//
+	// Optional.
+	Version string
+
+	// VersionWriter is where the version is written when it's requested.
+	//
+	// Optional. If not provided, os.Stdout is used.
+	VersionWriter io.Writer
+
+	// Args describes the positional arguments expected by the command. If the
+	// command is selected as the terminal command during the parse phase, the
+	// args left over after parsing are bound to these specs, in order. See
+	// [ArgSpec] for details. Exec still receives all of the leftover args.
+	//
+	// Optional.
+	Args []ArgSpec
+
+	isParsed    bool
+	selected    *Command
+	parent      *Command
+	args        []string
+	versionFlag Flag
+	showVersion bool
 
 	// Exec is invoked by Run (or ParseAndRun) if this command was selected as
 	// the terminal command during the parse phase. The args passed to Exec are
@@ -80,13 +202,58 @@ type Command struct {
 	//
 	// Optional. If not provided, running this command will result in ErrNoExec.
 	Exec func(ctx context.Context, args []string) error
+
+	// PreRun is invoked by Run if this command was traversed during the parse
+	// phase, i.e. if it's the terminal command or one of its ancestors. PreRun
+	// functions are called top-down, from the root command to the terminal
+	// command, before the terminal command's Exec function. The args passed to
+	// PreRun are the args left over after parsing this command. If PreRun
+	// returns an error, Run stops, and returns that error, without calling
+	// Exec, or the PreRun functions of any subcommands.
+	//
+	// PreRun is useful for setup which depends on parsed flags, e.g. opening a
+	// database connection which is shared by every subcommand.
+	//
+	// Optional.
+	PreRun func(ctx context.Context, args []string) error
+
+	// PostRun is invoked by Run for every traversed command whose PreRun, if
+	// any, returned successfully. PostRun functions are called bottom-up, from
+	// the terminal command to the root command, after the terminal command's
+	// Exec function, even if Exec, or a descendant's PreRun or PostRun, returned
+	// an error. Any errors are combined via [errors.Join] and returned by Run.
+	//
+	// PostRun is useful for teardown of anything set up in PreRun.
+	//
+	// Optional.
+	PostRun func(ctx context.Context, args []string) error
 }
 
 // Parse the args and options against the defined command, which sets relevant
 // flags, traverses the command hierarchy to select a terminal command, and
 // captures the arguments that will be given to that command's exec function.
 // The args should not include the program name: pass os.Args[1:], not os.Args.
+//
+// If PrintUsageOnError is set, and parsing fails with an error other than
+// [ErrHelp], usage text for the selected command is written to UsageWriter.
+//
+// If the version of a traversed command is requested, see [Command.Version],
+// the version is written to its VersionWriter, and Parse returns [ErrVersion].
 func (cmd *Command) Parse(args []string, options ...Option) error {
+	err := cmd.parse(args, options)
+	if err != nil && cmd.PrintUsageOnError && !errors.Is(err, ErrHelp) {
+		cmd.printUsage()
+	}
+	if err == nil {
+		if v := cmd.getVersionCommand(); v != nil {
+			v.printVersion()
+			return ErrVersion
+		}
+	}
+	return err
+}
+
+func (cmd *Command) parse(args []string, options []Option) error {
 	// Initial validation and safety checks.
 	if cmd.Name == "" {
 		return fmt.Errorf("name is required")
@@ -100,9 +267,19 @@ func (cmd *Command) Parse(args []string, options ...Option) error {
 		cmd.Flags = NewFlagSet(cmd.Name)
 	}
 
-	// Parse this command's flag set from the provided args.
+	// If a version was given, add a --version flag, unless one exists.
+	if err := cmd.addVersionFlag(); err != nil {
+		return fmt.Errorf("%s: %w", cmd.Name, err)
+	}
+
+	// Parse this command's flag set from the provided args. If the version was
+	// requested, other errors, e.g. missing required flags, are ignored.
 	if err := parse(cmd.Flags, args, options...); err != nil {
 		cmd.selected = cmd // allow GetSelected to work even with errors
+		if !errors.Is(err, ErrHelp) && cmd.versionRequested() {
+			cmd.isParsed = true
+			return nil
+		}
 		return fmt.Errorf("%s: %w", cmd.Name, err)
 	}
 
@@ -112,14 +289,23 @@ func (cmd *Command) Parse(args []string, options ...Option) error {
 	// Set this command's args to the args left over after parsing.
 	cmd.args = cmd.Flags.GetArgs()
 
+	// Subcommand names and aliases must be unambiguous.
+	if err := cmd.checkSubcommandNames(); err != nil {
+		return err
+	}
+
 	// If there were any args, we might need to descend to a subcommand.
 	if len(cmd.args) > 0 {
 		first := cmd.args[0]
 		for _, subcommand := range cmd.Subcommands {
-			if strings.EqualFold(first, subcommand.Name) {
-				cmd.selected = subcommand
-				subcommand.parent = cmd
-				return subcommand.Parse(cmd.args[1:], options...)
+			if cmd.matchAny(first, subcommand) {
+				target, err := cmd.maybeForward(subcommand, options)
+				if err != nil {
+					return err
+				}
+				cmd.selected = target
+				target.parent = cmd
+				return target.parse(cmd.args[1:], options)
 			}
 		}
 	}
@@ -127,31 +313,352 @@ func (cmd *Command) Parse(args []string, options ...Option) error {
 	// We didn't find a matching subcommand, so we selected ourselves.
 	cmd.selected = cmd
 
+	// The version can be requested by flag, or via a version subcommand.
+	if cmd.versionRequested() {
+		return nil
+	}
+	if cmd.Version != "" && len(cmd.Subcommands) > 0 && len(cmd.args) > 0 && cmd.match(cmd.args[0], "version") {
+		cmd.showVersion = true
+		return nil
+	}
+
+	// If we can't do anything with the args, the user probably made a typo.
+	if len(cmd.args) > 0 && len(cmd.Subcommands) > 0 && cmd.Exec == nil {
+		return cmd.noSubcommandError()
+	}
+
+	// Bind any positional args.
+	if err := bindArgs(cmd.Args, cmd.args); err != nil {
+		return fmt.Errorf("%s: %w", cmd.Name, err)
+	}
+
 	// Parse complete.
 	return nil
 }
 
+// printUsage writes usage text for the selected command to the usage writer.
+func (cmd *Command) printUsage() {
+	selected := cmd.GetSelected()
+	if selected == nil {
+		selected = cmd
+	}
+
+	var usage string
+	switch {
+	case cmd.UsageFunc != nil:
+		usage = cmd.UsageFunc(selected)
+	case selected.Usage != "":
+		usage = fmt.Sprintf("USAGE\n  %s\n", selected.Usage)
+	default:
+		usage = fmt.Sprintf("USAGE\n  %s\n", selected.Name)
+	}
+
+	w := cmd.UsageWriter
+	if w == nil {
+		w = os.Stderr
+	}
+	fmt.Fprintf(w, "%s\n", strings.TrimRight(usage, "\n"))
+}
+
+// addVersionFlag adds the built-in --version flag to the command's flag set, if
+// the command has a version, and the flag set doesn't already have the flag.
+func (cmd *Command) addVersionFlag() error {
+	if cmd.Version == "" || cmd.versionFlag != nil {
+		return nil
+	}
+	fs, ok := cmd.Flags.(*FlagSet)
+	if !ok {
+		return nil
+	}
+	if _, ok := fs.GetFlag("version"); ok {
+		return nil // user-defined flags take precedence
+	}
+	var version bool
+	f, err := fs.AddFlag(FlagConfig{
+		LongName: "version",
+		Usage:    "print version and exit",
+		Value:    ffval.NewValue(&version),
+	})
+	if err != nil {
+		return err
+	}
+	cmd.versionFlag = f
+	return nil
+}
+
+// versionRequested returns true if the built-in --version flag of cmd, or of
+// any of its parents, was set. In that case, the command which owns the flag is
+// marked to show its version.
+func (cmd *Command) versionRequested() bool {
+	for c := cmd; c != nil; c = c.parent {
+		if c.versionFlag != nil && c.versionFlag.IsSet() {
+			c.showVersion = true
+			return true
+		}
+	}
+	return false
+}
+
+// getVersionCommand returns the first traversed command, from cmd down to the
+// terminal command, whose version was requested, or nil if there is none.
+func (cmd *Command) getVersionCommand() *Command {
+	for c := cmd; c != nil; c = c.selected {
+		if c.showVersion {
+			return c
+		}
+		if c.selected == c {
+			break
+		}
+	}
+	return nil
+}
+
+// printVersion writes the version of the command to the version writer.
+func (cmd *Command) printVersion() {
+	w := cmd.VersionWriter
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, "%s\n", cmd.Version)
+}
+
+// match reports whether name selects the subcommand with the candidate name.
+func (cmd *Command) match(name, candidate string) bool {
+	if cmd.MatchFunc != nil {
+		return cmd.MatchFunc(name, candidate)
+	}
+	return strings.EqualFold(name, candidate)
+}
+
+// matchAny reports whether name selects the subcommand, by its name or any of
+// its aliases.
+func (cmd *Command) matchAny(name string, subcommand *Command) bool {
+	if cmd.match(name, subcommand.Name) {
+		return true
+	}
+	for _, alias := range subcommand.Aliases {
+		if cmd.match(name, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSubcommandNames returns an error if any name or alias of a subcommand
+// matches the name or an alias of a different subcommand.
+func (cmd *Command) checkSubcommandNames() error {
+	for i, a := range cmd.Subcommands {
+		for _, b := range cmd.Subcommands[i+1:] {
+			for _, name := range append([]string{a.Name}, a.Aliases...) {
+				if cmd.matchAny(name, b) {
+					return fmt.Errorf("%s: %w: %q (%s, %s)", cmd.Name, ErrDuplicateCommand, name, a.Name, b.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// maybeForward warns if the selected subcommand is deprecated, and returns the
+// sibling command which replaces it, if it should be forwarded.
+func (cmd *Command) maybeForward(subcommand *Command, options []Option) (*Command, error) {
+	if subcommand.DeprecatedBy == "" {
+		return subcommand, nil
+	}
+
+	var pc ParseContext
+	for _, option := range options {
+		option(&pc)
+	}
+	if w := pc.getDeprecationWriter(); w != nil {
+		fmt.Fprintf(w, "warning: command %q is deprecated, use %q instead\n", subcommand.Name, subcommand.DeprecatedBy)
+	}
+
+	if !subcommand.ForwardDeprecated {
+		return subcommand, nil
+	}
+
+	for _, sibling := range cmd.Subcommands {
+		if sibling != subcommand && cmd.match(subcommand.DeprecatedBy, sibling.Name) {
+			return sibling, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: %s: replacement command %q not found", cmd.Name, subcommand.Name, subcommand.DeprecatedBy)
+}
+
+// ArgSpec describes a positional argument to a [Command]. Exactly one of
+// Pointer or Variadic must be set.
+//
+// Fixed args, with Pointer set, are required, and consume a single leftover
+// arg each. A variadic arg, with Variadic set, consumes every leftover arg
+// after the fixed args which precede it, and must therefore be the final spec.
+// If there's no variadic arg, any leftover args beyond the fixed args are
+// permitted, and ignored.
+type ArgSpec struct {
+	// Name of the argument, used in error messages, e.g. "FILE".
+	Name string
+
+	// Pointer is set to the value of a fixed arg.
+	Pointer *string
+
+	// Variadic is set to the values of a variadic arg.
+	Variadic *[]string
+
+	// Min is the minimum number of values required by a variadic arg. It's
+	// ignored for fixed args, which always require exactly one value.
+	Min int
+}
+
+func bindArgs(specs []ArgSpec, args []string) error {
+	for i, spec := range specs {
+		switch {
+		case spec.Pointer == nil && spec.Variadic == nil:
+			return fmt.Errorf("arg %s: either Pointer or Variadic is required", spec.Name)
+		case spec.Pointer != nil && spec.Variadic != nil:
+			return fmt.Errorf("arg %s: Pointer and Variadic are mutually exclusive", spec.Name)
+		case spec.Variadic != nil && i != len(specs)-1:
+			return fmt.Errorf("arg %s: variadic arg must be last", spec.Name)
+		}
+	}
+
+	for _, spec := range specs {
+		switch {
+		case spec.Pointer != nil:
+			if len(args) <= 0 {
+				return fmt.Errorf("arg %s: missing value", spec.Name)
+			}
+			*spec.Pointer, args = args[0], args[1:]
+
+		case spec.Variadic != nil:
+			if len(args) < spec.Min {
+				return fmt.Errorf("arg %s: need at least %d value(s), have %d", spec.Name, spec.Min, len(args))
+			}
+			*spec.Variadic = append([]string{}, args...)
+			args = args[len(args):]
+		}
+	}
+
+	return nil
+}
+
 // Run the Exec function of the terminal command selected during the parse
 // phase, passing the args left over after parsing. Calling [Command.Run]
-// without first calling [Command.Parse] will result in [ErrNotParsed].
+// without first calling [Command.Parse] will result in [ErrNotParsed]. If the
+// version was requested during the parse phase, Run does nothing, and returns
+// nil.
 func (cmd *Command) Run(ctx context.Context) error {
 	switch {
 	case !cmd.isParsed:
 		return ErrNotParsed
 	case cmd.isParsed && cmd.selected == nil:
 		return ErrNotParsed
-	case cmd.isParsed && cmd.selected == cmd && cmd.Exec == nil:
-		return fmt.Errorf("%s: %w", cmd.Name, ErrNoExec)
-	case cmd.isParsed && cmd.selected == cmd && cmd.Exec != nil:
+	case cmd.getVersionCommand() != nil:
+		return nil
+	}
+
+	// Check the terminal command can be run before calling any hooks.
+	switch terminal := cmd.GetSelected(); {
+	case terminal.RequireSubcommand:
+		return terminal.noSubcommandError()
+	case terminal.Exec == nil:
+		return fmt.Errorf("%s: %w", terminal.Name, ErrNoExec)
+	}
+
+	return cmd.run(ctx)
+}
+
+// run calls the PreRun hook of cmd, then either the Exec function of cmd, if
+// it's the terminal command, or run of the selected subcommand, and finally
+// the PostRun hook of cmd.
+func (cmd *Command) run(ctx context.Context) (err error) {
+	if cmd.PreRun != nil {
+		if err := cmd.PreRun(ctx, cmd.args); err != nil {
+			return err
+		}
+	}
+
+	if cmd.PostRun != nil {
+		defer func() {
+			err = errors.Join(err, cmd.PostRun(ctx, cmd.args))
+		}()
+	}
+
+	if cmd.selected == cmd {
 		return cmd.Exec(ctx, cmd.args)
-	default:
-		return cmd.selected.Run(ctx)
 	}
+
+	return cmd.selected.run(ctx)
+}
+
+func (cmd *Command) noSubcommandError() error {
+	var names []string
+	for _, sc := range cmd.Subcommands {
+		if !sc.Hidden {
+			names = append(names, sc.Name)
+		}
+	}
+
+	available := "none"
+	if len(names) > 0 {
+		available = strings.Join(names, ", ")
+	}
+
+	if len(cmd.args) > 0 {
+		if suggestions := cmd.suggestSubcommands(cmd.args[0]); len(suggestions) > 0 {
+			quoted := make([]string, len(suggestions))
+			for i := range suggestions {
+				quoted[i] = strconv.Quote(suggestions[i])
+			}
+			return fmt.Errorf("%s: %w: unknown subcommand %q (did you mean %s?)", cmd.Name, ErrNoSubcommand, cmd.args[0], strings.Join(quoted, " or "))
+		}
+		return fmt.Errorf("%s: %w: unknown subcommand %q (available: %s)", cmd.Name, ErrNoSubcommand, cmd.args[0], available)
+	}
+	return fmt.Errorf("%s: %w (available: %s)", cmd.Name, ErrNoSubcommand, available)
 }
 
-// ParseAndRun calls [Command.Parse] and, upon success, [Command.Run].
+// suggestSubcommands returns the names and aliases of non-hidden subcommands
+// which are closest to the given unknown name, in order. See SuggestDistance.
+func (cmd *Command) suggestSubcommands(name string) []string {
+	maxDist := cmd.SuggestDistance
+	switch {
+	case maxDist < 0:
+		return nil
+	case maxDist == 0:
+		maxDist = defaultSuggestDistance(name)
+	}
+
+	var (
+		best     []string
+		bestDist = -1
+	)
+	for _, sc := range cmd.Subcommands {
+		if sc.Hidden {
+			continue
+		}
+		for _, candidate := range append([]string{sc.Name}, sc.Aliases...) {
+			dist := levenshtein(strings.ToLower(name), strings.ToLower(candidate))
+			switch {
+			case dist > maxDist:
+				continue
+			case bestDist < 0 || dist < bestDist:
+				best, bestDist = []string{candidate}, dist
+			case dist == bestDist:
+				best = append(best, candidate)
+			}
+		}
+	}
+	return best
+}
+
+// ParseAndRun calls [Command.Parse] and, upon success, [Command.Run]. If Parse
+// returns [ErrVersion], the version has been written, and ParseAndRun returns
+// nil without calling Run.
 func (cmd *Command) ParseAndRun(ctx context.Context, args []string, options ...Option) error {
 	if err := cmd.Parse(args, options...); err != nil {
+		if errors.Is(err, ErrVersion) {
+			return nil
+		}
 		return err
 	}
 
@@ -227,6 +734,7 @@ func (cmd *Command) Reset() error {
 	cmd.selected = nil
 	cmd.parent = nil
 	cmd.args = []string{}
+	cmd.showVersion = false
 
 	return nil
 }
@@ -1,8 +1,10 @@
 package ff_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"testing"
@@ -77,6 +79,450 @@ func TestCommandReset(t *testing.T) {
 	})
 }
 
+func TestCommandArgs(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name       string
+		args       []string
+		wantSrc    string
+		wantDst    string
+		wantRest   []string
+		wantErrStr string
+	}{
+		{
+			name:     "two fixed and variadic",
+			args:     []string{"src", "dst", "a", "b", "c"},
+			wantSrc:  "src",
+			wantDst:  "dst",
+			wantRest: []string{"a", "b", "c"},
+		},
+		{
+			name:     "flags before args",
+			args:     []string{"-v", "src", "dst", "a"},
+			wantSrc:  "src",
+			wantDst:  "dst",
+			wantRest: []string{"a"},
+		},
+		{
+			name:       "variadic below minimum",
+			args:       []string{"src", "dst"},
+			wantErrStr: "arg REST: need at least 1 value(s), have 0",
+		},
+		{
+			name:       "missing fixed",
+			args:       []string{"src"},
+			wantErrStr: "arg DST: missing value",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				src, dst string
+				rest     []string
+				execArgs []string
+			)
+			fs := ff.NewFlagSet("copy")
+			fs.Bool('v', "verbose", "verbose logging")
+			cmd := &ff.Command{
+				Name:  "copy",
+				Flags: fs,
+				Args: []ff.ArgSpec{
+					{Name: "SRC", Pointer: &src},
+					{Name: "DST", Pointer: &dst},
+					{Name: "REST", Variadic: &rest, Min: 1},
+				},
+				Exec: func(_ context.Context, args []string) error { execArgs = args; return nil },
+			}
+
+			err := cmd.ParseAndRun(context.Background(), test.args)
+			if test.wantErrStr != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), test.wantErrStr) {
+					t.Fatalf("want error %q, have %v", test.wantErrStr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAndRun: %v", err)
+			}
+
+			if want, have := test.wantSrc, src; want != have {
+				t.Errorf("src: want %q, have %q", want, have)
+			}
+			if want, have := test.wantDst, dst; want != have {
+				t.Errorf("dst: want %q, have %q", want, have)
+			}
+			if want, have := test.wantRest, rest; !reflect.DeepEqual(want, have) {
+				t.Errorf("rest: want %q, have %q", want, have)
+			}
+			if want, have := append([]string{test.wantSrc, test.wantDst}, test.wantRest...), execArgs; !reflect.DeepEqual(want, have) {
+				t.Errorf("exec args: want %q, have %q", want, have)
+			}
+		})
+	}
+
+	t.Run("variadic not last", func(t *testing.T) {
+		var a string
+		var b []string
+		cmd := &ff.Command{
+			Name: "bad",
+			Args: []ff.ArgSpec{{Name: "B", Variadic: &b}, {Name: "A", Pointer: &a}},
+		}
+		if err := cmd.Parse([]string{"x", "y"}); err == nil {
+			t.Errorf("want error, have none")
+		}
+	})
+}
+
+func TestCommandRequireSubcommand(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{name: "no args", args: []string{}, wantErr: "root: subcommand required (available: foo, bar)"},
+		{name: "unknown", args: []string{"quux"}, wantErr: `root: subcommand required: unknown subcommand "quux" (available: foo, bar)`},
+		{name: "typo", args: []string{"baz"}, wantErr: `root: subcommand required: unknown subcommand "baz" (did you mean "bar"?)`},
+		{name: "selected", args: []string{"foo"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var ran bool
+			cmd := &ff.Command{
+				Name:              "root",
+				RequireSubcommand: true,
+				Exec:              func(context.Context, []string) error { t.Errorf("root exec called"); return nil },
+				Subcommands: []*ff.Command{
+					{Name: "foo", Exec: func(context.Context, []string) error { ran = true; return nil }},
+					{Name: "bar"},
+					{Name: "debug", Hidden: true},
+				},
+			}
+
+			err := cmd.ParseAndRun(context.Background(), test.args)
+			if test.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ParseAndRun: %v", err)
+				}
+				if !ran {
+					t.Errorf("subcommand didn't run")
+				}
+				return
+			}
+			if !errors.Is(err, ff.ErrNoSubcommand) {
+				t.Fatalf("want %v, have %v", ff.ErrNoSubcommand, err)
+			}
+			if want, have := test.wantErr, err.Error(); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestCommandSuggestions(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(distance int) *ff.Command {
+		return &ff.Command{
+			Name:            "git",
+			SuggestDistance: distance,
+			Subcommands: []*ff.Command{
+				{Name: "status"},
+				{Name: "stash"},
+				{Name: "remove", Aliases: []string{"rm"}},
+				{Name: "statusx", Hidden: true},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		distance int
+		args     []string
+		wantErr  string
+	}{
+		{"typo", 0, []string{"statuss"}, `git: subcommand required: unknown subcommand "statuss" (did you mean "status"?)`},
+		{"case", 0, []string{"STATS"}, `git: subcommand required: unknown subcommand "STATS" (did you mean "status"?)`},
+		{"alias", 0, []string{"rn"}, `git: subcommand required: unknown subcommand "rn" (did you mean "rm"?)`},
+		{"ties", 2, []string{"stat"}, `git: subcommand required: unknown subcommand "stat" (did you mean "status" or "stash"?)`},
+		{"too far", 0, []string{"stuff"}, `git: subcommand required: unknown subcommand "stuff" (available: status, stash, remove)`},
+		{"larger distance", 3, []string{"stuff"}, `git: subcommand required: unknown subcommand "stuff" (did you mean "stash"?)`},
+		{"disabled", -1, []string{"statuss"}, `git: subcommand required: unknown subcommand "statuss" (available: status, stash, remove)`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := newRoot(test.distance)
+			err := root.Parse(test.args)
+			if !errors.Is(err, ff.ErrNoSubcommand) {
+				t.Fatalf("want %v, have %v", ff.ErrNoSubcommand, err)
+			}
+			if want, have := test.wantErr, err.Error(); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestCommandMatchFunc(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(matchFunc func(name, candidate string) bool) *ff.Command {
+		return &ff.Command{
+			Name:      "root",
+			MatchFunc: matchFunc,
+			Exec:      func(context.Context, []string) error { return nil },
+			Subcommands: []*ff.Command{
+				{Name: "Status"},
+				{Name: "my-cmd"},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name      string
+		matchFunc func(name, candidate string) bool
+		args      []string
+		want      string
+	}{
+		{"default case-insensitive", nil, []string{"status"}, "Status"},
+		{"case-sensitive match", func(a, b string) bool { return a == b }, []string{"Status"}, "Status"},
+		{"case-sensitive reject", func(a, b string) bool { return a == b }, []string{"status"}, "root"},
+		{"default no normalization", nil, []string{"my_cmd"}, "root"},
+		{"normalized", func(a, b string) bool {
+			normalize := func(s string) string { return strings.ReplaceAll(strings.ToLower(s), "_", "-") }
+			return normalize(a) == normalize(b)
+		}, []string{"my_cmd"}, "my-cmd"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			root := newRoot(test.matchFunc)
+			if err := root.Parse(test.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, root.GetSelected().Name; want != have {
+				t.Errorf("selected: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestCommandPreRunPostRun(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errExec = errors.New("exec error")
+		errPre  = errors.New("pre-run error")
+		errPost = errors.New("post-run error")
+	)
+
+	type errs struct{ rootPre, midPre, leafExec, midPost error }
+
+	newRoot := func(calls *[]string, e errs) *ff.Command {
+		hook := func(name string, err error) func(context.Context, []string) error {
+			return func(_ context.Context, args []string) error {
+				*calls = append(*calls, fmt.Sprintf("%s %v", name, args))
+				return err
+			}
+		}
+		leaf := &ff.Command{
+			Name:    "leaf",
+			PreRun:  hook("leaf pre", nil),
+			Exec:    hook("leaf exec", e.leafExec),
+			PostRun: hook("leaf post", nil),
+		}
+		mid := &ff.Command{
+			Name:        "mid",
+			PreRun:      hook("mid pre", e.midPre),
+			PostRun:     hook("mid post", e.midPost),
+			Subcommands: []*ff.Command{leaf},
+		}
+		return &ff.Command{
+			Name:        "root",
+			PreRun:      hook("root pre", e.rootPre),
+			PostRun:     hook("root post", nil),
+			Subcommands: []*ff.Command{mid},
+		}
+	}
+
+	for _, test := range []struct {
+		name      string
+		errs      errs
+		wantCalls []string
+		wantErrs  []error
+	}{
+		{
+			name:      "success",
+			wantCalls: []string{"root pre [mid leaf x]", "mid pre [leaf x]", "leaf pre [x]", "leaf exec [x]", "leaf post [x]", "mid post [leaf x]", "root post [mid leaf x]"},
+		},
+		{
+			name:      "exec error",
+			errs:      errs{leafExec: errExec},
+			wantCalls: []string{"root pre [mid leaf x]", "mid pre [leaf x]", "leaf pre [x]", "leaf exec [x]", "leaf post [x]", "mid post [leaf x]", "root post [mid leaf x]"},
+			wantErrs:  []error{errExec},
+		},
+		{
+			name:      "pre-run error",
+			errs:      errs{midPre: errPre},
+			wantCalls: []string{"root pre [mid leaf x]", "mid pre [leaf x]", "root post [mid leaf x]"},
+			wantErrs:  []error{errPre},
+		},
+		{
+			name:      "root pre-run error",
+			errs:      errs{rootPre: errPre},
+			wantCalls: []string{"root pre [mid leaf x]"},
+			wantErrs:  []error{errPre},
+		},
+		{
+			name:      "exec and post-run errors",
+			errs:      errs{leafExec: errExec, midPost: errPost},
+			wantCalls: []string{"root pre [mid leaf x]", "mid pre [leaf x]", "leaf pre [x]", "leaf exec [x]", "leaf post [x]", "mid post [leaf x]", "root post [mid leaf x]"},
+			wantErrs:  []error{errExec, errPost},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			root := newRoot(&calls, test.errs)
+			err := root.ParseAndRun(context.Background(), []string{"mid", "leaf", "x"})
+			if test.wantErrs == nil && err != nil {
+				t.Errorf("ParseAndRun: want no error, have %v", err)
+			}
+			for _, want := range test.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("ParseAndRun: want %v, have %v", want, err)
+				}
+			}
+			if want, have := test.wantCalls, calls; !reflect.DeepEqual(want, have) {
+				t.Errorf("calls: want %v, have %v", want, have)
+			}
+		})
+	}
+
+	t.Run("no exec", func(t *testing.T) {
+		var calls []string
+		root := newRoot(&calls, errs{})
+		if err := root.ParseAndRun(context.Background(), []string{"mid"}); !errors.Is(err, ff.ErrNoExec) {
+			t.Errorf("ParseAndRun: want %v, have %v", ff.ErrNoExec, err)
+		}
+		if len(calls) > 0 {
+			t.Errorf("calls: want none, have %v", calls)
+		}
+	})
+}
+
+func TestCommandAliases(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func() *ff.Command {
+		return &ff.Command{
+			Name: "root",
+			Exec: func(context.Context, []string) error { return nil },
+			Subcommands: []*ff.Command{
+				{Name: "remove", Aliases: []string{"rm", "del"}},
+				{Name: "list", Aliases: []string{"ls"}},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"remove"}, "remove"},
+		{[]string{"rm"}, "remove"},
+		{[]string{"DEL"}, "remove"},
+		{[]string{"ls"}, "list"},
+		{[]string{"l"}, "root"},
+	} {
+		root := newRoot()
+		if err := root.Parse(test.args); err != nil {
+			t.Fatalf("%v: Parse: %v", test.args, err)
+		}
+		if want, have := test.want, root.GetSelected().Name; want != have {
+			t.Errorf("%v: selected: want %q, have %q", test.args, want, have)
+		}
+	}
+
+	t.Run("duplicate alias", func(t *testing.T) {
+		root := newRoot()
+		root.Subcommands = append(root.Subcommands, &ff.Command{Name: "rmdir", Aliases: []string{"RM"}})
+		err := root.Parse([]string{"ls"})
+		if !errors.Is(err, ff.ErrDuplicateCommand) {
+			t.Fatalf("want %v, have %v", ff.ErrDuplicateCommand, err)
+		}
+		if want, have := `root: duplicate command: "rm" (remove, rmdir)`, err.Error(); want != have {
+			t.Errorf("error: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("alias duplicates name", func(t *testing.T) {
+		root := newRoot()
+		root.Subcommands = append(root.Subcommands, &ff.Command{Name: "ls"})
+		if err := root.Parse([]string{}); !errors.Is(err, ff.ErrDuplicateCommand) {
+			t.Fatalf("want %v, have %v", ff.ErrDuplicateCommand, err)
+		}
+	})
+}
+
+func TestCommandDeprecatedBy(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name        string
+		forward     bool
+		replacement string
+		wantRan     string
+		wantErr     bool
+	}{
+		{name: "warn only", forward: false, replacement: "new", wantRan: "old"},
+		{name: "forward", forward: true, replacement: "new", wantRan: "new"},
+		{name: "forward missing", forward: true, replacement: "nope", wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				ran      string
+				ranArgs  []string
+				warnings strings.Builder
+			)
+			cmd := &ff.Command{
+				Name: "root",
+				Subcommands: []*ff.Command{
+					{
+						Name:              "old",
+						DeprecatedBy:      test.replacement,
+						ForwardDeprecated: test.forward,
+						Exec:              func(_ context.Context, args []string) error { ran, ranArgs = "old", args; return nil },
+					},
+					{
+						Name: "new",
+						Exec: func(_ context.Context, args []string) error { ran, ranArgs = "new", args; return nil },
+					},
+				},
+			}
+
+			err := cmd.ParseAndRun(context.Background(), []string{"old", "a", "b"}, ff.WithDeprecationWriter(&warnings))
+			if want, have := fmt.Sprintf("warning: command %q is deprecated, use %q instead\n", "old", test.replacement), warnings.String(); want != have {
+				t.Errorf("warning: want %q, have %q", want, have)
+			}
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("want error, have none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAndRun: %v", err)
+			}
+			if want, have := test.wantRan, ran; want != have {
+				t.Errorf("ran: want %q, have %q", want, have)
+			}
+			if want, have := []string{"a", "b"}, ranArgs; !reflect.DeepEqual(want, have) {
+				t.Errorf("args: want %q, have %q", want, have)
+			}
+			if want, have := test.wantRan, cmd.GetSelected().Name; want != have {
+				t.Errorf("selected: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
 func makeTestCommand(t *testing.T) (*ff.Command, *testCommandVars) {
 	t.Helper()
 
@@ -156,3 +602,168 @@ func compareTestCommandVars(t *testing.T, want, have testCommandVars) {
 		}
 	}
 }
+
+func TestCommandPrintUsageOnError(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(usage *bytes.Buffer) *ff.Command {
+		subFlags := ff.NewFlagSet("sub")
+		subFlags.IntLong("count", 0, "count")
+		return &ff.Command{
+			Name:              "root",
+			Usage:             "root [FLAGS] <SUBCOMMAND>",
+			PrintUsageOnError: true,
+			UsageWriter:       usage,
+			Subcommands: []*ff.Command{
+				{Name: "sub", Flags: subFlags, Exec: func(context.Context, []string) error { return nil }},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name      string
+		args      []string
+		usageFunc func(*ff.Command) string
+		wantErr   bool
+		wantUsage string
+	}{
+		{
+			name: "success",
+			args: []string{"sub", "--count=1"},
+		},
+		{
+			name: "help",
+			args: []string{"-h"},
+		},
+		{
+			name:      "unknown flag",
+			args:      []string{"--bogus"},
+			wantErr:   true,
+			wantUsage: "USAGE\n  root [FLAGS] <SUBCOMMAND>\n",
+		},
+		{
+			name:      "subcommand error without usage",
+			args:      []string{"sub", "--count=x"},
+			wantErr:   true,
+			wantUsage: "USAGE\n  sub\n",
+		},
+		{
+			name:      "usage func",
+			args:      []string{"sub", "--count=x"},
+			usageFunc: func(cmd *ff.Command) string { return "help for " + cmd.Name },
+			wantErr:   true,
+			wantUsage: "help for sub\n",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var usage bytes.Buffer
+			root := newRoot(&usage)
+			root.UsageFunc = test.usageFunc
+			err := root.Parse(test.args)
+			if want, have := test.wantErr, err != nil && !errors.Is(err, ff.ErrHelp); want != have {
+				t.Fatalf("error: want %v, have %v", want, err)
+			}
+			if want, have := test.wantUsage, usage.String(); want != have {
+				t.Errorf("usage: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestCommandVersion(t *testing.T) {
+	t.Parallel()
+
+	newRoot := func(output *bytes.Buffer, ran *bool) *ff.Command {
+		subFlags := ff.NewFlagSet("sub")
+		subFlags.IntLong("count", 0, "count")
+		return &ff.Command{
+			Name:          "root",
+			Version:       "v1.2.3",
+			VersionWriter: output,
+			Subcommands: []*ff.Command{
+				{Name: "sub", Flags: subFlags, Exec: func(context.Context, []string) error { *ran = true; return nil }},
+			},
+		}
+	}
+
+	for _, test := range []struct {
+		name        string
+		args        []string
+		wantVersion bool
+		wantRan     bool
+	}{
+		{name: "flag", args: []string{"--version"}, wantVersion: true},
+		{name: "subcommand", args: []string{"version"}, wantVersion: true},
+		{name: "flag before subcommand", args: []string{"--version", "sub"}, wantVersion: true},
+		{name: "no version", args: []string{"sub", "--count=1"}, wantRan: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				output bytes.Buffer
+				ran    bool
+				root   = newRoot(&output, &ran)
+			)
+
+			err := root.Parse(test.args)
+			if want, have := test.wantVersion, errors.Is(err, ff.ErrVersion); want != have {
+				t.Fatalf("Parse: want ErrVersion %v, have %v", want, err)
+			}
+			if !test.wantVersion && err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if err := root.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if want, have := test.wantRan, ran; want != have {
+				t.Errorf("ran: want %v, have %v", want, have)
+			}
+
+			wantOutput := ""
+			if test.wantVersion {
+				wantOutput = "v1.2.3\n"
+			}
+			if want, have := wantOutput, output.String(); want != have {
+				t.Errorf("output: want %q, have %q", want, have)
+			}
+		})
+	}
+
+	t.Run("ParseAndRun", func(t *testing.T) {
+		var (
+			output bytes.Buffer
+			ran    bool
+			root   = newRoot(&output, &ran)
+		)
+		if err := root.ParseAndRun(context.Background(), []string{"--version"}); err != nil {
+			t.Fatalf("ParseAndRun: %v", err)
+		}
+		if ran {
+			t.Errorf("Exec was called")
+		}
+		if want, have := "v1.2.3\n", output.String(); want != have {
+			t.Errorf("output: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("user flag overrides", func(t *testing.T) {
+		var output bytes.Buffer
+		fs := ff.NewFlagSet("root")
+		version := fs.StringLong("version", "", "version to deploy")
+		root := &ff.Command{
+			Name:          "root",
+			Version:       "v1.2.3",
+			VersionWriter: &output,
+			Flags:         fs,
+			Exec:          func(context.Context, []string) error { return nil },
+		}
+		if err := root.ParseAndRun(context.Background(), []string{"--version=v9"}); err != nil {
+			t.Fatalf("ParseAndRun: %v", err)
+		}
+		if want, have := "v9", *version; want != have {
+			t.Errorf("version flag: want %q, have %q", want, have)
+		}
+		if want, have := "", output.String(); want != have {
+			t.Errorf("output: want %q, have %q", want, have)
+		}
+	})
+}
@@ -10,6 +10,11 @@ var (
 	// args indicate the user has requested help.
 	ErrHelp = flag.ErrHelp
 
+	// ErrVersion is returned by [Command.Parse] when the user has requested the
+	// version of a command, and the version has been written. It's analogous
+	// to ErrHelp, and [Command.ParseAndRun] treats it as a clean exit.
+	ErrVersion = errors.New("version requested")
+
 	// ErrDuplicateFlag should be returned by flag sets when the user tries to
 	// add a flag with the same name as a pre-existing flag.
 	ErrDuplicateFlag = errors.New("duplicate flag")
@@ -27,6 +32,30 @@ var (
 	// specific or user-requested flag was provided but could not be found.
 	ErrUnknownFlag = errors.New("unknown flag")
 
+	// ErrDuplicateCommand is returned when two subcommands of the same command
+	// share a name or alias.
+	ErrDuplicateCommand = errors.New("duplicate command")
+
 	// ErrNoExec is returned when a command without an exec function is run.
 	ErrNoExec = errors.New("no exec function")
+
+	// ErrNoSubcommand is returned when a command which requires a subcommand is
+	// run without one.
+	ErrNoSubcommand = errors.New("subcommand required")
+
+	// ErrFrozen is returned when the user tries to set the value of a flag in a
+	// flag set which has been frozen.
+	ErrFrozen = errors.New("frozen")
+
+	// ErrMutuallyExclusive is returned by parse when more than one flag in a
+	// mutually exclusive group is set.
+	ErrMutuallyExclusive = errors.New("mutually exclusive flags")
+
+	// ErrRequiredTogether is returned by parse when some, but not all, of the
+	// flags in a group which must be set together are set.
+	ErrRequiredTogether = errors.New("flags must be set together")
+
+	// ErrMissingRequired is returned by parse when one or more required flags
+	// weren't set by any source.
+	ErrMissingRequired = errors.New("missing required flag")
 )
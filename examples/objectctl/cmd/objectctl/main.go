@@ -11,7 +11,6 @@ import (
 	"github.com/peterbourgon/ff/v4/examples/objectctl/pkg/createcmd"
 	"github.com/peterbourgon/ff/v4/examples/objectctl/pkg/deletecmd"
 	"github.com/peterbourgon/ff/v4/examples/objectctl/pkg/listcmd"
-	"github.com/peterbourgon/ff/v4/examples/objectctl/pkg/objectapi"
 	"github.com/peterbourgon/ff/v4/examples/objectctl/pkg/rootcmd"
 	"github.com/peterbourgon/ff/v4/ffhelp"
 )
@@ -52,13 +51,6 @@ func exec(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io
 		return fmt.Errorf("parse: %w", err)
 	}
 
-	client, err := objectapi.NewClient(root.Token)
-	if err != nil {
-		return fmt.Errorf("construct API client: %w", err)
-	}
-
-	root.Client = client
-
 	if err := root.Command.Run(ctx); err != nil {
 		return fmt.Errorf("run: %w", err)
 	}
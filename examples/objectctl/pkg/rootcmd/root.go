@@ -1,6 +1,8 @@
 package rootcmd
 
 import (
+	"context"
+	"fmt"
 	"io"
 
 	"github.com/peterbourgon/ff/v4"
@@ -36,6 +38,18 @@ func New(stdout, stderr io.Writer) *RootConfig {
 		ShortHelp: "control objects",
 		Usage:     "objectctl [FLAGS] <SUBCOMMAND> ...",
 		Flags:     cfg.Flags,
+		PreRun:    cfg.preRun,
 	}
 	return &cfg
 }
+
+// preRun constructs the API client used by every subcommand, after the token
+// flag has been parsed.
+func (cfg *RootConfig) preRun(context.Context, []string) error {
+	client, err := objectapi.NewClient(cfg.Token)
+	if err != nil {
+		return fmt.Errorf("construct API client: %w", err)
+	}
+	cfg.Client = client
+	return nil
+}
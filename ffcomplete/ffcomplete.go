@@ -0,0 +1,370 @@
+// Package ffcomplete generates shell completion scripts from a command tree.
+package ffcomplete
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peterbourgon/ff/v4"
+)
+
+// Bash returns a bash completion script for the root command. The script
+// completes the names and aliases of subcommands, the names of flags available
+// to each command, and the valid values of enum flags, e.g. [ffval.Enum]. Hidden
+// commands and flags aren't completed.
+//
+// The script is typically sourced from a user's .bashrc, e.g. via
+//
+//	source <(myapp completion bash)
+//
+// [ffval.Enum]: https://pkg.go.dev/github.com/peterbourgon/ff/v4/ffval#Enum
+func Bash(root *ff.Command) (string, error) {
+	nodes, err := walk(root)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		sb   strings.Builder
+		name = root.Name
+		fn   = "_" + identifier(name) + "_completions"
+	)
+
+	fmt.Fprintf(&sb, "# bash completion for %s\n\n", name)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	fmt.Fprintf(&sb, "\tlocal cur prev cmdpath word i\n")
+	fmt.Fprintf(&sb, "\tcur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(&sb, "\tprev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(&sb, "\tcmdpath=%s\n", quote(name))
+	fmt.Fprintf(&sb, "\tfor ((i = 1; i < COMP_CWORD; i++)); do\n")
+	fmt.Fprintf(&sb, "\t\tword=\"${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(&sb, "\t\tcase \"$cmdpath:$word\" in\n")
+	for _, n := range nodes {
+		for _, sub := range n.subcommands {
+			fmt.Fprintf(&sb, "\t\t%s) cmdpath=%s ;;\n", patterns(n.path, sub.names), quote(n.path+" "+sub.names[0]))
+		}
+	}
+	fmt.Fprintf(&sb, "\t\tesac\n")
+	fmt.Fprintf(&sb, "\tdone\n\n")
+
+	fmt.Fprintf(&sb, "\tcase \"$cmdpath:$prev\" in\n")
+	for _, n := range nodes {
+		for _, f := range n.flags {
+			if len(f.valid) > 0 {
+				fmt.Fprintf(&sb, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return ;;\n", patterns(n.path, f.names()), quote(strings.Join(f.valid, " ")))
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "\tesac\n\n")
+
+	fmt.Fprintf(&sb, "\tcase \"$cmdpath\" in\n")
+	for _, n := range nodes {
+		fmt.Fprintf(&sb, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", quote(n.path), quote(strings.Join(n.words(), " ")))
+	}
+	fmt.Fprintf(&sb, "\tesac\n")
+	fmt.Fprintf(&sb, "}\n\n")
+
+	fmt.Fprintf(&sb, "complete -F %s %s\n", fn, name)
+
+	return sb.String(), nil
+}
+
+// Zsh returns a zsh completion script for the root command. It completes the
+// same words as [Bash]. The script can be sourced, or installed as a file named
+// _<root> in a directory in $fpath.
+func Zsh(root *ff.Command) (string, error) {
+	nodes, err := walk(root)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		sb   strings.Builder
+		name = root.Name
+		fn   = "_" + identifier(name)
+	)
+
+	fmt.Fprintf(&sb, "#compdef %s\n\n", name)
+	fmt.Fprintf(&sb, "%s() {\n", fn)
+	fmt.Fprintf(&sb, "\tlocal cmdpath=%s word prev i\n", quote(name))
+	fmt.Fprintf(&sb, "\tfor ((i = 2; i < CURRENT; i++)); do\n")
+	fmt.Fprintf(&sb, "\t\tword=\"${words[i]}\"\n")
+	fmt.Fprintf(&sb, "\t\tcase \"$cmdpath:$word\" in\n")
+	for _, n := range nodes {
+		for _, sub := range n.subcommands {
+			fmt.Fprintf(&sb, "\t\t%s) cmdpath=%s ;;\n", patterns(n.path, sub.names), quote(n.path+" "+sub.names[0]))
+		}
+	}
+	fmt.Fprintf(&sb, "\t\tesac\n")
+	fmt.Fprintf(&sb, "\tdone\n\n")
+
+	fmt.Fprintf(&sb, "\tprev=\"${words[CURRENT-1]}\"\n")
+	fmt.Fprintf(&sb, "\tcase \"$cmdpath:$prev\" in\n")
+	for _, n := range nodes {
+		for _, f := range n.flags {
+			if len(f.valid) > 0 {
+				fmt.Fprintf(&sb, "\t%s) compadd -- %s; return ;;\n", patterns(n.path, f.names()), quoteAll(f.valid))
+			}
+		}
+	}
+	fmt.Fprintf(&sb, "\tesac\n\n")
+
+	fmt.Fprintf(&sb, "\tcase \"$cmdpath\" in\n")
+	for _, n := range nodes {
+		if words := n.words(); len(words) > 0 {
+			fmt.Fprintf(&sb, "\t%s) compadd -- %s ;;\n", quote(n.path), quoteAll(words))
+		}
+	}
+	fmt.Fprintf(&sb, "\tesac\n")
+	fmt.Fprintf(&sb, "}\n\n")
+
+	fmt.Fprintf(&sb, "if [ \"$funcstack[1]\" = %s ]; then\n", quote(fn))
+	fmt.Fprintf(&sb, "\t%s \"$@\"\n", fn)
+	fmt.Fprintf(&sb, "else\n")
+	fmt.Fprintf(&sb, "\tcompdef %s %s\n", fn, name)
+	fmt.Fprintf(&sb, "fi\n")
+
+	return sb.String(), nil
+}
+
+// Fish returns a fish completion script for the root command. It completes the
+// same words as [Bash], and includes the short help of subcommands and the
+// usage of flags as descriptions. The script can be sourced, or installed as a
+// file named <root>.fish in ~/.config/fish/completions.
+func Fish(root *ff.Command) (string, error) {
+	nodes, err := walk(root)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		sb   strings.Builder
+		name = root.Name
+		fn   = "__" + identifier(name) + "_cmdpath"
+	)
+
+	fmt.Fprintf(&sb, "# fish completion for %s\n\n", name)
+	fmt.Fprintf(&sb, "function %s\n", fn)
+	fmt.Fprintf(&sb, "\tset -l cmdpath %s\n", quote(name))
+	fmt.Fprintf(&sb, "\tfor word in (commandline -opc)[2..-1]\n")
+	fmt.Fprintf(&sb, "\t\tswitch \"$cmdpath:$word\"\n")
+	for _, n := range nodes {
+		for _, sub := range n.subcommands {
+			var cases []string
+			for _, s := range sub.names {
+				cases = append(cases, quote(n.path+":"+s))
+			}
+			fmt.Fprintf(&sb, "\t\tcase %s\n", strings.Join(cases, " "))
+			fmt.Fprintf(&sb, "\t\t\tset cmdpath %s\n", quote(n.path+" "+sub.names[0]))
+		}
+	}
+	fmt.Fprintf(&sb, "\t\tend\n")
+	fmt.Fprintf(&sb, "\tend\n")
+	fmt.Fprintf(&sb, "\techo $cmdpath\n")
+	fmt.Fprintf(&sb, "end\n\n")
+
+	fmt.Fprintf(&sb, "complete -c %s -f\n", name)
+	for _, n := range nodes {
+		cond := quote(fmt.Sprintf("test (%s) = %s", fn, quoteDouble(n.path)))
+		for _, sub := range n.subcommands {
+			fmt.Fprintf(&sb, "complete -c %s -n %s -a %s -d %s\n", name, cond, quote(strings.Join(sub.names, " ")), quote(sub.help))
+		}
+		for _, f := range n.flags {
+			fmt.Fprintf(&sb, "complete -c %s -n %s", name, cond)
+			if f.short != 0 {
+				fmt.Fprintf(&sb, " -s %s", quote(string(f.short)))
+			}
+			if f.long != "" {
+				fmt.Fprintf(&sb, " -l %s", quote(f.long))
+			}
+			switch {
+			case len(f.valid) > 0:
+				fmt.Fprintf(&sb, " -x -a %s", quote(strings.Join(f.valid, " ")))
+			case f.takesValue:
+				fmt.Fprintf(&sb, " -r")
+			}
+			fmt.Fprintf(&sb, " -d %s\n", quote(f.usage))
+		}
+	}
+
+	return sb.String(), nil
+}
+
+//
+//
+//
+
+type node struct {
+	path        string
+	subcommands []subcommand
+	flags       []flag
+}
+
+type subcommand struct {
+	names []string // name, followed by aliases
+	help  string
+}
+
+type flag struct {
+	short      rune
+	long       string
+	usage      string
+	takesValue bool
+	valid      []string
+}
+
+// names returns the hyphenated names of the flag, e.g. --foo and -f.
+func (f flag) names() []string {
+	var names []string
+	if f.long != "" {
+		names = append(names, "--"+f.long)
+	}
+	if f.short != 0 {
+		names = append(names, "-"+string(f.short))
+	}
+	return names
+}
+
+// words returns every word which can be completed in the context of the node,
+// i.e. the names and aliases of its subcommands, and the names of its flags.
+func (n node) words() []string {
+	var words []string
+	for _, sub := range n.subcommands {
+		words = append(words, sub.names...)
+	}
+	for _, f := range n.flags {
+		words = append(words, f.names()...)
+	}
+	return words
+}
+
+// walk returns a node for the root command, and every non-hidden subcommand,
+// recursively, in depth-first order.
+func walk(root *ff.Command) ([]node, error) {
+	if root == nil {
+		return nil, fmt.Errorf("root command is required")
+	}
+
+	var (
+		nodes []node
+		visit func(cmd *ff.Command, path string) error
+	)
+	visit = func(cmd *ff.Command, path string) error {
+		if err := checkWord(cmd.Name); err != nil {
+			return fmt.Errorf("%s: name: %w", path, err)
+		}
+
+		n := node{path: path}
+		for _, sc := range cmd.Subcommands {
+			if sc.Hidden {
+				continue
+			}
+			names := append([]string{sc.Name}, sc.Aliases...)
+			for _, name := range names {
+				if err := checkWord(name); err != nil {
+					return fmt.Errorf("%s: subcommand %q: %w", path, name, err)
+				}
+			}
+			n.subcommands = append(n.subcommands, subcommand{names: names, help: sc.ShortHelp})
+		}
+
+		if cmd.Flags != nil {
+			if err := cmd.Flags.WalkFlags(func(f ff.Flag) error {
+				if f.IsHidden() {
+					return nil
+				}
+				short, _ := f.GetShortName()
+				long, _ := f.GetLongName()
+				fl := flag{
+					short:      short,
+					long:       long,
+					usage:      f.GetUsage(),
+					takesValue: !f.IsBoolFlag(),
+				}
+				if e, ok := f.(interface{ GetValid() []any }); ok {
+					for _, v := range e.GetValid() {
+						s := fmt.Sprint(v)
+						if err := checkWord(s); err != nil {
+							return fmt.Errorf("%s: flag %s: valid value %q: %w", path, strings.Join(fl.names(), ", "), s, err)
+						}
+						fl.valid = append(fl.valid, s)
+					}
+				}
+				n.flags = append(n.flags, fl)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		nodes = append(nodes, n)
+
+		for _, sc := range cmd.Subcommands {
+			if sc.Hidden {
+				continue
+			}
+			if err := visit(sc, path+" "+sc.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := visit(root, root.Name); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// checkWord returns an error if s can't be completed as a single shell word.
+func checkWord(s string) error {
+	switch {
+	case s == "":
+		return fmt.Errorf("empty")
+	case strings.ContainsAny(s, " \t\n'\"\\$`:"):
+		return fmt.Errorf("contains whitespace or shell metacharacters")
+	default:
+		return nil
+	}
+}
+
+// identifier returns s with every character which isn't valid in a shell
+// function name replaced with an underscore.
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// patterns returns a case pattern matching path:name for each of the names.
+func patterns(path string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(path + ":" + name)
+	}
+	return strings.Join(quoted, "|")
+}
+
+// quote returns s in single quotes, which is valid in bash, zsh, and fish.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func quoteAll(ss []string) string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = quote(s)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteDouble returns s in double quotes, for use within a single-quoted fish
+// condition. Words are checked via checkWord, so no escaping is required.
+func quoteDouble(s string) string {
+	return `"` + s + `"`
+}
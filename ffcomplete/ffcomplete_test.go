@@ -0,0 +1,136 @@
+package ffcomplete_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/peterbourgon/ff/v4"
+	"github.com/peterbourgon/ff/v4/ffcomplete"
+)
+
+func newTestCommand() *ff.Command {
+	rootFlags := ff.NewFlagSet("myapp")
+	rootFlags.Bool('v', "verbose", "log more")
+	rootFlags.StringEnum('o', "output", "output format", "text", "json")
+
+	createFlags := ff.NewFlagSet("create").SetParent(rootFlags)
+	createFlags.StringLong("name", "", "name of the object")
+	createFlags.BoolConfig(ff.FlagConfig{LongName: "secret", Usage: "hidden flag", Hidden: true})
+
+	noop := func(context.Context, []string) error { return nil }
+	return &ff.Command{
+		Name:  "myapp",
+		Flags: rootFlags,
+		Subcommands: []*ff.Command{
+			{Name: "create", Aliases: []string{"new"}, ShortHelp: "create an object", Flags: createFlags, Exec: noop},
+			{Name: "list", ShortHelp: "list objects", Exec: noop},
+			{Name: "debug", Hidden: true, Exec: noop},
+		},
+	}
+}
+
+func TestBash(t *testing.T) {
+	t.Parallel()
+
+	script, err := ffcomplete.Bash(newTestCommand())
+	if err != nil {
+		t.Fatalf("Bash: %v", err)
+	}
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skipf("bash not available: %v", err)
+	}
+
+	for _, test := range []struct {
+		line string
+		want string
+	}{
+		{"myapp ", "create new list --verbose -v --output -o"},
+		{"myapp c", "create"},
+		{"myapp --output ", "text json"},
+		{"myapp create --", "--name --verbose --output"},
+		{"myapp new -o j", "json"},
+		{"myapp list ", ""},
+	} {
+		t.Run(test.line, func(t *testing.T) {
+			words := strings.Fields(test.line)
+			if strings.HasSuffix(test.line, " ") {
+				words = append(words, "")
+			}
+			quoted := make([]string, len(words))
+			for i := range words {
+				quoted[i] = "'" + words[i] + "'"
+			}
+			driver := script + "\n" +
+				"COMP_WORDS=(" + strings.Join(quoted, " ") + ")\n" +
+				"COMP_CWORD=$((${#COMP_WORDS[@]} - 1))\n" +
+				"_myapp_completions\n" +
+				"echo \"${COMPREPLY[*]}\"\n"
+
+			out, err := exec.Command(bash, "-c", driver).CombinedOutput()
+			if err != nil {
+				t.Fatalf("bash: %v: %s", err, out)
+			}
+			if want, have := test.want, strings.TrimSpace(string(out)); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestZshFish(t *testing.T) {
+	t.Parallel()
+
+	zsh, err := ffcomplete.Zsh(newTestCommand())
+	if err != nil {
+		t.Fatalf("Zsh: %v", err)
+	}
+	for _, want := range []string{
+		"#compdef myapp\n",
+		"\t'myapp:create'|'myapp:new') cmdpath='myapp create' ;;\n",
+		"\t'myapp:--output'|'myapp:-o') compadd -- 'text' 'json'; return ;;\n",
+		"\t'myapp create') compadd -- '--name' '--verbose' '-v' '--output' '-o' ;;\n",
+		"\tcompdef _myapp myapp\n",
+	} {
+		if !strings.Contains(zsh, want) {
+			t.Errorf("zsh: missing %q", want)
+		}
+	}
+
+	fish, err := ffcomplete.Fish(newTestCommand())
+	if err != nil {
+		t.Fatalf("Fish: %v", err)
+	}
+	for _, want := range []string{
+		"\t\tcase 'myapp:create' 'myapp:new'\n",
+		`complete -c myapp -n 'test (__myapp_cmdpath) = "myapp"' -a 'create new' -d 'create an object'` + "\n",
+		`complete -c myapp -n 'test (__myapp_cmdpath) = "myapp"' -s 'o' -l 'output' -x -a 'text json' -d 'output format'` + "\n",
+		`complete -c myapp -n 'test (__myapp_cmdpath) = "myapp create"' -l 'name' -r -d 'name of the object'` + "\n",
+	} {
+		if !strings.Contains(fish, want) {
+			t.Errorf("fish: missing %q", want)
+		}
+	}
+
+	for name, script := range map[string]string{"zsh": zsh, "fish": fish} {
+		if strings.Contains(script, "debug") || strings.Contains(script, "secret") {
+			t.Errorf("%s: hidden command or flag included", name)
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ffcomplete.Bash(nil); err == nil {
+		t.Errorf("nil root: want error, have none")
+	}
+
+	root := &ff.Command{Name: "myapp", Subcommands: []*ff.Command{{Name: "bad name"}}}
+	if _, err := ffcomplete.Bash(root); err == nil {
+		t.Errorf("bad subcommand name: want error, have none")
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"strconv"
 	"strings"
+
+	"github.com/peterbourgon/ff/v4"
 )
 
 // Parse is a parser for .env files. Each line is tokenized on the first `=`
@@ -17,10 +19,29 @@ import (
 // quoted", control characters like `\n` are expanded. Lines beginning with `#`
 // are interpreted as comments. End-of-line comments are not supported.
 //
+// A line with an empty value, like FOO=, is an invalid line. Use [Parser] to
+// accept such lines when [ff.WithBooleanPresenceTrue] is provided.
+//
 // The parser respects the [ff.WithEnvVarPrefix] option. For example, if parse
 // is called with an env var prefix MYPROG, then both FOO=bar and MYPROG_FOO=bar
 // would set a flag named foo.
 func Parse(r io.Reader, set func(name, value string) error) error {
+	return parse(r, set, false)
+}
+
+// Parser is an [ff.ConfigFileParser] for .env files, provided via
+// [ff.WithConfigFileParserInfo]. It behaves like [Parse], except that when the
+// parse uses [ff.WithBooleanPresenceTrue], a line with an empty value, like
+// FOO=, provides the empty string as the value, which sets a boolean flag to
+// true.
+type Parser struct{}
+
+// Parse implements [ff.ConfigFileParser].
+func (Parser) Parse(r io.Reader, set func(name, value string) error, info ff.ParseInfo) error {
+	return parse(r, set, info.BooleanPresenceTrue)
+}
+
+func parse(r io.Reader, set func(name, value string) error, allowEmpty bool) error {
 	s := bufio.NewScanner(r)
 	for s.Scan() {
 		line := strings.TrimSpace(s.Text())
@@ -46,7 +67,7 @@ func Parse(r io.Reader, set func(name, value string) error) error {
 			return fmt.Errorf("%w: %s", ErrInvalidLine, line)
 		}
 
-		if len(value) <= 0 {
+		if len(value) <= 0 && !allowEmpty {
 			return fmt.Errorf("%w: %s", ErrInvalidLine, line)
 		}
 
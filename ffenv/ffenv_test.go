@@ -40,6 +40,26 @@ func TestEnvFileParser(t *testing.T) {
 			ConfigFile: "testdata/no-value.env",
 			Want:       fftest.Vars{WantParseErrorIs: ffenv.ErrInvalidLine},
 		},
+		{
+			ConfigFile: "testdata/no-equals.env",
+			Want:       fftest.Vars{WantParseErrorIs: ffenv.ErrInvalidLine},
+		},
+		{
+			ConfigFile: "testdata/presence.env",
+			Want:       fftest.Vars{WantParseErrorIs: ffenv.ErrInvalidLine},
+		},
+		{
+			Name:       "presence.env with Parser",
+			ConfigFile: "testdata/presence.env",
+			Options:    []ff.Option{ff.WithConfigFileParserInfo(ffenv.Parser{})},
+			Want:       fftest.Vars{WantParseErrorIs: ffenv.ErrInvalidLine},
+		},
+		{
+			Name:       "presence.env with Parser and boolean presence true",
+			ConfigFile: "testdata/presence.env",
+			Options:    []ff.Option{ff.WithConfigFileParserInfo(ffenv.Parser{}), ff.WithBooleanPresenceTrue()},
+			Want:       fftest.Vars{S: "present", B: true},
+		},
 		{
 			ConfigFile: "testdata/spaces.env",
 			Want:       fftest.Vars{X: []string{"1", "2", "3", "4", "5", " 6", " 7 ", " 8 ", "9"}},
@@ -50,6 +70,7 @@ func TestEnvFileParser(t *testing.T) {
 		},
 		{
 			ConfigFile: "testdata/capitalization.env",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "hello", I: 12345},
 		},
 		{
@@ -0,0 +1,139 @@
+package ffhelp
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Colors are ANSI escape sequences used to colorize help text. Each sequence
+// is written before the corresponding text, and followed by a reset. An empty
+// sequence leaves the corresponding text uncolored.
+type Colors struct {
+	// Title is used for section titles, e.g. FLAGS.
+	Title string
+
+	// Flag is used for flag names in FLAGS sections, e.g. -f, --foo.
+	Flag string
+
+	// Placeholder is used for flag placeholders in FLAGS sections, e.g. STRING.
+	Placeholder string
+}
+
+// DefaultColors are used by [WithColor] if no colors are provided via
+// [WithColors]:
+// bold section titles, cyan flag names, and yellow placeholders.
+var DefaultColors = Colors{
+	Title:       "\x1b[1m",
+	Flag:        "\x1b[36m",
+	Placeholder: "\x1b[33m",
+}
+
+// colorReset is written after every colorized piece of text.
+const colorReset = "\x1b[0m"
+
+// Colorize returns a copy of the help with ANSI colors applied to section
+// titles, and to the flag names and placeholders in FLAGS sections. Colors are
+// always applied, regardless of the output device. See [WithColor] to apply
+// colors only when writing to a terminal.
+//
+// Colors are applied to the already-aligned lines of each section, so they
+// don't affect alignment. Sections with LineColumns set, e.g. SUBCOMMANDS, are
+// aligned when they're written, so only their titles are colorized.
+func (h Help) Colorize(c Colors) Help {
+	colorized := make(Help, len(h))
+	for i, s := range h {
+		if s.Title != "" {
+			s.Title = colorize(c.Title, s.Title)
+		}
+		if strings.HasPrefix(h[i].Title, "FLAGS") && !s.LineColumns {
+			lines := make([]string, len(s.Lines))
+			for j, line := range s.Lines {
+				lines[j] = colorizeFlagLine(line, c)
+			}
+			s.Lines = lines
+		}
+		colorized[i] = s
+	}
+	return colorized
+}
+
+// colorize applies the colors configured via [WithColor] and [WithColors], if
+// enabled, and if os.Stderr is a color terminal.
+func (cfg helpConfig) colorize(h Help) Help {
+	if !cfg.color || !IsColorTerminal(os.Stderr) {
+		return h
+	}
+	c := cfg.colors
+	if c == (Colors{}) {
+		c = DefaultColors
+	}
+	return h.Colorize(c)
+}
+
+// IsColorTerminal returns true if w is a terminal which should receive colored
+// output. That's the case if w is an *os.File representing a character device,
+// the NO_COLOR environment variable is empty or unset, and the TERM environment
+// variable isn't "dumb". See https://no-color.org.
+func IsColorTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// colorizeFlagLine colorizes the flag names and placeholders in a line of a
+// FLAGS section, produced by [FlagSpec]. The spec is separated from the usage
+// text by at least tabWriterPadding spaces, or by a tab in the layout produced
+// by [FlagsPrintDefaultsStyle]. Lines which don't start with a flag
+// name, e.g. wrapped usage text, are returned unchanged.
+func colorizeFlagLine(line string, c Colors) string {
+	var (
+		rest = strings.TrimLeft(line, " ")
+		lead = line[:len(line)-len(rest)]
+	)
+	if !strings.HasPrefix(rest, "-") {
+		return line
+	}
+
+	spec, usage := rest, ""
+	if index := strings.Index(rest, strings.Repeat(" ", tabWriterPadding)); index >= 0 {
+		spec, usage = rest[:index], rest[index:]
+	}
+	if index := strings.IndexByte(spec, '\t'); index >= 0 {
+		spec, usage = spec[:index], spec[index:]+usage // PrintDefaults style
+	}
+
+	tokens := strings.Split(spec, " ")
+	for i, token := range tokens {
+		switch {
+		case token == "":
+			continue
+		case strings.HasPrefix(token, "-"):
+			name, comma := strings.CutSuffix(token, ",")
+			tokens[i] = colorize(c.Flag, name)
+			if comma {
+				tokens[i] += ","
+			}
+		default:
+			tokens[i] = colorize(c.Placeholder, token)
+		}
+	}
+
+	return lead + strings.Join(tokens, " ") + usage
+}
+
+func colorize(color, s string) string {
+	if color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
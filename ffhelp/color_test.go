@@ -0,0 +1,79 @@
+package ffhelp_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/peterbourgon/ff/v4"
+	"github.com/peterbourgon/ff/v4/ffhelp"
+	"github.com/peterbourgon/ff/v4/fftest"
+)
+
+func TestHelpColorize(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	fs.Duration('d', "dur", 0, "duration flag")
+	fs.BoolLong("verbose", "verbose output")
+
+	colors := ffhelp.Colors{Title: "<T>", Flag: "<F>", Placeholder: "<P>"}
+	want := fftest.UnindentString(`
+		<T>NAME` + "\x1b[0m" + `
+		  fftest
+
+		<T>FLAGS` + "\x1b[0m" + `
+		  <F>-d` + "\x1b[0m" + `, <F>--dur` + "\x1b[0m" + ` <P>DURATION` + "\x1b[0m" + `   duration flag (default: 0s)
+		      <F>--verbose` + "\x1b[0m" + `        verbose output
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).Colorize(colors).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	want = fftest.UnindentString(`
+		<T>FLAGS` + "\x1b[0m" + `
+		  <F>-d` + "\x1b[0m" + `, <F>--dur` + "\x1b[0m" + ` <P>duration` + "\x1b[0m" + `
+		    	duration flag
+		  <F>--verbose` + "\x1b[0m" + `
+		    	verbose output
+	`)
+	have = fftest.UnindentString(ffhelp.Help{ffhelp.NewFlagsSectionPrintDefaultsStyle(fs)}.Colorize(colors).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestWithColor(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	fs.Duration('d', "dur", 0, "duration flag")
+
+	// Colors are only applied when os.Stderr is a color terminal, which it
+	// usually isn't under go test.
+	colors := ffhelp.Colors{Title: "<T>", Flag: "<F>", Placeholder: "<P>"}
+	plain := ffhelp.Flags(fs)
+	want := plain.String()
+	if ffhelp.IsColorTerminal(os.Stderr) {
+		want = plain.Colorize(colors).String()
+	}
+	if have := ffhelp.Flags(fs, ffhelp.WithColor(true), ffhelp.WithColors(colors)).String(); want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	if want, have := plain.String(), ffhelp.Flags(fs, ffhelp.WithColor(false), ffhelp.WithColors(colors)).String(); want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestIsColorTerminal(t *testing.T) {
+	if ffhelp.IsColorTerminal(&bytes.Buffer{}) {
+		t.Errorf("buffer: want false, have true")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ffhelp.IsColorTerminal(nil) {
+		t.Errorf("NO_COLOR: want false, have true")
+	}
+}
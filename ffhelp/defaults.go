@@ -0,0 +1,168 @@
+package ffhelp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peterbourgon/ff/v4"
+)
+
+// FlagsPrintDefaultsStyle returns help text for every flag available to fs,
+// in the layout produced by [flag.FlagSet.PrintDefaults]. It's intended for
+// programs migrating from package flag, whose users expect identical help.
+//
+// Each flag is printed on its own line, followed by its usage on the next line,
+// indented with a tab. As a special case, single-character flags without a
+// placeholder, which are typically bools, are printed with their usage on the
+// same line. Newlines in usage strings are indented to match.
+//
+// Placeholders are derived like package flag: a `backticked` substring of the
+// usage string is used as-is, and the backticks are removed; otherwise, the
+// placeholder is the lowercase form of [ff.Flag.GetPlaceholder], e.g. duration
+// or string. Bool flags never have placeholders. Non-zero defaults are appended
+// as (default x), and are quoted for string flags.
+//
+// Hidden flags are omitted, unless the [ShowHidden] option is provided. Other
+// options are ignored.
+func FlagsPrintDefaultsStyle(fs ff.Flags, options ...Option) string {
+	var (
+		cfg = makeHelpConfig(options)
+		sb  strings.Builder
+	)
+	fs.WalkFlags(func(f ff.Flag) error {
+		if f.IsHidden() && !cfg.showHidden {
+			return nil
+		}
+
+		name, usage := unquoteUsage(f)
+		if f.IsBoolFlag() {
+			name = "" // bool flags never have placeholders
+		}
+
+		var b strings.Builder
+		b.WriteString("  " + printDefaultsNames(f))
+		if name != "" {
+			b.WriteString(" " + name)
+		}
+
+		// Like package flag: "  -x" is short enough to share a line with usage.
+		if b.Len() <= 4 {
+			b.WriteString("\t")
+		} else {
+			b.WriteString("\n    \t")
+		}
+		b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
+
+		if def := f.GetDefault(); !isZeroDefault(def) {
+			if sf, ok := f.(interface{ IsStringFlag() bool }); ok && sf.IsStringFlag() {
+				fmt.Fprintf(&b, " (default %q)", def)
+			} else {
+				fmt.Fprintf(&b, " (default %v)", def)
+			}
+		}
+
+		sb.WriteString(b.String() + "\n")
+		return nil
+	})
+	return sb.String()
+}
+
+// NewFlagsSectionPrintDefaultsStyle returns a single FLAGS section containing
+// the help text produced by [FlagsPrintDefaultsStyle]. The lines carry their
+// own indentation, so the section has no line prefix.
+func NewFlagsSectionPrintDefaultsStyle(fs ff.Flags, options ...Option) Section {
+	return Section{
+		Title: "FLAGS",
+		Lines: splitLines(FlagsPrintDefaultsStyle(fs, options...)),
+	}
+}
+
+// CommandPrintDefaultsStyle is like [Command], but renders the flags of the
+// selected command in a single FLAGS section, in the layout produced by
+// [flag.FlagSet.PrintDefaults]. Flag usage is printed on the line after each
+// flag, so it doesn't shift when longer flags are added. See
+// [FlagsPrintDefaultsStyle] for details. Options apply to the other sections,
+// as with Command, and [ShowHidden] also applies to the FLAGS section.
+func CommandPrintDefaultsStyle(cmd *ff.Command, options ...Option) Help {
+	var (
+		cfg  = makeHelpConfig(options)
+		help Help
+	)
+
+	if selected := cmd.GetSelected(); selected != nil {
+		cmd = selected
+	}
+
+	commandTitle := cmd.Name
+	if cmd.ShortHelp != "" {
+		commandTitle = fmt.Sprintf("%s -- %s", commandTitle, cmd.ShortHelp)
+	}
+	help = append(help, NewSection("COMMAND", commandTitle))
+
+	if cmd.Usage != "" {
+		help = append(help, NewSection("USAGE", cmd.Usage))
+	}
+
+	if cmd.LongHelp != "" {
+		help = append(help, newLongHelpSection(cmd.LongHelp, cfg))
+	}
+
+	if hasVisibleSubcommands(cmd, cfg.showHidden) {
+		help = append(help, NewSubcommandsSection(cmd.Subcommands, options...))
+	}
+
+	if cmd.Flags != nil {
+		if section := NewFlagsSectionPrintDefaultsStyle(cmd.Flags, options...); len(section.Lines) > 0 {
+			help = append(help, section)
+		}
+	}
+
+	if footer := getFooter(cmd); footer != "" {
+		help = append(help, NewUntitledSection(footer))
+	}
+
+	return cfg.colorize(help)
+}
+
+// printDefaultsNames returns the hyphenated names of the flag, e.g. -f, --foo.
+// Flags from a stdlib flag set adapter have a single name, with one hyphen.
+func printDefaultsNames(f ff.Flag) string {
+	names := FormatFlag(f, "%+s")
+	if sf, ok := f.(interface{ IsStdFlag() bool }); ok && sf.IsStdFlag() {
+		names = strings.Replace(names, "--", "-", 1)
+	}
+	return names
+}
+
+// unquoteUsage is like [flag.UnquoteUsage], but for [ff.Flag].
+func unquoteUsage(f ff.Flag) (name, usage string) {
+	usage = f.GetUsage()
+	if first := strings.IndexByte(usage, '`'); first >= 0 {
+		if n := strings.IndexByte(usage[first+1:], '`'); n >= 0 {
+			name = usage[first+1 : first+1+n]
+			usage = usage[:first] + name + usage[first+1+n+1:]
+			return name, usage
+		}
+	}
+
+	name = strings.ToLower(f.GetPlaceholder())
+	switch name {
+	case "float64":
+		name = "float"
+	case "int64":
+		name = "int"
+	case "uint64":
+		name = "uint"
+	}
+	return name, usage
+}
+
+// isZeroDefault returns true for defaults that package flag would omit.
+func isZeroDefault(def string) bool {
+	switch def {
+	case "", "false", "0", "0s", "[]":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+package ffhelp_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/peterbourgon/ff/v4"
+	"github.com/peterbourgon/ff/v4/ffhelp"
+	"github.com/peterbourgon/ff/v4/fftest"
+	"github.com/peterbourgon/ff/v4/ffval"
+)
+
+func TestFlagsPrintDefaultsStyle(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stdlib", func(t *testing.T) {
+		stdfs := flag.NewFlagSet("stdlib", flag.ContinueOnError)
+		stdfs.Bool("v", false, "verbose logging")
+		stdfs.Bool("debug", true, "debug mode")
+		stdfs.Duration("timeout", 3*time.Second, "request timeout")
+		stdfs.Duration("z", 0, "zero duration")
+		stdfs.Float64("ratio", 0.5, "sample ratio")
+		stdfs.Int("n", 0, "number of workers")
+		stdfs.String("addr", "localhost:8080", "listen `address`")
+		stdfs.String("name", "", "name of the thing\nwhich spans\nmultiple lines")
+
+		var want strings.Builder
+		stdfs.SetOutput(&want)
+		stdfs.PrintDefaults()
+
+		have := ffhelp.FlagsPrintDefaultsStyle(ff.NewFlagSetFrom("stdlib", stdfs))
+		if want.String() != have {
+			t.Error(fftest.DiffString(want.String(), have))
+		}
+	})
+
+	t.Run("native", func(t *testing.T) {
+		fs := ff.NewFlagSet("native")
+		fs.Bool('v', "verbose", "verbose logging")
+		fs.BoolShort('q', "quiet mode")
+		fs.Duration('d', "delay", time.Second, "delay between `attempts`")
+		fs.StringLong("name", "foo", "name of the thing\nwhich spans two lines")
+
+		want := strings.Join([]string{
+			"  -v, --verbose",
+			"    \tverbose logging",
+			"  -q\tquiet mode",
+			"  -d, --delay attempts",
+			"    \tdelay between attempts (default 1s)",
+			"  --name string",
+			"    \tname of the thing",
+			"    \twhich spans two lines (default \"foo\")",
+		}, "\n") + "\n"
+		have := ffhelp.FlagsPrintDefaultsStyle(fs)
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		fs := ff.NewFlagSet("hidden")
+		fs.BoolShort('q', "quiet mode")
+		if _, err := fs.AddFlag(ff.FlagConfig{LongName: "secret", Value: new(ffval.Bool), Usage: "secret flag", Hidden: true}); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "  -q\tquiet mode\n"
+		if have := ffhelp.FlagsPrintDefaultsStyle(fs); want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+
+		want = "  -q\tquiet mode\n  --secret\n    \tsecret flag\n"
+		if have := ffhelp.FlagsPrintDefaultsStyle(fs, ffhelp.ShowHidden()); want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+}
+
+func TestCommandPrintDefaultsStyle(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("root")
+	fs.BoolShort('q', "quiet mode")
+	fs.Duration('t', "timeout", time.Second, "request timeout")
+	fs.StringLong("name", "", "name of the thing\nwhich spans two lines")
+	if _, err := fs.AddFlag(ff.FlagConfig{LongName: "secret", Value: new(ffval.Bool), Usage: "secret flag", Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+	root := &ff.Command{
+		Name:      "root",
+		ShortHelp: "the root command",
+		Usage:     "root [FLAGS]",
+		Flags:     fs,
+	}
+
+	want := strings.Join([]string{
+		"COMMAND",
+		"  root -- the root command",
+		"",
+		"USAGE",
+		"  root [FLAGS]",
+		"",
+		"FLAGS",
+		"  -q\tquiet mode",
+		"  -t, --timeout duration",
+		"    \trequest timeout (default 1s)",
+		"  --name string",
+		"    \tname of the thing",
+		"    \twhich spans two lines",
+	}, "\n") + "\n"
+	have := ffhelp.CommandPrintDefaultsStyle(root).String()
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
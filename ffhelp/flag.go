@@ -141,6 +141,12 @@ func MakeFlagSpec(f ff.Flag) FlagSpec {
 	if def := f.GetDefault(); def != "" {
 		usage = fmt.Sprintf("%s (default: %s)", usage, def)
 	}
+	if rf, ok := f.(interface{ IsRequired() bool }); ok && rf.IsRequired() && !f.IsSet() {
+		usage = fmt.Sprintf("%s (required)", usage)
+	}
+	if df, ok := f.(interface{ GetDeprecated() string }); ok && df.GetDeprecated() != "" {
+		usage = fmt.Sprintf("%s (deprecated: %s)", usage, df.GetDeprecated())
+	}
 
 	return FlagSpec{
 		Flag:  f,
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/peterbourgon/ff/v4"
 )
@@ -11,30 +12,37 @@ import (
 // Help represents help output for a flag set, command, etc.
 type Help []Section
 
-// Flags returns a default [Help] value representing fs. If usage strings are
-// provided, they're used as lines in a USAGE section, included before any FLAGS
-// sections.
+// Flags returns a default [Help] value representing fs. If usage lines are
+// provided via [WithUsage], they're used as lines in a USAGE section, included
+// before any FLAGS sections. Other options are passed to [NewFlagsSections].
 //
 // This function is meant as reasonable default for most users, and as an
 // example. Callers who want different help output should implement their own
 // [Help] value constructors like this one.
-func Flags(fs ff.Flags, usage ...string) Help {
+func Flags(fs ff.Flags, options ...Option) Help {
+	cfg := makeHelpConfig(options)
+
 	var help Help
 	help = append(help, NewSection("NAME", fs.GetName()))
-	if len(usage) > 0 {
-		help = append(help, NewSection("USAGE", usage...))
+	if len(cfg.usage) > 0 {
+		help = append(help, NewSection("USAGE", cfg.usage...))
 	}
-	help = append(help, NewFlagsSections(fs)...)
-	return help
+	help = append(help, NewFlagsSections(fs, options...)...)
+	return cfg.colorize(help)
 }
 
-// Command returns a standard [Help] for the given command.
+// Command returns a standard [Help] for the given command. Options are passed
+// to the section constructors, e.g. [NewFlagsSections]. If a width is given via
+// [WithWidth], the command's long help is rewrapped via [NewLongHelpSection].
 //
 // This function is meant as reasonable default for most users, and as an
 // example. Callers who want different help output should implement their own
 // [Help] value constructors like this one.
-func Command(cmd *ff.Command) Help {
-	var help Help
+func Command(cmd *ff.Command, options ...Option) Help {
+	var (
+		cfg  = makeHelpConfig(options)
+		help Help
+	)
 
 	if selected := cmd.GetSelected(); selected != nil {
 		cmd = selected
@@ -51,16 +59,113 @@ func Command(cmd *ff.Command) Help {
 	}
 
 	if cmd.LongHelp != "" {
-		help = append(help, NewUntitledSection(cmd.LongHelp))
+		help = append(help, newLongHelpSection(cmd.LongHelp, cfg))
+	}
+
+	if hasVisibleSubcommands(cmd, cfg.showHidden) {
+		help = append(help, NewSubcommandsSection(cmd.Subcommands, options...))
+	}
+
+	help = append(help, NewFlagsSections(cmd.Flags, options...)...)
+
+	if footer := getFooter(cmd); footer != "" {
+		help = append(help, NewUntitledSection(footer))
+	}
+
+	return cfg.colorize(help)
+}
+
+// CommandWithParents is like [Command], but describes the selected command in
+// the context of its parent commands. The USAGE section begins with the full
+// command chain, e.g. "root [flags] foo [flags] bar [flags]", followed by the
+// command's Usage string, if any. The FLAGS sections include the flags of every
+// parent command, grouped by command name. See [NewCommandFlagsSections].
+func CommandWithParents(cmd *ff.Command, options ...Option) Help {
+	var (
+		cfg  = makeHelpConfig(options)
+		help Help
+	)
+
+	if selected := cmd.GetSelected(); selected != nil {
+		cmd = selected
+	}
+
+	commandTitle := cmd.Name
+	if cmd.ShortHelp != "" {
+		commandTitle = fmt.Sprintf("%s -- %s", commandTitle, cmd.ShortHelp)
+	}
+	help = append(help, NewSection("COMMAND", commandTitle))
+
+	usage := []string{commandChain(cmd, cfg.showHidden)}
+	if cmd.Usage != "" {
+		usage = append(usage, cmd.Usage)
+	}
+	help = append(help, NewSection("USAGE", usage...))
+
+	if cmd.LongHelp != "" {
+		help = append(help, newLongHelpSection(cmd.LongHelp, cfg))
+	}
+
+	if hasVisibleSubcommands(cmd, cfg.showHidden) {
+		help = append(help, NewSubcommandsSection(cmd.Subcommands, options...))
+	}
+
+	help = append(help, NewCommandFlagsSections(cmd, options...)...)
+
+	if footer := getFooter(cmd); footer != "" {
+		help = append(help, NewUntitledSection(footer))
+	}
+
+	return cfg.colorize(help)
+}
+
+// commandChain returns the names of cmd and all of its parents, from the root
+// command down, each followed by "[flags]" if that command defines visible
+// flags of its own, or any flags of its own if showHidden is true.
+func commandChain(cmd *ff.Command, showHidden bool) string {
+	var chain []string
+	for c := cmd; c != nil; c = c.GetParent() {
+		elem := c.Name
+		if hasVisibleFlags(c.Flags, showHidden) {
+			elem += " [flags]"
+		}
+		chain = append([]string{elem}, chain...)
 	}
+	return strings.Join(chain, " ")
+}
 
-	if len(cmd.Subcommands) > 0 {
-		help = append(help, NewSubcommandsSection(cmd.Subcommands))
+func hasVisibleFlags(fs ff.Flags, showHidden bool) bool {
+	if fs == nil {
+		return false
 	}
+	var visible bool
+	fs.WalkFlags(func(f ff.Flag) error {
+		if f.GetFlags() == fs && (!f.IsHidden() || showHidden) {
+			visible = true
+		}
+		return nil
+	})
+	return visible
+}
 
-	help = append(help, NewFlagsSections(cmd.Flags)...)
+// getFooter returns the footer of the command, or of its nearest ancestor with
+// a footer.
+func getFooter(cmd *ff.Command) string {
+	for c := cmd; c != nil; c = c.GetParent() {
+		if c.Footer != "" {
+			return c.Footer
+		}
+	}
+	return ""
+}
 
-	return help
+func hasVisibleSubcommands(cmd *ff.Command, showHidden bool) bool {
+	for _, sc := range cmd.Subcommands {
+		if !sc.Hidden || showHidden {
+			return true
+		}
+	}
+	return false
 }
 
 // WriteTo implements [io.WriterTo].
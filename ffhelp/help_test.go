@@ -1,11 +1,15 @@
 package ffhelp_test
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/ffhelp"
 	"github.com/peterbourgon/ff/v4/fftest"
+	"github.com/peterbourgon/ff/v4/ffval"
 )
 
 func TestFlagsHelp(t *testing.T) {
@@ -53,7 +57,7 @@ func TestFlagsHelp(t *testing.T) {
 			  -d, --dur DURATION   duration flag (default: 0s)
 			  -s, --str STRING     string flag
 		`)
-		have := fftest.UnindentString(ffhelp.Flags(fs, loremIpsumSlice...).String())
+		have := fftest.UnindentString(ffhelp.Flags(fs, ffhelp.WithUsage(loremIpsumSlice...)).String())
 		if want != have {
 			t.Error(fftest.DiffString(want, have))
 		}
@@ -80,3 +84,295 @@ func TestFlagsHelp_OnlyLong(t *testing.T) {
 		t.Error(fftest.DiffString(want, have))
 	}
 }
+
+func TestFlagsHelp_Options(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	fs.Bool('v', "verbose", "log more")
+	fs.String('o', "out", "", "output file for the results of the operation")
+
+	want := fftest.UnindentString(`
+		NAME
+		  fftest
+
+		USAGE
+		  fftest [FLAGS]
+
+		FLAGS
+		  -v, --verbose      log more
+		  -o, --out STRING   output file for the
+		                     results of the
+		                     operation
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs, ffhelp.WithUsage("fftest [FLAGS]"), ffhelp.WithWidth(40)).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestFlagsHelp_Required(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if _, err := fs.AddFlag(ff.FlagConfig{
+			LongName: name,
+			Usage:    name + " usage",
+			Value:    new(ffval.String),
+			Required: name != "gamma",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := ff.Parse(fs, []string{}, ff.WithRequiredHelp())
+	if !errors.Is(err, ff.ErrHelp) || !errors.Is(err, ff.ErrMissingRequired) {
+		t.Fatalf("want ErrHelp and ErrMissingRequired, have %v", err)
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  fftest
+
+		FLAGS
+		  --alpha STRING   alpha usage (required)
+		  --beta STRING    beta usage (required)
+		  --gamma STRING   gamma usage
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	err = ff.Parse(fs, []string{"--alpha=a"}, ff.WithRequiredHelp())
+	if !errors.Is(err, ff.ErrHelp) {
+		t.Fatalf("want ErrHelp, have %v", err)
+	}
+	if help := ffhelp.Flags(fs).String(); strings.Contains(help, "alpha usage (required)") || !strings.Contains(help, "beta usage (required)") {
+		t.Errorf("want only beta marked as required, have\n%s", help)
+	}
+}
+
+func TestFlagsHelp_Deprecated(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	for _, cfg := range []ff.FlagConfig{
+		{ShortName: 'n', LongName: "new", Usage: "new usage", Value: new(ffval.String)},
+		{ShortName: 'o', LongName: "old", Usage: "old usage", Value: new(ffval.String), Deprecated: "use --new"},
+		{LongName: "older", Usage: "older usage", Value: new(ffval.String), Deprecated: "use --new", Hidden: true},
+	} {
+		if _, err := fs.AddFlag(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  fftest
+
+		FLAGS
+		  -n, --new STRING   new usage
+		  -o, --old STRING   old usage (deprecated: use --new)
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestFlagsHelp_Hidden(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	fs.String('a', "alpha", "", "alpha usage")
+	if _, err := fs.AddFlag(ff.FlagConfig{ShortName: 'd', LongName: "debug-addr", Usage: "debug usage", Value: new(ffval.String), Hidden: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ff.Parse(fs, []string{"--debug-addr=:8081"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if f, _ := fs.GetFlag("debug-addr"); f.GetValue() != ":8081" {
+		t.Errorf("debug-addr: want %q, have %q", ":8081", f.GetValue())
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  fftest
+
+		FLAGS
+		  -a, --alpha STRING   alpha usage
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	want = fftest.UnindentString(`
+		NAME
+		  fftest
+
+		FLAGS
+		  -a, --alpha STRING        alpha usage
+		  -d, --debug-addr STRING   debug usage
+	`)
+	have = fftest.UnindentString(ffhelp.Flags(fs, ffhelp.ShowHidden()).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestCommandHelp_Footer(t *testing.T) {
+	t.Parallel()
+
+	sub := &ff.Command{
+		Name:      "sub",
+		ShortHelp: "a subcommand",
+		Exec:      func(context.Context, []string) error { return nil },
+	}
+	fs := ff.NewFlagSet("root")
+	fs.Bool('v', "verbose", "log more")
+	root := &ff.Command{
+		Name:        "root",
+		Usage:       "root <SUBCOMMAND>",
+		Footer:      "See https://example.com/docs for more information.",
+		Flags:       fs,
+		Subcommands: []*ff.Command{sub},
+	}
+
+	if err := root.Parse([]string{}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := fftest.UnindentString(`
+		COMMAND
+		  root
+
+		USAGE
+		  root <SUBCOMMAND>
+
+		SUBCOMMANDS
+		  sub   a subcommand
+
+		FLAGS
+		  -v, --verbose   log more
+
+		See https://example.com/docs for more information.
+	`)
+	have := fftest.UnindentString(ffhelp.Command(root).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	if err := root.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := root.Parse([]string{"sub"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want = fftest.UnindentString(`
+		COMMAND
+		  sub -- a subcommand
+
+		See https://example.com/docs for more information.
+	`)
+	have = fftest.UnindentString(ffhelp.Command(root).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestCommandHelp_LongHelp(t *testing.T) {
+	t.Parallel()
+
+	cmd := &ff.Command{
+		Name:      "root",
+		ShortHelp: "the root command",
+		LongHelp: `
+			Root does many things, and this sentence is long enough that it
+			needs to be rewrapped.
+
+			It also has a second paragraph.
+		`,
+		Exec: func(context.Context, []string) error { return nil },
+	}
+
+	if err := cmd.Parse([]string{}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := fftest.UnindentString(`
+		COMMAND
+		  root -- the root command
+
+		  Root does many things, and this sentence is
+		  long enough that it needs to be rewrapped.
+
+		  It also has a second paragraph.
+	`)
+	have := fftest.UnindentString(ffhelp.Command(cmd, ffhelp.WithWidth(48)).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestCommandWithParentsHelp(t *testing.T) {
+	t.Parallel()
+
+	rootFlags := ff.NewFlagSet("root")
+	rootFlags.Bool('v', "verbose", "log more")
+	fooFlags := ff.NewFlagSet("foo")
+	fooFlags.String('r', "region", "us-east", "region to use")
+	barFlags := ff.NewFlagSet("bar").SetParent(fooFlags)
+	barFlags.Int('n', "count", 3, "number of items")
+
+	bar := &ff.Command{
+		Name:      "bar",
+		ShortHelp: "do the bar thing",
+		Usage:     "bar [FLAGS] <ARG>",
+		Flags:     barFlags,
+		Exec:      func(context.Context, []string) error { return nil },
+	}
+	foo := &ff.Command{
+		Name:        "foo",
+		Flags:       fooFlags,
+		Subcommands: []*ff.Command{bar},
+	}
+	root := &ff.Command{
+		Name:        "root",
+		Flags:       rootFlags,
+		Subcommands: []*ff.Command{foo},
+	}
+
+	if err := root.Parse([]string{"foo", "bar"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := fftest.UnindentString(`
+		COMMAND
+		  bar -- do the bar thing
+
+		USAGE
+		  root [flags] foo [flags] bar [flags]
+		  bar [FLAGS] <ARG>
+
+		FLAGS (bar)
+		  -n, --count INT       number of items (default: 3)
+
+		FLAGS (foo)
+		  -r, --region STRING   region to use (default: us-east)
+
+		FLAGS (root)
+		  -v, --verbose         log more
+	`)
+	have := fftest.UnindentString(ffhelp.CommandWithParents(root).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
@@ -0,0 +1,122 @@
+package ffhelp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peterbourgon/ff/v4"
+)
+
+// Markdown returns a Markdown document describing cmd. It's intended for
+// generating documentation which can be committed alongside the program.
+//
+// The document starts with an H1 heading containing the command name, followed
+// by the short help, the usage in a code block, and the long help as a
+// paragraph. Then, a Subcommands section lists every non-hidden subcommand in a
+// table, and a Flags section lists every non-hidden flag available to the
+// command, including parent flags, in a table with columns for the flag names,
+// placeholder, default value, and usage.
+//
+// Subcommands aren't described in detail. Use [MarkdownRecursive] to include
+// them as well.
+func Markdown(cmd *ff.Command) string {
+	var sb strings.Builder
+	writeMarkdown(&sb, cmd, cmd.Name, 1, false)
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// MarkdownRecursive is like [Markdown], but also describes every non-hidden
+// subcommand, recursively, after the command itself. Each subcommand is nested
+// under a heading one level deeper than its parent, containing the full path to
+// the subcommand, e.g. "## root sub".
+func MarkdownRecursive(cmd *ff.Command) string {
+	var sb strings.Builder
+	writeMarkdown(&sb, cmd, cmd.Name, 1, true)
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+func writeMarkdown(sb *strings.Builder, cmd *ff.Command, path string, level int, recursive bool) {
+	fmt.Fprintf(sb, "%s %s\n\n", markdownHeading(level), path)
+
+	if cmd.ShortHelp != "" {
+		fmt.Fprintf(sb, "%s\n\n", cmd.ShortHelp)
+	}
+
+	if cmd.Usage != "" {
+		fmt.Fprintf(sb, "```\n%s\n```\n\n", strings.TrimSpace(cmd.Usage))
+	}
+
+	if cmd.LongHelp != "" {
+		fmt.Fprintf(sb, "%s\n\n", RewrapAt(cmd.LongHelp, 80))
+	}
+
+	if hasVisibleSubcommands(cmd, false) {
+		fmt.Fprintf(sb, "%s Subcommands\n\n", markdownHeading(level+1))
+		sb.WriteString("| Name | Description |\n")
+		sb.WriteString("| --- | --- |\n")
+		for _, sc := range cmd.Subcommands {
+			if sc.Hidden {
+				continue
+			}
+			fmt.Fprintf(sb, "| `%s` | %s |\n", commandNames(sc), markdownCell(sc.ShortHelp))
+		}
+		sb.WriteString("\n")
+	}
+
+	var flags []ff.Flag
+	if cmd.Flags != nil {
+		cmd.Flags.WalkFlags(func(f ff.Flag) error {
+			if !f.IsHidden() {
+				flags = append(flags, f)
+			}
+			return nil
+		})
+	}
+	if len(flags) > 0 {
+		fmt.Fprintf(sb, "%s Flags\n\n", markdownHeading(level+1))
+		sb.WriteString("| Flag | Placeholder | Default | Usage |\n")
+		sb.WriteString("| --- | --- | --- | --- |\n")
+		for _, f := range flags {
+			fmt.Fprintf(sb, "| %s | %s | %s | %s |\n",
+				markdownCode(printDefaultsNames(f)),
+				markdownCode(f.GetPlaceholder()),
+				markdownCode(f.GetDefault()),
+				markdownCell(f.GetUsage()),
+			)
+		}
+		sb.WriteString("\n")
+	}
+
+	if recursive {
+		for _, sc := range cmd.Subcommands {
+			if sc.Hidden {
+				continue
+			}
+			writeMarkdown(sb, sc, path+" "+sc.Name, level+1, recursive)
+		}
+	}
+}
+
+// markdownHeading returns the heading prefix for level, e.g. ## for level 2.
+// Markdown doesn't support headings deeper than level 6.
+func markdownHeading(level int) string {
+	if level > 6 {
+		level = 6
+	}
+	return strings.Repeat("#", level)
+}
+
+// markdownCell escapes s so that it can be used in a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\n", "<br>")
+	return s
+}
+
+// markdownCode wraps s in backticks, or returns the empty string if s is empty.
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
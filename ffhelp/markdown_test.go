@@ -0,0 +1,88 @@
+package ffhelp_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/peterbourgon/ff/v4"
+	"github.com/peterbourgon/ff/v4/ffhelp"
+	"github.com/peterbourgon/ff/v4/fftest"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	rootFlags := ff.NewFlagSet("root")
+	rootFlags.Bool('v', "verbose", "log more")
+	root := &ff.Command{
+		Name:      "root",
+		ShortHelp: "root command",
+		Usage:     "root [FLAGS] <SUBCOMMAND>",
+		LongHelp:  "Root does\nmany things.",
+		Flags:     rootFlags,
+	}
+
+	addFlags := ff.NewFlagSet("add").SetParent(rootFlags)
+	addFlags.String('n', "name", "default", "name of the thing | or not")
+	add := &ff.Command{
+		Name:      "add",
+		ShortHelp: "add a thing",
+		Flags:     addFlags,
+	}
+	hidden := &ff.Command{
+		Name:   "hidden",
+		Hidden: true,
+	}
+	root.Subcommands = []*ff.Command{add, hidden}
+
+	rootWant := strings.Join([]string{
+		"# root",
+		"",
+		"root command",
+		"",
+		"```",
+		"root [FLAGS] <SUBCOMMAND>",
+		"```",
+		"",
+		"Root does many things.",
+		"",
+		"## Subcommands",
+		"",
+		"| Name | Description |",
+		"| --- | --- |",
+		"| `add` | add a thing |",
+		"",
+		"## Flags",
+		"",
+		"| Flag | Placeholder | Default | Usage |",
+		"| --- | --- | --- | --- |",
+		"| `-v, --verbose` |  |  | log more |",
+		"",
+	}, "\n")
+
+	t.Run("single", func(t *testing.T) {
+		if want, have := rootWant, ffhelp.Markdown(root); want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+
+	t.Run("recursive", func(t *testing.T) {
+		want := rootWant + strings.Join([]string{
+			"",
+			"## root add",
+			"",
+			"add a thing",
+			"",
+			"### Flags",
+			"",
+			"| Flag | Placeholder | Default | Usage |",
+			"| --- | --- | --- | --- |",
+			"| `-n, --name` | `STRING` | `default` | name of the thing \\| or not |",
+			"| `-v, --verbose` |  |  | log more |",
+			"",
+		}, "\n")
+		if have := ffhelp.MarkdownRecursive(root); want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package ffhelp
+
+// Option controls some aspect of help output. Options are accepted by [Flags],
+// [Command], and the section constructors they use, e.g. [NewFlagsSections],
+// and can be combined freely. Options which don't apply to a given constructor
+// are ignored.
+type Option func(*helpConfig)
+
+type helpConfig struct {
+	usage []string
+	width int // wrap flag usage text so lines fit in width, if > 0
+
+	sorted       bool // sort flags by name within each section
+	sortTogether bool // sort all flags together, in a single section
+
+	showHidden bool // include hidden flags and subcommands
+
+	color  bool   // colorize output, if writing to a color terminal
+	colors Colors // colors to use, or DefaultColors if zero
+}
+
+func makeHelpConfig(options []Option) helpConfig {
+	var cfg helpConfig
+	for _, option := range options {
+		option(&cfg)
+	}
+	return cfg
+}
+
+// WithUsage provides lines for the USAGE section produced by [Flags]. By
+// default, no USAGE section is included. Commands define their USAGE section
+// via [ff.Command.Usage] instead.
+func WithUsage(lines ...string) Option {
+	return func(cfg *helpConfig) {
+		cfg.usage = append(cfg.usage, lines...)
+	}
+}
+
+// WithWidth wraps usage text in FLAGS sections so that lines, including
+// [DefaultLinePrefix], fit within width columns where possible. Wrapped lines
+// are indented to align with the first line of usage text. If width is zero or
+// less, the width of the current TTY is used, or [DefaultFlagsWidth] if it
+// can't be determined. By default, usage text isn't wrapped.
+//
+// The width also applies to the long help of commands, which is normalized via
+// [NewLongHelpSection]. By default, long help is rendered as-is.
+func WithWidth(width int) Option {
+	return func(cfg *helpConfig) {
+		if width <= 0 {
+			width = flagsWidth()
+		}
+		cfg.width = width
+	}
+}
+
+// Sorted sorts flags in FLAGS sections alphabetically by long name, or short
+// name if a flag has no long name. If together is false, flags from parent flag
+// sets remain in their own sections, and are sorted within those sections. If
+// together is true, all flags are sorted together, in a single FLAGS section.
+// By default, flags are listed in the order they were defined.
+func Sorted(together bool) Option {
+	return func(cfg *helpConfig) {
+		cfg.sorted = true
+		cfg.sortTogether = together
+	}
+}
+
+// ShowHidden includes hidden flags in FLAGS sections, and hidden subcommands in
+// SUBCOMMANDS sections. By default, they're omitted.
+func ShowHidden() Option {
+	return func(cfg *helpConfig) {
+		cfg.showHidden = true
+	}
+}
+
+// WithColor colorizes section titles, and flag names and placeholders in FLAGS
+// sections, with ANSI escape sequences, if enabled is true. Since help is
+// produced before it's written, colors are only applied if os.Stderr, where
+// help is conventionally written, is a color terminal, see [IsColorTerminal].
+// That means colors respect the NO_COLOR environment variable, and output which
+// is piped or redirected to a file stays plain. To colorize help regardless of
+// the output device, use [Help.Colorize]. By default, help isn't colorized.
+func WithColor(enabled bool) Option {
+	return func(cfg *helpConfig) {
+		cfg.color = enabled
+	}
+}
+
+// WithColors overrides the colors applied via [WithColor]. It doesn't enable
+// colors by itself. By default, [DefaultColors] are used.
+func WithColors(c Colors) Option {
+	return func(cfg *helpConfig) {
+		cfg.colors = c
+	}
+}
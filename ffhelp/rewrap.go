@@ -84,15 +84,32 @@ var DefaultColumns = 120
 
 // Columns in the current TTY, or [DefaultColumns] if the TTY can't be determined.
 func Columns() int {
+	if cols, ok := detectColumns(); ok {
+		return cols
+	}
+	return DefaultColumns
+}
+
+// DefaultFlagsWidth is used by [WithWidth] when no width is given, and the
+// width of the TTY can't be determined.
+var DefaultFlagsWidth = 80
+
+func flagsWidth() int {
+	if cols, ok := detectColumns(); ok {
+		return cols
+	}
+	return DefaultFlagsWidth
+}
+
+func detectColumns() (int, bool) {
 	getColumns.Do(func() {
 		// Don't run the stty subprocess unless we have to.
 		if cols, err := sttySizeCols(); err == nil {
 			ttyColumns.Store(int64(cols))
-		} else {
-			ttyColumns.Store(int64(DefaultColumns))
 		}
 	})
-	return int(ttyColumns.Load())
+	cols := int(ttyColumns.Load())
+	return cols, cols > 0
 }
 
 var (
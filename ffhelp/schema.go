@@ -0,0 +1,150 @@
+package ffhelp
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/peterbourgon/ff/v4"
+)
+
+// JSONSchema returns a JSON Schema (draft-07) describing the flags available to
+// cmd, including all parent flags. It's intended for validating config which is
+// provided externally, e.g. a JSON config file, before it's parsed.
+//
+// The schema is an object with one property per flag, keyed by the flag's long
+// name, or its short name if it has no long name. If multiple flags have the
+// same key, the first one wins. Each property has a type derived from the typed
+// value of the flag, see [ff.GetAny]: bools are booleans, integers are
+// integers, floats are numbers, slices are arrays, maps are objects, and all
+// other values, including types which implement [fmt.Stringer] like
+// [time.Duration], are strings. Flags with valid values, like [ffval.Enum],
+// produce an enum constraint. The usage string becomes the description, and
+// the default value, if any, becomes the default.
+//
+// Flags which implement `IsRequired() bool` and return true are listed as
+// required properties.
+func JSONSchema(cmd *ff.Command) ([]byte, error) {
+	schema := jsonSchema{
+		Schema:     "http://json-schema.org/draft-07/schema#",
+		Title:      cmd.Name,
+		Type:       "object",
+		Properties: map[string]jsonSchemaProperty{},
+	}
+
+	if cmd.Flags != nil {
+		if err := cmd.Flags.WalkFlags(func(f ff.Flag) error {
+			var key string
+			if long, ok := f.GetLongName(); ok {
+				key = long
+			} else if short, ok := f.GetShortName(); ok {
+				key = string(short)
+			}
+			if _, ok := schema.Properties[key]; ok {
+				return nil
+			}
+
+			schema.Properties[key] = makeJSONSchemaProperty(f)
+			if r, ok := f.(interface{ IsRequired() bool }); ok && r.IsRequired() {
+				schema.Required = append(schema.Required, key)
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("walk flags: %w", err)
+		}
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+type jsonSchema struct {
+	Schema     string                        `json:"$schema"`
+	Title      string                        `json:"title,omitempty"`
+	Type       string                        `json:"type"`
+	Properties map[string]jsonSchemaProperty `json:"properties"`
+	Required   []string                      `json:"required,omitempty"`
+}
+
+type jsonSchemaProperty struct {
+	Type        string              `json:"type"`
+	Description string              `json:"description,omitempty"`
+	Items       *jsonSchemaProperty `json:"items,omitempty"`
+	Enum        []any               `json:"enum,omitempty"`
+	Default     any                 `json:"default,omitempty"`
+}
+
+func makeJSONSchemaProperty(f ff.Flag) jsonSchemaProperty {
+	prop := jsonSchemaProperty{
+		Type:        "string",
+		Description: f.GetUsage(),
+	}
+
+	v := reflect.ValueOf(ff.GetAny(f))
+	if v.IsValid() {
+		prop.Type = jsonSchemaType(v.Type())
+		if prop.Type == "array" {
+			prop.Items = &jsonSchemaProperty{Type: jsonSchemaType(v.Type().Elem())}
+		}
+	}
+
+	if e, ok := f.(interface{ GetValid() []any }); ok {
+		prop.Enum = e.GetValid()
+	}
+
+	if def := f.GetDefault(); def != "" {
+		prop.Default = jsonSchemaDefault(prop.Type, def)
+	}
+
+	return prop
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+func jsonSchemaType(typ reflect.Type) string {
+	if typ.Implements(stringerType) {
+		return "string"
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+// jsonSchemaDefault converts the default string to the given schema type.
+// Defaults which can't be converted, e.g. for arrays and objects, are omitted.
+func jsonSchemaDefault(typ, def string) any {
+	switch typ {
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	case "integer":
+		if i, err := strconv.ParseInt(def, 10, 64); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(def, 10, 64); err == nil {
+			return u
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(def, 64); err == nil {
+			return f
+		}
+	case "string":
+		return def
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package ffhelp_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/peterbourgon/ff/v4"
+	"github.com/peterbourgon/ff/v4/ffhelp"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	rootFlags := ff.NewFlagSet("root")
+	rootFlags.Bool('v', "verbose", "verbose logging")
+	rootFlags.StringEnumLong("format", "output format", "json", "text")
+
+	subFlags := ff.NewFlagSet("sub").SetParent(rootFlags)
+	subFlags.IntLong("workers", 4, "number of workers")
+	subFlags.Float64Short('r', 0.5, "sample ratio")
+	subFlags.DurationLong("timeout", 3*time.Second, "request timeout")
+	subFlags.StringListLong("tag", "tags")
+
+	cmd := &ff.Command{Name: "sub", Flags: subFlags}
+	data, err := ffhelp.JSONSchema(cmd)
+	if err != nil {
+		t.Fatalf("JSONSchema: %v", err)
+	}
+
+	var schema struct {
+		Schema     string `json:"$schema"`
+		Type       string `json:"type"`
+		Properties map[string]struct {
+			Type        string         `json:"type"`
+			Description string         `json:"description"`
+			Items       map[string]any `json:"items"`
+			Enum        []any          `json:"enum"`
+			Default     any            `json:"default"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if want, have := "http://json-schema.org/draft-07/schema#", schema.Schema; want != have {
+		t.Errorf("$schema: want %q, have %q", want, have)
+	}
+	if want, have := "object", schema.Type; want != have {
+		t.Errorf("type: want %q, have %q", want, have)
+	}
+
+	for key, want := range map[string]struct {
+		typ string
+		def any
+	}{
+		"verbose": {"boolean", nil},
+		"format":  {"string", "json"},
+		"workers": {"integer", float64(4)},
+		"r":       {"number", 0.5},
+		"timeout": {"string", "3s"},
+		"tag":     {"array", nil},
+	} {
+		prop, ok := schema.Properties[key]
+		if !ok {
+			t.Errorf("%s: missing property", key)
+			continue
+		}
+		if want, have := want.typ, prop.Type; want != have {
+			t.Errorf("%s: type: want %q, have %q", key, want, have)
+		}
+		if want, have := want.def, prop.Default; !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: default: want %v, have %v", key, want, have)
+		}
+	}
+
+	if want, have := []any{"json", "text"}, schema.Properties["format"].Enum; !reflect.DeepEqual(want, have) {
+		t.Errorf("format: enum: want %v, have %v", want, have)
+	}
+	if want, have := "string", schema.Properties["tag"].Items["type"]; want != have {
+		t.Errorf("tag: items type: want %v, have %v", want, have)
+	}
+	if want, have := "number of workers", schema.Properties["workers"].Description; want != have {
+		t.Errorf("workers: description: want %q, have %q", want, have)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
@@ -50,7 +51,11 @@ func (s Section) WriteTo(w io.Writer) (n int64, _ error) {
 	}
 
 	for _, line := range s.Lines {
-		nn, err := fmt.Fprint(dst, ensureNewline(s.LinePrefix+line))
+		prefix := s.LinePrefix
+		if line == "" {
+			prefix = "" // avoid trailing whitespace on blank lines
+		}
+		nn, err := fmt.Fprint(dst, ensureNewline(prefix+line))
 		if err != nil {
 			return n, err
 		}
@@ -90,30 +95,147 @@ func NewUntitledSection(lines ...string) Section {
 	}
 }
 
-// NewFlagsSection returns a single FLAGS section representing every flag
-// available to fs. Each flag is rendered via [FlagSpec].
-func NewFlagsSection(fs ff.Flags) Section {
-	ss := newFlagSections(flagSectionsConfig{Flags: fs, SingleSection: true})
-	if len(ss) != 1 {
+// NewLongHelpSection returns an untitled section representing a long help
+// string, e.g. [ff.Command.LongHelp], normalized to match the other sections
+// in this package. Each paragraph is dedented, and rewrapped via [RewrapAt] so
+// that lines, including [DefaultLinePrefix], fit within width columns.
+// Paragraphs are separated by blank lines.
+func NewLongHelpSection(s string, width int) Section {
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		if strings.TrimSpace(lines[i]) == "" {
+			lines[i] = "" // whitespace-only lines are paragraph breaks
+		}
+	}
+
+	rewrapped := RewrapAt(strings.Join(lines, "\n"), width-len(DefaultLinePrefix))
+
+	return Section{
+		Lines:      strings.Split(rewrapped, "\n"),
+		LinePrefix: DefaultLinePrefix,
+	}
+}
+
+// newLongHelpSection returns a section representing the long help s, which is
+// normalized via NewLongHelpSection if a width was given, and rendered as-is
+// otherwise.
+func newLongHelpSection(s string, cfg helpConfig) Section {
+	if cfg.width > 0 {
+		return NewLongHelpSection(s, cfg.width)
+	}
+	return NewUntitledSection(s)
+}
+
+// NewFlagsSection returns a single FLAGS section representing every non-hidden
+// flag available to fs, or every flag if the [ShowHidden] option is given. Each
+// flag is rendered via [FlagSpec]. If there are no such flags, the section
+// contains the single line "(no flags)".
+func NewFlagsSection(fs ff.Flags, options ...Option) Section {
+	cfg := makeHelpConfig(options)
+	ss := newFlagSections(flagSectionsConfig{
+		Flags:         fs,
+		SingleSection: true,
+		Width:         cfg.width,
+		Sorted:        cfg.sorted,
+		IncludeHidden: cfg.showHidden,
+	})
+	switch len(ss) {
+	case 0: // e.g. every flag is hidden
+		return Section{
+			Title:      "FLAGS",
+			Lines:      []string{"(no flags)"},
+			LinePrefix: DefaultLinePrefix,
+		}
+	case 1:
+		return ss[0]
+	default:
 		panic(fmt.Errorf("expected 1 section, got %d", len(ss)))
 	}
-	return ss[0]
 }
 
-// NewFlagsSections returns FLAGS section(s) representing every flag available
-// to fs. Flags are grouped into sections according to their parent flag set.
-// Each flag is rendered via [FlagSpec].
-func NewFlagsSections(fs ff.Flags) []Section {
-	return newFlagSections(flagSectionsConfig{Flags: fs, SharedAlignment: true})
+// NewFlagsSections returns FLAGS section(s) representing every non-hidden flag
+// available to fs, or every flag if the [ShowHidden] option is given. Flags are
+// grouped into sections according to their parent flag set, unless they're
+// sorted together via [Sorted]. Each flag is rendered via [FlagSpec].
+func NewFlagsSections(fs ff.Flags, options ...Option) []Section {
+	cfg := makeHelpConfig(options)
+	return newFlagSections(flagSectionsConfig{
+		Flags:           fs,
+		SingleSection:   cfg.sortTogether,
+		SharedAlignment: true,
+		Width:           cfg.width,
+		Sorted:          cfg.sorted,
+		IncludeHidden:   cfg.showHidden,
+	})
+}
+
+// NewCommandFlagsSections is like [NewFlagsSections], but also includes flags
+// from the flag sets of every parent of cmd, even if those flag sets aren't
+// parents of the command's own flag set. Flags from parent commands are grouped
+// into sections titled with the name of the command.
+func NewCommandFlagsSections(cmd *ff.Command, options ...Option) []Section {
+	var (
+		cfg    = makeHelpConfig(options)
+		groups []ff.FlagGroup
+		owners = map[ff.Flags]bool{}
+	)
+	add := func(c *ff.Command) {
+		if c.Flags == nil {
+			return
+		}
+		for _, group := range ff.FlagsByOwner(c.Flags) {
+			owner := group.Flags[0].GetFlags()
+			if owners[owner] {
+				continue
+			}
+			owners[owner] = true
+			if owner == c.Flags {
+				group.Name = c.Name
+			}
+			groups = append(groups, group)
+		}
+	}
+	for c := cmd; c != nil; c = c.GetParent() {
+		add(c)
+	}
+	if len(groups) <= 0 {
+		return nil
+	}
+	return newFlagSections(flagSectionsConfig{
+		Groups:          groups,
+		SingleSection:   cfg.sortTogether,
+		SharedAlignment: true,
+		Width:           cfg.width,
+		Sorted:          cfg.sorted,
+		IncludeHidden:   cfg.showHidden,
+	})
 }
 
 // NewSubcommandsSection returns a SUBCOMMANDS section containing one line for
-// every subcommand in the slice. Lines consist of the subcommand name and the
-// ShortHelp for that subcommand, in a columnar format.
-func NewSubcommandsSection(subcommands []*ff.Command) Section {
+// every non-hidden subcommand in the slice. Lines consist of the subcommand name
+// and the ShortHelp for that subcommand, in a columnar format. Hidden
+// subcommands are included if the [ShowHidden] option is given.
+func NewSubcommandsSection(subcommands []*ff.Command, options ...Option) Section {
+	cfg := makeHelpConfig(options)
+	return newSubcommandsSection(subcommandsSectionConfig{Subcommands: subcommands, IncludeHidden: cfg.showHidden})
+}
+
+//
+//
+//
+
+type subcommandsSectionConfig struct {
+	Subcommands   []*ff.Command
+	IncludeHidden bool // include subcommands with Hidden set to true
+}
+
+func newSubcommandsSection(cfg subcommandsSectionConfig) Section {
 	var lines []string
-	for _, sc := range subcommands {
-		lines = append(lines, fmt.Sprintf("%s\t%s\n", sc.Name, sc.ShortHelp))
+	for _, sc := range cfg.Subcommands {
+		if sc.Hidden && !cfg.IncludeHidden {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%s\n", commandNames(sc), sc.ShortHelp))
 	}
 	if len(lines) <= 0 {
 		lines = append(lines, "(no subcommands)")
@@ -126,15 +248,15 @@ func NewSubcommandsSection(subcommands []*ff.Command) Section {
 	}
 }
 
-//
-//
-//
-
 type flagSectionsConfig struct {
 	Flags           ff.Flags
-	SingleSection   bool // treat all flags as belonging to the base flag set
-	AlwaysSubtitle  bool // add the flag set name to every section title
-	SharedAlignment bool // use the same column spacing across all sections
+	SingleSection   bool           // treat all flags as belonging to the base flag set
+	AlwaysSubtitle  bool           // add the flag set name to every section title
+	SharedAlignment bool           // use the same column spacing across all sections
+	Width           int            // wrap usage text so lines fit in width, if > 0
+	Sorted          bool           // sort flags by name within each section
+	IncludeHidden   bool           // include flags with IsHidden returning true
+	Groups          []ff.FlagGroup // use these groups instead of grouping Flags by owner
 }
 
 func newFlagSections(cfg flagSectionsConfig) []Section {
@@ -142,25 +264,104 @@ func newFlagSections(cfg flagSectionsConfig) []Section {
 		index = map[string][]ff.Flag{}
 		order = []string{}
 	)
-	cfg.Flags.WalkFlags(func(f ff.Flag) error {
-		var parent string
-		if cfg.SingleSection {
-			parent = cfg.Flags.GetName()
-		} else {
-			parent = f.GetFlags().GetName()
+	if cfg.SingleSection && cfg.Groups != nil {
+		name := cfg.Groups[0].Name
+		for _, group := range cfg.Groups {
+			for _, f := range group.Flags {
+				if !f.IsHidden() || cfg.IncludeHidden {
+					index[name] = append(index[name], f)
+				}
+			}
 		}
-		if _, ok := index[parent]; !ok {
-			order = append(order, parent)
+		if len(index[name]) > 0 {
+			order = append(order, name)
 		}
-		index[parent] = append(index[parent], f)
-		return nil
-	})
+	} else if cfg.SingleSection {
+		name := cfg.Flags.GetName()
+		cfg.Flags.WalkFlags(func(f ff.Flag) error {
+			if !f.IsHidden() || cfg.IncludeHidden {
+				index[name] = append(index[name], f)
+			}
+			return nil
+		})
+		if len(index[name]) > 0 {
+			order = append(order, name)
+		}
+	} else {
+		groups := cfg.Groups
+		if groups == nil {
+			groups = ff.FlagsByOwner(cfg.Flags)
+		}
+		for _, group := range groups {
+			var flags []ff.Flag
+			for _, f := range group.Flags {
+				if !f.IsHidden() || cfg.IncludeHidden {
+					flags = append(flags, f)
+				}
+			}
+			if len(flags) == 0 {
+				continue
+			}
+			if _, ok := index[group.Name]; !ok {
+				order = append(order, group.Name)
+			}
+			index[group.Name] = append(index[group.Name], flags...)
+		}
+	}
+
+	if cfg.Sorted {
+		for _, flags := range index {
+			sort.SliceStable(flags, func(i, j int) bool {
+				return flagSortKey(flags[i]) < flagSortKey(flags[j])
+			})
+		}
+	}
+
+	// Usage text is wrapped to fit in the space to the right of the spec
+	// column, which is as wide as the widest spec in the alignment group, plus
+	// tabwriter padding, minus any common leading whitespace that's trimmed
+	// from the rendered lines below.
+	usageWidth := map[string]int{}
+	if cfg.Width > 0 {
+		group := func(name string) string {
+			if cfg.SharedAlignment {
+				return ""
+			}
+			return name
+		}
+		var (
+			maxSpec = map[string]int{}
+			minLead = map[string]int{}
+		)
+		for _, name := range order {
+			g := group(name)
+			for _, f := range index[name] {
+				spec := MakeFlagSpec(f).Spec
+				lead := len(spec) - len(strings.TrimLeft(spec, " "))
+				if len(spec) > maxSpec[g] {
+					maxSpec[g] = len(spec)
+				}
+				if min, ok := minLead[g]; !ok || lead < min {
+					minLead[g] = lead
+				}
+			}
+		}
+		for _, name := range order {
+			g := group(name)
+			column := len(DefaultLinePrefix) + maxSpec[g] + tabWriterPadding - minLead[g]
+			usageWidth[name] = cfg.Width - column
+			if usageWidth[name] < minUsageWidth {
+				usageWidth[name] = minUsageWidth
+			}
+		}
+	}
 
 	var (
 		buffer   = &bytes.Buffer{}
 		tab      = newTabWriter(buffer)
 		flushOne func() error
 		flushAll func() error
+		counts   = map[string]int{}
 	)
 	if cfg.SharedAlignment {
 		flushOne = func() error { return nil }
@@ -176,7 +377,20 @@ func newFlagSections(cfg flagSectionsConfig) []Section {
 			continue
 		}
 		for _, f := range flags {
-			fmt.Fprint(tab, MakeFlagSpec(f).String())
+			spec := MakeFlagSpec(f)
+			if width, ok := usageWidth[name]; ok && len(spec.Usage) > width {
+				for i, line := range strings.Split(RewrapAt(spec.Usage, width), "\n") {
+					if i == 0 {
+						fmt.Fprintf(tab, "%s\t%s\n", spec.Spec, line)
+					} else {
+						fmt.Fprintf(tab, "\t%s\n", line)
+					}
+					counts[name]++
+				}
+				continue
+			}
+			fmt.Fprint(tab, spec.String())
+			counts[name]++
 		}
 		if err := flushOne(); err != nil {
 			panic(err)
@@ -196,11 +410,12 @@ func newFlagSections(cfg flagSectionsConfig) []Section {
 			continue
 		}
 
-		if len(lines) < len(flags) {
-			panic(fmt.Errorf("%s: flag count %d, remaining section line count %d", name, len(flags), len(lines)))
+		count := counts[name]
+		if len(lines) < count {
+			panic(fmt.Errorf("%s: line count %d, remaining section line count %d", name, count, len(lines)))
 		}
 
-		sectionLines := lines[:len(flags)]
+		sectionLines := lines[:count]
 		if len(sectionLines) <= 0 {
 			panic(fmt.Errorf("%s: flag count %d, section line count 0", name, len(flags)))
 		}
@@ -216,7 +431,7 @@ func newFlagSections(cfg flagSectionsConfig) []Section {
 			LinePrefix: DefaultLinePrefix,
 		})
 
-		lines = lines[len(flags):]
+		lines = lines[count:]
 	}
 
 	var (
@@ -258,8 +473,36 @@ func newFlagSections(cfg flagSectionsConfig) []Section {
 	return flat
 }
 
+// commandNames returns the name of the command, followed by its aliases in
+// parentheses, if it has any, e.g. "remove (rm)".
+func commandNames(cmd *ff.Command) string {
+	if len(cmd.Aliases) <= 0 {
+		return cmd.Name
+	}
+	return fmt.Sprintf("%s (%s)", cmd.Name, strings.Join(cmd.Aliases, ", "))
+}
+
+// flagSortKey returns the long name of the flag, or its short name if it has no
+// long name.
+func flagSortKey(f ff.Flag) string {
+	if long, ok := f.GetLongName(); ok {
+		return long
+	}
+	if short, ok := f.GetShortName(); ok {
+		return string(short)
+	}
+	return ""
+}
+
+// tabWriterPadding is the minimum space between columns.
+const tabWriterPadding = 3
+
+// minUsageWidth prevents usage text from being wrapped into a uselessly narrow
+// column when flag specs are very wide.
+const minUsageWidth = 20
+
 func newTabWriter(w io.Writer) *tabwriter.Writer {
-	return tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	return tabwriter.NewWriter(w, 0, 0, tabWriterPadding, ' ', 0)
 }
 
 func ensureNewline(s string) string {
@@ -10,6 +10,7 @@ import (
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/ffhelp"
 	"github.com/peterbourgon/ff/v4/fftest"
+	"github.com/peterbourgon/ff/v4/ffval"
 )
 
 func TestSection_Flags(t *testing.T) {
@@ -129,6 +130,233 @@ func TestSections_Command(t *testing.T) {
 	}
 }
 
+func TestSections_HiddenSubcommands(t *testing.T) {
+	t.Parallel()
+
+	var debugRan bool
+	testcmd := makeTestCommand(t)
+	testcmd.Subcommands = append(testcmd.Subcommands, &ff.Command{
+		Name:      "debug",
+		ShortHelp: "internal debug subcommand",
+		Hidden:    true,
+		Exec:      func(context.Context, []string) error { debugRan = true; return nil },
+	})
+
+	if err := testcmd.ParseAndRun(context.Background(), []string{"debug"}); err != nil {
+		t.Fatalf("ParseAndRun: %v", err)
+	}
+	if want, have := "debug", testcmd.GetSelected().Name; want != have {
+		t.Errorf("selected: want %q, have %q", want, have)
+	}
+	if !debugRan {
+		t.Errorf("debug subcommand didn't run")
+	}
+
+	if err := testcmd.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	{
+		want := strings.TrimSpace(testCommandRootHelp)
+		have := strings.TrimSpace(ffhelp.Command(testcmd).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	}
+
+	{
+		want := fftest.UnindentString(`
+			SUBCOMMANDS
+			  foo     the foo subcommand
+			  debug   internal debug subcommand
+		`)
+		have := fftest.UnindentString(ffhelp.NewSubcommandsSection(testcmd.Subcommands, ffhelp.ShowHidden()).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	}
+
+	{
+		help := ffhelp.Command(testcmd, ffhelp.ShowHidden()).String()
+		if want := "debug   internal debug subcommand"; !strings.Contains(help, want) {
+			t.Errorf("command help with hidden: want %q, have\n%s", want, help)
+		}
+	}
+}
+
+func TestSection_SubcommandAliases(t *testing.T) {
+	t.Parallel()
+
+	want := fftest.UnindentString(`
+		SUBCOMMANDS
+		  remove (rm, del)   remove an object
+		  list               list objects
+	`)
+	have := fftest.UnindentString(ffhelp.NewSubcommandsSection([]*ff.Command{
+		{Name: "remove", Aliases: []string{"rm", "del"}, ShortHelp: "remove an object"},
+		{Name: "list", ShortHelp: "list objects"},
+	}).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestSection_LongHelp(t *testing.T) {
+	t.Parallel()
+
+	longHelp := "" +
+		"\t\tLorem ipsum dolor sit amet, consectetur adipiscing elit. Nam diam eros,\n" +
+		"\t\tvestibulum at pulvinar vulputate, vehicula id lacus. Class aptent taciti\n" +
+		"\t\tsociosqu ad litora torquent per conubia nostra, per inceptos himenaeos.\n" +
+		"    \n" +
+		"\t    Mauris venenatis felis orci, ac consectetur mi molestie ac. Integer pharetra pharetra odio. Maecenas metus eros, viverra eget efficitur ut, feugiat in tortor.\n" +
+		"\n" +
+		"\t\tQuisque elit nibh, rhoncus in posuere et, bibendum non turpis.\n"
+
+	want := strings.Join([]string{
+		"  Lorem ipsum dolor sit amet, consectetur adipiscing elit. Nam diam eros,",
+		"  vestibulum at pulvinar vulputate, vehicula id lacus. Class aptent taciti",
+		"  sociosqu ad litora torquent per conubia nostra, per inceptos himenaeos.",
+		"",
+		"  Mauris venenatis felis orci, ac consectetur mi molestie ac. Integer pharetra",
+		"  pharetra odio. Maecenas metus eros, viverra eget efficitur ut, feugiat in",
+		"  tortor.",
+		"",
+		"  Quisque elit nibh, rhoncus in posuere et, bibendum non turpis.",
+		"",
+	}, "\n")
+	have := ffhelp.NewLongHelpSection(longHelp, 78).String()
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	for _, line := range strings.Split(have, "\n") {
+		if len(line) > 78 {
+			t.Errorf("line exceeds 78 columns: %q", line)
+		}
+	}
+}
+
+func TestSection_FlagsWidth(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	parent.StringLong("log-destination", "", "where to write logs, which can be a file path, a URL, or one of the special values stdout and stderr")
+	child := ff.NewFlagSet("child").SetParent(parent)
+	child.Bool('v', "verbose", "log more")
+	child.String('o', "out", "", "output file for the results of the operation, created if it doesn't already exist")
+
+	want := strings.Join([]string{
+		"FLAGS (child)",
+		"  -v, --verbose                  log more",
+		"  -o, --out STRING               output file for the results of the",
+		"                                 operation, created if it doesn't already",
+		"                                 exist",
+		"",
+		"FLAGS (parent)",
+		"      --log-destination STRING   where to write logs, which can be a file",
+		"                                 path, a URL, or one of the special values",
+		"                                 stdout and stderr",
+		"",
+	}, "\n")
+
+	var sb strings.Builder
+	for _, s := range ffhelp.NewFlagsSections(child, ffhelp.WithWidth(74)) {
+		sb.WriteString(s.String())
+		sb.WriteString("\n")
+	}
+	have := strings.TrimSuffix(sb.String(), "\n")
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	for _, line := range strings.Split(have, "\n") {
+		if len(line) > 74 {
+			t.Errorf("line exceeds 74 columns: %q", line)
+		}
+	}
+}
+
+func TestSection_FlagsSorted(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	parent.BoolLong("zeta", "zeta usage")
+	parent.BoolLong("beta", "beta usage")
+	child := ff.NewFlagSet("child").SetParent(parent)
+	child.BoolShort('q', "q usage")
+	child.BoolLong("gamma", "gamma usage")
+	child.Bool('x', "alpha", "alpha usage")
+
+	t.Run("separate", func(t *testing.T) {
+		want := fftest.UnindentString(`
+			NAME
+			  child
+
+			FLAGS (child)
+			  -x, --alpha   alpha usage
+			      --gamma   gamma usage
+			  -q            q usage
+
+			FLAGS (parent)
+			      --beta    beta usage
+			      --zeta    zeta usage
+		`)
+		have := fftest.UnindentString(ffhelp.Flags(child, ffhelp.Sorted(false)).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+
+	t.Run("together", func(t *testing.T) {
+		want := fftest.UnindentString(`
+			FLAGS
+			  -x, --alpha   alpha usage
+			      --beta    beta usage
+			      --gamma   gamma usage
+			  -q            q usage
+			      --zeta    zeta usage
+		`)
+		have := fftest.UnindentString(ffhelp.NewFlagsSection(child, ffhelp.Sorted(true)).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+
+	t.Run("together with width", func(t *testing.T) {
+		want := fftest.UnindentString(`
+			NAME
+			  child
+
+			FLAGS
+			  -x, --alpha   alpha usage
+			      --beta    beta usage
+			      --gamma   gamma usage
+			  -q            q usage
+			      --zeta    zeta usage
+		`)
+		have := fftest.UnindentString(ffhelp.Flags(child, ffhelp.Sorted(true), ffhelp.WithWidth(80)).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+
+	t.Run("default order unchanged", func(t *testing.T) {
+		want := fftest.UnindentString(`
+			FLAGS
+			  -q            q usage
+			      --gamma   gamma usage
+			  -x, --alpha   alpha usage
+			      --zeta    zeta usage
+			      --beta    beta usage
+		`)
+		have := fftest.UnindentString(ffhelp.NewFlagsSection(child).String())
+		if want != have {
+			t.Error(fftest.DiffString(want, have))
+		}
+	})
+}
+
 var testCommandRootHelp = `
 COMMAND
   testcmd
@@ -246,3 +474,30 @@ enim, faucibus id auctor ac, ornare sed metus.
 `
 
 var loremIpsumSlice = strings.Split(strings.TrimSpace(loremIpsum), "\n")
+
+func TestSection_FlagsAllHidden(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet("fftest")
+	if _, err := fs.AddFlag(ff.FlagConfig{LongName: "secret", Value: new(ffval.Bool), Usage: "secret flag", Hidden: true}); err != nil {
+		t.Fatalf("AddFlag: %v", err)
+	}
+
+	want := fftest.UnindentString(`
+		FLAGS
+		  (no flags)
+	`)
+	have := fftest.UnindentString(ffhelp.NewFlagsSection(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	want = fftest.UnindentString(`
+		FLAGS
+		  --secret   secret flag
+	`)
+	have = fftest.UnindentString(ffhelp.NewFlagsSection(fs, ffhelp.ShowHidden()).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
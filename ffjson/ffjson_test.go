@@ -2,6 +2,9 @@ package ffjson_test
 
 import (
 	"io"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,13 +25,27 @@ func TestParser(t *testing.T) {
 		{
 			Name:       "basic KV pairs",
 			ConfigFile: "testdata/basic.json",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "s", I: 10, B: true, D: 5 * time.Second},
 		},
 		{
 			Name:       "value arrays",
 			ConfigFile: "testdata/value_arrays.json",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "bb", I: 12, B: true, D: 5 * time.Second, X: []string{"a", "B", "👍"}},
 		},
+		{
+			Name:       "spaces",
+			ConfigFile: "testdata/spaces.json",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
+			Want:       fftest.Vars{S: "  padded  ", X: []string{"  a ", "b\t"}},
+		},
+		{
+			Name:       "spaces WithConfigTrimSpace",
+			ConfigFile: "testdata/spaces.json",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigTrimSpace()},
+			Want:       fftest.Vars{S: "padded", X: []string{"a", "b"}},
+		},
 		{
 			Name:       "bad JSON file",
 			ConfigFile: "testdata/bad.json",
@@ -47,7 +64,45 @@ func TestParser(t *testing.T) {
 			Options:      []ff.Option{ff.WithConfigFileParser(ffjson.Parser{Delimiter: "-"}.Parse)},
 			Want:         fftest.Vars{S: "foo bar", I: 34, X: []string{"alpha", "beta", "delta"}},
 		},
+		{
+			Name:       "WithConfigFileSection",
+			ConfigFile: "testdata/sections.json",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigFileSection("mytool")},
+			Want:       fftest.Vars{S: "mytool string", I: 10, X: []string{"a", "b"}},
+		},
+		{
+			Name:       "WithConfigFileSection other",
+			ConfigFile: "testdata/sections.json",
+			Options:    []ff.Option{ff.WithConfigFileSection("othertool")},
+			Want:       fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+		{
+			Name:       "WithConfigFileSection missing",
+			ConfigFile: "testdata/sections.json",
+			Options:    []ff.Option{ff.WithConfigFileSection("missing")},
+			Want:       fftest.Vars{},
+		},
 	}
 
 	testcases.Run(t)
 }
+
+func TestParse_Flatten(t *testing.T) {
+	t.Parallel()
+
+	input := `{"log": {"level": "debug", "json": true}, "port": 8080, "ratio": 0.5, "tag": ["a", "b"]}`
+
+	var have []string
+	if err := ffjson.Parse(strings.NewReader(input), func(name, value string) error {
+		have = append(have, name+"="+value)
+		return nil
+	}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	sort.Strings(have)
+
+	want := []string{"log.json=true", "log.level=debug", "port=8080", "ratio=0.5", "tag=a", "tag=b"}
+	if !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
+	}
+}
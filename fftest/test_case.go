@@ -59,6 +59,8 @@ func (tc *ParseTest) Run(t *testing.T) {
 			parseFunc = fftoml.Parse
 		case ".env":
 			parseFunc = ffenv.Parse
+		case ".properties":
+			parseFunc = ff.PropertiesParser
 		default:
 			parseFunc = ff.PlainParser
 		}
@@ -21,6 +21,7 @@ func TestParser(t *testing.T) {
 		{
 			Name:       "basic KV pairs",
 			ConfigFile: "testdata/basic.toml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want: fftest.Vars{
 				S: "s",
 				I: 10,
@@ -30,6 +31,12 @@ func TestParser(t *testing.T) {
 				X: []string{"1", "a", "👍"},
 			},
 		},
+		{
+			Name:       "times",
+			ConfigFile: "testdata/times.toml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
+			Want:       fftest.Vars{S: "2024-01-02", X: []string{"2024-01-02T15:04:05Z", "15:04:05", "2024-01-02T15:04:05"}},
+		},
 		{
 			Name:       "bad TOML file",
 			ConfigFile: "testdata/bad.toml",
@@ -49,6 +56,24 @@ func TestParser(t *testing.T) {
 			Options:      []ff.Option{ff.WithConfigFileParser(fftoml.Parser{Delimiter: "-"}.Parse)},
 			Want:         fftest.Vars{S: "a string", F: 1.23, X: []string{"one", "two", "three"}},
 		},
+		{
+			Name:       "WithConfigFileSection",
+			ConfigFile: "testdata/sections.toml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigFileSection("mytool")},
+			Want:       fftest.Vars{S: "mytool string", I: 10, X: []string{"a", "b"}},
+		},
+		{
+			Name:       "WithConfigFileSection other",
+			ConfigFile: "testdata/sections.toml",
+			Options:    []ff.Option{ff.WithConfigFileSection("othertool")},
+			Want:       fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+		{
+			Name:       "WithConfigFileSection missing",
+			ConfigFile: "testdata/sections.toml",
+			Options:    []ff.Option{ff.WithConfigFileSection("missing")},
+			Want:       fftest.Vars{},
+		},
 	}
 
 	testcases.Run(t)
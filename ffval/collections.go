@@ -4,8 +4,12 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/netip"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // DefaultStringFunc is used by [List] and [UniqueList] if no StringFunc is
@@ -40,6 +44,11 @@ type List[T any] struct {
 	// used.
 	StringFunc func([]T) string
 
+	// Default values of the list. If provided, the list is initialized with the
+	// default values, and the first explicit call to Set replaces them, rather
+	// than appending to them. Reset restores the default values.
+	Default []T
+
 	initialized bool
 	isSet       bool
 }
@@ -69,6 +78,68 @@ func NewListParser[T any](parseFunc func(string) (T, error)) *List[T] {
 	return v
 }
 
+// NewListReflect returns a list for ptr, which must be a pointer to a slice of
+// a supported [ValueType]. If def is non-empty, it's parsed as the single
+// default value of the list. It's primarily used to support slice fields in
+// structs passed to [github.com/peterbourgon/ff/v4.FlagSet.AddStruct].
+func NewListReflect(ptr any, def string) (flag.Value, error) {
+	switch p := ptr.(type) {
+	case *[]bool:
+		return newListReflect(p, def)
+	case *[]int:
+		return newListReflect(p, def)
+	case *[]int8:
+		return newListReflect(p, def)
+	case *[]int16:
+		return newListReflect(p, def)
+	case *[]int32:
+		return newListReflect(p, def)
+	case *[]int64:
+		return newListReflect(p, def)
+	case *[]uint:
+		return newListReflect(p, def)
+	case *[]uint8:
+		return newListReflect(p, def)
+	case *[]uint16:
+		return newListReflect(p, def)
+	case *[]uint32:
+		return newListReflect(p, def)
+	case *[]uint64:
+		return newListReflect(p, def)
+	case *[]float32:
+		return newListReflect(p, def)
+	case *[]float64:
+		return newListReflect(p, def)
+	case *[]string:
+		return newListReflect(p, def)
+	case *[]complex64:
+		return newListReflect(p, def)
+	case *[]complex128:
+		return newListReflect(p, def)
+	case *[]time.Duration:
+		return newListReflect(p, def)
+	case *[]netip.Addr:
+		return newListReflect(p, def)
+	case *[]netip.AddrPort:
+		return newListReflect(p, def)
+	default:
+		return nil, fmt.Errorf("unsupported type %T", ptr)
+	}
+}
+
+func newListReflect[T ValueType](ptr *[]T, def string) (flag.Value, error) {
+	v := NewList(ptr)
+	if def != "" {
+		d, err := v.ParseFunc(def)
+		if err != nil {
+			return nil, err
+		}
+		v.Default = []T{d}
+		*v.Pointer = append((*v.Pointer)[:0], d)
+	}
+	return v, nil
+}
+
 func (v *List[T]) initialize() {
 	if v.initialized {
 		return
@@ -96,6 +167,10 @@ func (v *List[T]) initialize() {
 		v.StringFunc = DefaultStringFunc[T]
 	}
 
+	if len(v.Default) > 0 {
+		*v.Pointer = append((*v.Pointer)[:0], v.Default...)
+	}
+
 	v.initialized = true
 }
 
@@ -109,6 +184,10 @@ func (v *List[T]) Set(s string) error {
 		return err
 	}
 
+	if !v.isSet && len(v.Default) > 0 {
+		*v.Pointer = (*v.Pointer)[:0] // replace defaults
+	}
+
 	*v.Pointer = append(*v.Pointer, value)
 	v.isSet = true
 	return nil
@@ -126,10 +205,11 @@ func (v *List[T]) GetPointer() *[]T {
 	return v.Pointer
 }
 
-// Reset the list of values to its default (empty) state.
+// Reset the list of values to its default state, which is empty unless default
+// values were provided.
 func (v *List[T]) Reset() error {
 	v.initialize()
-	*v.Pointer = (*v.Pointer)[:0]
+	*v.Pointer = append((*v.Pointer)[:0], v.Default...)
 	v.isSet = false
 	return nil
 }
@@ -149,6 +229,157 @@ func (v *List[T]) IsSet() bool {
 //
 //
 
+// FixedList is a [List] with a fixed number of values, e.g. the three
+// components of an RGB color, provided by repeating the flag. Values fill the
+// list in the order they're provided.
+//
+// Set returns an error if more than Size values are provided. Providing fewer
+// than Size values, but at least one, is an error reported by ValidateParsed,
+// which is called by ff.Parse after all parse stages. Providing no values is
+// allowed, and leaves the list empty; use a required flag to prevent that.
+type FixedList[T any] struct {
+	// ParseFunc parses a string to the type T. If no ParseFunc is provided, and
+	// T is a supported [ValueType], then a default ParseFunc will be assigned
+	// lazily. If no ParseFunc is provided, and T is not a supported
+	// [ValueType], then most method calls will panic.
+	ParseFunc func(string) (T, error)
+
+	// Pointer is the actual slice of type T which is managed and updated by the
+	// list. If no Pointer is provided, a new slice is allocated lazily. For
+	// this reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer *[]T
+
+	// StringFunc is used by the String method to transform the underlying slice
+	// of T to a string. If no StringFunc is provided, [DefaultStringFunc] is
+	// used.
+	StringFunc func([]T) string
+
+	// Size is the exact number of values expected. It must be greater than
+	// zero.
+	Size int
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*FixedList[any])(nil)
+
+// NewFixedList returns a fixed list of underlying [ValueType] T, which updates
+// the given pointer ptr when set, and which expects exactly size values.
+func NewFixedList[T ValueType](ptr *[]T, size int) *FixedList[T] {
+	v := &FixedList[T]{
+		Pointer: ptr,
+		Size:    size,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *FixedList[T]) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.ParseFunc == nil {
+		var zero T
+		valueType := reflect.TypeOf(zero)
+		parse, ok := defaultParseFuncs[valueType]
+		if !ok {
+			panic(fmt.Errorf("%s: unsupported value type", valueType.String()))
+		}
+		pf, ok := parse.(func(string) (T, error))
+		if !ok {
+			panic(fmt.Errorf("%s: invalid default parse func (%T)", valueType.String(), parse))
+		}
+		v.ParseFunc = pf
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = &([]T{})
+	}
+
+	if v.StringFunc == nil {
+		v.StringFunc = DefaultStringFunc[T]
+	}
+
+	*v.Pointer = (*v.Pointer)[:0]
+
+	v.initialized = true
+}
+
+// Set parses the given string, and appends the successfully parsed value to the
+// list. It returns an error if the list already has Size values.
+func (v *FixedList[T]) Set(s string) error {
+	v.initialize()
+
+	if len(*v.Pointer) >= v.Size {
+		return fmt.Errorf("too many values (want %d)", v.Size)
+	}
+
+	value, err := v.ParseFunc(s)
+	if err != nil {
+		return err
+	}
+
+	*v.Pointer = append(*v.Pointer, value)
+	v.isSet = true
+	return nil
+}
+
+// ValidateParsed returns an error if the list has been set, but with fewer
+// than Size values.
+func (v *FixedList[T]) ValidateParsed() error {
+	v.initialize()
+	if n := len(*v.Pointer); v.isSet && n < v.Size {
+		return fmt.Errorf("too few values (want %d, have %d)", v.Size, n)
+	}
+	return nil
+}
+
+// ValidateSelf returns an error if Size isn't greater than zero.
+func (v *FixedList[T]) ValidateSelf() error {
+	if v.Size <= 0 {
+		return fmt.Errorf("invalid size %d", v.Size)
+	}
+	return nil
+}
+
+// Get the current list of values.
+func (v *FixedList[T]) Get() []T {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying slice of T.
+func (v *FixedList[T]) GetPointer() *[]T {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the list to its empty state.
+func (v *FixedList[T]) Reset() error {
+	v.initialize()
+	*v.Pointer = (*v.Pointer)[:0]
+	v.isSet = false
+	return nil
+}
+
+// String returns a string representation of the list of values.
+func (v *FixedList[T]) String() string {
+	v.initialize()
+	return v.StringFunc(v.Get())
+}
+
+// IsSet returns true if the list has been explicitly set.
+func (v *FixedList[T]) IsSet() bool {
+	return v.isSet
+}
+
+//
+//
+//
+
 // UniqueList is a [List] that doesn't allow duplicate values.
 type UniqueList[T comparable] struct {
 	// ParseFunc parses a string to the type T. If no ParseFunc is provided, and
@@ -170,6 +401,11 @@ type UniqueList[T comparable] struct {
 	// default, ErrDuplicate is nil, so duplicate values are silently dropped.
 	ErrDuplicate error
 
+	// Default values of the list. If provided, the list is initialized with the
+	// default values, and the first explicit call to Set replaces them, rather
+	// than appending to them. Reset restores the default values.
+	Default []T
+
 	initialized bool
 	isSet       bool
 }
@@ -228,6 +464,10 @@ func (v *UniqueList[T]) initialize() {
 		v.StringFunc = DefaultStringFunc[T]
 	}
 
+	if len(v.Default) > 0 {
+		*v.Pointer = append((*v.Pointer)[:0], v.Default...)
+	}
+
 	v.initialized = true
 }
 
@@ -242,6 +482,10 @@ func (v *UniqueList[T]) Set(s string) error {
 		return err
 	}
 
+	if !v.isSet && len(v.Default) > 0 {
+		*v.Pointer = (*v.Pointer)[:0] // replace defaults
+	}
+
 	for _, existing := range *(v.Pointer) {
 		if value == existing {
 			return v.ErrDuplicate
@@ -265,10 +509,11 @@ func (v *UniqueList[T]) GetPointer() *[]T {
 	return v.Pointer
 }
 
-// Reset the list of values to its default (empty) state.
+// Reset the list of values to its default state, which is empty unless default
+// values were provided.
 func (v *UniqueList[T]) Reset() error {
 	v.initialize()
-	*v.Pointer = (*v.Pointer)[:0]
+	*v.Pointer = append((*v.Pointer)[:0], v.Default...)
 	v.isSet = false
 	return nil
 }
@@ -288,6 +533,150 @@ func (v *UniqueList[T]) IsSet() bool {
 //
 //
 
+// DefaultMaxRangeSpan is used by [IntRangeList] if no MaxSpan is provided.
+const DefaultMaxRangeSpan = 65536
+
+// IntRangeList is a [flag.Value] that represents a list of ints, where each
+// call to Set appends either a single int, e.g. "8000", or every int in an
+// inclusive range, e.g. "8000-8010", to the end of the list. Ranges where the
+// low value is greater than the high value are rejected, as are ranges which
+// span more than MaxSpan values.
+type IntRangeList struct {
+	// Pointer is the actual slice of ints which is managed and updated by the
+	// list. If no Pointer is provided, a new slice is allocated lazily. For
+	// this reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer *[]int
+
+	// MaxSpan is the maximum number of ints that a single range may produce.
+	// If no MaxSpan is provided, [DefaultMaxRangeSpan] is used.
+	MaxSpan int
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*IntRangeList)(nil)
+
+// NewIntRangeList returns an int range list, which updates the given pointer
+// ptr when set.
+func NewIntRangeList(ptr *[]int) *IntRangeList {
+	v := &IntRangeList{
+		Pointer: ptr,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *IntRangeList) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = &([]int{})
+	}
+
+	if v.MaxSpan <= 0 {
+		v.MaxSpan = DefaultMaxRangeSpan
+	}
+
+	v.initialized = true
+}
+
+// Set parses the given string as a single int, or as an inclusive range of
+// ints "lo-hi", and appends the resulting int(s) to the list.
+func (v *IntRangeList) Set(s string) error {
+	v.initialize()
+
+	lo, hi, err := parseIntRange(s)
+	if err != nil {
+		return err
+	}
+
+	if lo > hi {
+		return fmt.Errorf("%s: inverted range", s)
+	}
+
+	// Compare in unsigned space, since hi-lo can overflow a signed int. The
+	// difference is exact, because lo <= hi.
+	if uint64(hi)-uint64(lo) >= uint64(v.MaxSpan) {
+		return fmt.Errorf("%s: range spans more than %d values", s, v.MaxSpan)
+	}
+
+	for i := lo; i <= hi; i++ {
+		*v.Pointer = append(*v.Pointer, i)
+		if i == hi {
+			break // avoid overflow when hi is the max int
+		}
+	}
+	v.isSet = true
+	return nil
+}
+
+// parseIntRange parses "n" as the range n-n, and "lo-hi" as lo-hi. A leading
+// hyphen is treated as a sign, so e.g. "-3--1" is the range -3 to -1.
+func parseIntRange(s string) (lo, hi int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, 0, fmt.Errorf("empty range")
+	}
+
+	loStr, hiStr := s, s
+	if i := strings.Index(s[1:], "-"); i >= 0 {
+		loStr, hiStr = s[:i+1], s[i+2:]
+	}
+
+	if lo, err = strconv.Atoi(strings.TrimSpace(loStr)); err != nil {
+		return 0, 0, err
+	}
+
+	if hi, err = strconv.Atoi(strings.TrimSpace(hiStr)); err != nil {
+		return 0, 0, err
+	}
+
+	return lo, hi, nil
+}
+
+// Get the current list of ints.
+func (v *IntRangeList) Get() []int {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying slice of ints.
+func (v *IntRangeList) GetPointer() *[]int {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the list to its default (empty) state.
+func (v *IntRangeList) Reset() error {
+	v.initialize()
+	*v.Pointer = (*v.Pointer)[:0]
+	v.isSet = false
+	return nil
+}
+
+// String returns a string representation of the list of ints.
+func (v *IntRangeList) String() string {
+	return DefaultStringFunc(v.Get())
+}
+
+// IsSet returns true if the list has been explicitly set.
+func (v *IntRangeList) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns RANGE.
+func (v *IntRangeList) GetPlaceholder() string {
+	return "RANGE"
+}
+
+//
+//
+//
+
 // Enum is a generic [flag.Value] that represents one of a fixed set of possible
 // values of any comparable type T. An enum must have at least one valid value,
 // or it is itself invalid. For this reason, the zero value of an enum is not
@@ -315,8 +704,16 @@ type Enum[T comparable] struct {
 	// field directly.
 	Default T
 
-	initialized bool
-	isSet       bool
+	// CaseInsensitive, if true, makes Set match valid values without regard to
+	// case, via [strings.EqualFold]. The stored value is always the matching
+	// valid value, rather than the input as provided. This only applies when
+	// the underlying type of T is a string; other types are always compared
+	// exactly.
+	CaseInsensitive bool
+
+	initialized    bool
+	isSet          bool
+	invalidDefault *T // non-nil if a non-zero default was replaced
 }
 
 // ErrInvalidValue is returned when a value is set with invalid input.
@@ -387,6 +784,11 @@ func (v *Enum[T]) initialize() {
 		}
 	}
 	if !defaultValid {
+		var zero T
+		if v.Default != zero {
+			invalid := v.Default
+			v.invalidDefault = &invalid
+		}
 		v.Default = v.Valid[0]
 	}
 
@@ -406,8 +808,8 @@ func (v *Enum[T]) Set(s string) error {
 	}
 
 	for _, valid := range v.Valid {
-		if value == valid {
-			*v.Pointer = value
+		if v.matches(value, valid) {
+			*v.Pointer = valid
 			v.isSet = true
 			return nil
 		}
@@ -416,6 +818,26 @@ func (v *Enum[T]) Set(s string) error {
 	return ErrInvalidValue
 }
 
+func (v *Enum[T]) matches(value, valid T) bool {
+	if value == valid {
+		return true
+	}
+
+	if !v.CaseInsensitive {
+		return false
+	}
+
+	var (
+		rvalue = reflect.ValueOf(value)
+		rvalid = reflect.ValueOf(valid)
+	)
+	if rvalue.Kind() != reflect.String || rvalid.Kind() != reflect.String {
+		return false
+	}
+
+	return strings.EqualFold(rvalue.String(), rvalid.String())
+}
+
 // Get the current value.
 func (v *Enum[T]) Get() T {
 	v.initialize()
@@ -434,6 +856,14 @@ func (v *Enum[T]) GetDefault() T {
 	return v.Default
 }
 
+// GetValid returns a copy of the valid values.
+func (v *Enum[T]) GetValid() []T {
+	v.initialize()
+	valid := make([]T, len(v.Valid))
+	copy(valid, v.Valid)
+	return valid
+}
+
 // Reset the enum to its initial state.
 func (v *Enum[T]) Reset() error {
 	v.initialize()
@@ -452,3 +882,399 @@ func (v *Enum[T]) String() string {
 func (v *Enum[T]) IsSet() bool {
 	return v.isSet
 }
+
+// ValidateSelf returns an error if the enum was configured with a non-zero
+// default value that isn't one of the valid values. Such defaults are otherwise
+// silently replaced by the first valid value.
+func (v *Enum[T]) ValidateSelf() error {
+	v.initialize()
+	if v.invalidDefault != nil {
+		return fmt.Errorf("default %v: %w", *v.invalidDefault, ErrInvalidValue)
+	}
+	return nil
+}
+
+//
+//
+//
+
+// Pair is a single key/value pair in an [OrderedMap].
+type Pair[V any] struct {
+	Key   string
+	Value V
+}
+
+// String returns the pair in the key=value form accepted by [OrderedMap.Set].
+func (p Pair[V]) String() string {
+	return fmt.Sprintf("%s=%v", p.Key, p.Value)
+}
+
+// OrderedMap is a generic [flag.Value] that represents an insertion-ordered
+// set of key/value pairs. Every call to Set parses a string of the form
+// key=value, where the key is a non-empty string, and the value is parsed to
+// the type V. Keys are unique: if a key is set more than once, its value is
+// updated in place, and it retains its original position.
+type OrderedMap[V any] struct {
+	// ParseFunc parses a string to the type V. If no ParseFunc is provided, and
+	// V is a supported [ValueType], then a default ParseFunc will be assigned
+	// lazily. If no ParseFunc is provided, and V is not a supported
+	// [ValueType], then most method calls will panic.
+	ParseFunc func(string) (V, error)
+
+	// Pointer is the actual slice of pairs which is managed and updated by the
+	// map. If no Pointer is provided, a new slice is allocated lazily. For this
+	// reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer *[]Pair[V]
+
+	index       map[string]int
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*OrderedMap[any])(nil)
+
+// NewOrderedMap returns an ordered map of underlying [ValueType] V, which
+// updates the given pointer ptr when set.
+func NewOrderedMap[V ValueType](ptr *[]Pair[V]) *OrderedMap[V] {
+	v := &OrderedMap[V]{
+		Pointer: ptr,
+	}
+	v.initialize()
+	return v
+}
+
+// NewOrderedMapParser returns an ordered map of any type V that can be parsed
+// from a string.
+//
+// This constructor is intended as a convenience function for tests; consumers
+// who want to provide a parser are probably better served by constructing an
+// ordered map directly, so that they can also provide other fields in a single
+// motion.
+func NewOrderedMapParser[V any](parseFunc func(string) (V, error)) *OrderedMap[V] {
+	v := &OrderedMap[V]{
+		ParseFunc: parseFunc,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *OrderedMap[V]) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.ParseFunc == nil {
+		var zero V
+		valueType := reflect.TypeOf(zero)
+		parse, ok := defaultParseFuncs[valueType]
+		if !ok {
+			panic(fmt.Errorf("%s: unsupported value type", valueType.String()))
+		}
+		pf, ok := parse.(func(string) (V, error))
+		if !ok {
+			panic(fmt.Errorf("%s: invalid default parse func (%T)", valueType.String(), parse))
+		}
+		v.ParseFunc = pf
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = &([]Pair[V]{})
+	}
+
+	v.index = make(map[string]int, len(*v.Pointer))
+	for i, p := range *v.Pointer {
+		v.index[p.Key] = i
+	}
+
+	v.initialized = true
+}
+
+// Set parses the given key=value string. If the key doesn't exist in the map,
+// the pair is added to the end. Otherwise, the existing value is updated in
+// place.
+func (v *OrderedMap[V]) Set(s string) error {
+	v.initialize()
+
+	key, val, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("%q: missing =", s)
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return fmt.Errorf("%q: empty key", s)
+	}
+
+	value, err := v.ParseFunc(val)
+	if err != nil {
+		return err
+	}
+
+	if i, ok := v.index[key]; ok {
+		(*v.Pointer)[i].Value = value
+	} else {
+		v.index[key] = len(*v.Pointer)
+		*v.Pointer = append(*v.Pointer, Pair[V]{Key: key, Value: value})
+	}
+	v.isSet = true
+	return nil
+}
+
+// Get the current pairs, in insertion order.
+func (v *OrderedMap[V]) Get() []Pair[V] {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying slice of pairs.
+func (v *OrderedMap[V]) GetPointer() *[]Pair[V] {
+	v.initialize()
+	return v.Pointer
+}
+
+// Lookup returns the value for the given key, if it exists.
+func (v *OrderedMap[V]) Lookup(key string) (V, bool) {
+	v.initialize()
+	if i, ok := v.index[key]; ok {
+		return (*v.Pointer)[i].Value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Reset the map to its default (empty) state.
+func (v *OrderedMap[V]) Reset() error {
+	v.initialize()
+	*v.Pointer = (*v.Pointer)[:0]
+	v.index = map[string]int{}
+	v.isSet = false
+	return nil
+}
+
+// String returns a string representation of the pairs, in insertion order,
+// rendered as key=value and joined with ", ".
+func (v *OrderedMap[V]) String() string {
+	v.initialize()
+	strs := make([]string, len(*v.Pointer))
+	for i, p := range *v.Pointer {
+		strs[i] = p.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// IsSet returns true if the map has been explicitly set.
+func (v *OrderedMap[V]) IsSet() bool {
+	return v.isSet
+}
+
+//
+//
+//
+
+// Map is a generic [flag.Value] that represents a map of keys of type K to
+// values of type V. Every call to Set parses a string of the form key=value,
+// split on the first =, and inserts the pair into the map. If a key is set more
+// than once, the last value wins. If a SplitFunc is provided, every call to Set
+// may provide multiple pairs, e.g. a=1,b=2 with [SplitCommas].
+type Map[K comparable, V any] struct {
+	// ParseKeyFunc parses a string to the type K. If no ParseKeyFunc is
+	// provided, and K is a supported [ValueType], then a default ParseKeyFunc
+	// will be assigned lazily. If no ParseKeyFunc is provided, and K is not a
+	// supported [ValueType], then most method calls will panic.
+	ParseKeyFunc func(string) (K, error)
+
+	// ParseValueFunc parses a string to the type V. If no ParseValueFunc is
+	// provided, and V is a supported [ValueType], then a default
+	// ParseValueFunc will be assigned lazily. If no ParseValueFunc is
+	// provided, and V is not a supported [ValueType], then most method calls
+	// will panic.
+	ParseValueFunc func(string) (V, error)
+
+	// SplitFunc, if provided, splits the string given to Set into multiple
+	// key=value pairs, which are parsed individually. If any pair is invalid,
+	// Set returns an error naming that pair, and the map isn't modified. If no
+	// SplitFunc is provided, the string is parsed as a single pair.
+	SplitFunc func(string) []string
+
+	// Pointer is the actual map which is managed and updated by the value. If
+	// no Pointer is provided, a new map is allocated lazily. For this reason,
+	// callers should generally access the pointer via GetPointer, rather than
+	// reading the field directly.
+	Pointer *map[K]V
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*Map[string, any])(nil)
+
+// NewMap returns a map of underlying [ValueType]s K and V, which updates the
+// given pointer ptr when set.
+func NewMap[K, V ValueType](ptr *map[K]V) *Map[K, V] {
+	v := &Map[K, V]{
+		Pointer: ptr,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *Map[K, V]) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.ParseKeyFunc == nil {
+		var zero K
+		keyType := reflect.TypeOf(zero)
+		parse, ok := defaultParseFuncs[keyType]
+		if !ok {
+			panic(fmt.Errorf("%s: unsupported key type", keyType.String()))
+		}
+		pf, ok := parse.(func(string) (K, error))
+		if !ok {
+			panic(fmt.Errorf("%s: invalid default parse func (%T)", keyType.String(), parse))
+		}
+		v.ParseKeyFunc = pf
+	}
+
+	if v.ParseValueFunc == nil {
+		var zero V
+		valueType := reflect.TypeOf(zero)
+		parse, ok := defaultParseFuncs[valueType]
+		if !ok {
+			panic(fmt.Errorf("%s: unsupported value type", valueType.String()))
+		}
+		pf, ok := parse.(func(string) (V, error))
+		if !ok {
+			panic(fmt.Errorf("%s: invalid default parse func (%T)", valueType.String(), parse))
+		}
+		v.ParseValueFunc = pf
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = &map[K]V{}
+	}
+
+	if *v.Pointer == nil {
+		*v.Pointer = map[K]V{}
+	}
+
+	v.initialized = true
+}
+
+// Set parses the given key=value string, and inserts the pair into the map,
+// replacing any existing value for the key. If a SplitFunc is provided, the
+// string is first split into multiple pairs, which are inserted in order.
+func (v *Map[K, V]) Set(s string) error {
+	v.initialize()
+
+	pairs := []string{s}
+	if v.SplitFunc != nil {
+		pairs = v.SplitFunc(s)
+	}
+
+	keys := make([]K, len(pairs))
+	values := make([]V, len(pairs))
+	for i, pair := range pairs {
+		key, value, err := v.parsePair(pair)
+		if err != nil {
+			return err
+		}
+		keys[i], values[i] = key, value
+	}
+
+	for i := range keys {
+		(*v.Pointer)[keys[i]] = values[i]
+	}
+	v.isSet = true
+	return nil
+}
+
+func (v *Map[K, V]) parsePair(s string) (K, V, error) {
+	var (
+		zeroKey   K
+		zeroValue V
+	)
+
+	k, val, ok := strings.Cut(s, "=")
+	if !ok {
+		return zeroKey, zeroValue, fmt.Errorf("%q: missing =", s)
+	}
+
+	k = strings.TrimSpace(k)
+	if k == "" {
+		return zeroKey, zeroValue, fmt.Errorf("%q: empty key", s)
+	}
+
+	key, err := v.ParseKeyFunc(k)
+	if err != nil {
+		return zeroKey, zeroValue, fmt.Errorf("%q: key: %w", s, err)
+	}
+
+	value, err := v.ParseValueFunc(val)
+	if err != nil {
+		return zeroKey, zeroValue, fmt.Errorf("%q: value: %w", s, err)
+	}
+
+	return key, value, nil
+}
+
+// SplitCommas splits s on commas, and is intended to be used as the SplitFunc
+// of a [Map]. A comma prefixed by a single backslash is treated as a literal
+// comma, rather than a split point, and the backslash is removed. For example,
+// `a=1,b=x\,y` is split into `a=1` and `b=x,y`.
+func SplitCommas(s string) []string {
+	tokens := strings.Split(s, ",")
+	for i := len(tokens) - 2; i >= 0; i-- {
+		if strings.HasSuffix(tokens[i], `\`) {
+			tokens[i] = tokens[i][:len(tokens[i])-1] + "," + tokens[i+1]
+			tokens = append(tokens[:i+1], tokens[i+2:]...)
+		}
+	}
+	return tokens
+}
+
+// Get the current map.
+func (v *Map[K, V]) Get() map[K]V {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying map.
+func (v *Map[K, V]) GetPointer() *map[K]V {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the map to its default (empty) state.
+func (v *Map[K, V]) Reset() error {
+	v.initialize()
+	for k := range *v.Pointer {
+		delete(*v.Pointer, k)
+	}
+	v.isSet = false
+	return nil
+}
+
+// String returns a string representation of the pairs, rendered as key=value,
+// sorted, and joined with ", ".
+func (v *Map[K, V]) String() string {
+	v.initialize()
+	strs := make([]string, 0, len(*v.Pointer))
+	for k, val := range *v.Pointer {
+		strs = append(strs, fmt.Sprintf("%v=%v", k, val))
+	}
+	sort.Strings(strs)
+	return strings.Join(strs, ", ")
+}
+
+// IsSet returns true if the map has been explicitly set.
+func (v *Map[K, V]) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns KEY=VALUE.
+func (v *Map[K, V]) GetPlaceholder() string {
+	return "KEY=VALUE"
+}
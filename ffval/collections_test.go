@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/peterbourgon/ff/v4/ffval"
@@ -142,6 +143,45 @@ func TestLists_reset(t *testing.T) {
 	}
 }
 
+func TestLists_default(t *testing.T) {
+	t.Parallel()
+
+	list := ffval.List[string]{Default: []string{"a"}}
+	set := ffval.UniqueList[string]{Default: []string{"a", "b"}}
+
+	if want, have := "a", list.String(); want != have {
+		t.Errorf("List: want %q, have %q", want, have)
+	}
+	if want, have := "a, b", set.String(); want != have {
+		t.Errorf("UniqueList: want %q, have %q", want, have)
+	}
+
+	for _, s := range []string{"b", "a", "b"} {
+		list.Set(s)
+		set.Set(s)
+	}
+
+	if want, have := "b, a, b", list.String(); want != have {
+		t.Errorf("List: want %q, have %q", want, have)
+	}
+	if want, have := "b, a", set.String(); want != have {
+		t.Errorf("UniqueList: want %q, have %q", want, have)
+	}
+
+	list.Reset()
+	set.Reset()
+
+	if want, have := "a", list.String(); want != have {
+		t.Errorf("List: after Reset: want %q, have %q", want, have)
+	}
+	if want, have := "a, b", set.String(); want != have {
+		t.Errorf("UniqueList: after Reset: want %q, have %q", want, have)
+	}
+	if want, have := []string{"a"}, list.Default; !reflect.DeepEqual(want, have) {
+		t.Errorf("List: Default: want %q, have %q", want, have)
+	}
+}
+
 func TestEnum(t *testing.T) {
 	t.Parallel()
 
@@ -223,6 +263,55 @@ func TestEnum(t *testing.T) {
 		}
 	})
 
+	t.Run("case insensitive", func(t *testing.T) {
+		var x string
+		e := &ffval.Enum[string]{
+			Valid:           []string{"debug", "info", "error"},
+			Pointer:         &x,
+			CaseInsensitive: true,
+		}
+		if err := e.Set("DEBUG"); err != nil {
+			t.Errorf("Set(DEBUG): %v", err)
+		}
+		if want, have := "debug", e.Get(); want != have {
+			t.Errorf("Get: want %q, have %q", want, have)
+		}
+		if err := e.Set("Error"); err != nil {
+			t.Errorf("Set(Error): %v", err)
+		}
+		if want, have := "error", x; want != have {
+			t.Errorf("x: want %q, have %q", want, have)
+		}
+		if err := e.Set("WARN"); !errors.Is(err, ffval.ErrInvalidValue) {
+			t.Errorf("Set(WARN): want %v, have %v", ffval.ErrInvalidValue, err)
+		}
+	})
+
+	t.Run("case sensitive", func(t *testing.T) {
+		e := ffval.NewEnum(new(string), "debug", "info")
+		if err := e.Set("DEBUG"); !errors.Is(err, ffval.ErrInvalidValue) {
+			t.Errorf("Set(DEBUG): want %v, have %v", ffval.ErrInvalidValue, err)
+		}
+	})
+
+	t.Run("ValidateSelf", func(t *testing.T) {
+		valid := &ffval.Enum[string]{Valid: []string{"a", "b"}, Default: "b"}
+		if err := valid.ValidateSelf(); err != nil {
+			t.Errorf("valid default: %v", err)
+		}
+		zero := &ffval.Enum[string]{Valid: []string{"a", "b"}}
+		if err := zero.ValidateSelf(); err != nil {
+			t.Errorf("zero default: %v", err)
+		}
+		invalid := &ffval.Enum[string]{Valid: []string{"a", "b"}, Default: "c"}
+		if err := invalid.ValidateSelf(); !errors.Is(err, ffval.ErrInvalidValue) {
+			t.Errorf("invalid default: want %v, have %v", ffval.ErrInvalidValue, err)
+		}
+		if want, have := "a", invalid.GetDefault(); want != have {
+			t.Errorf("GetDefault: want %q, have %q", want, have)
+		}
+	})
+
 	t.Run("custom type", func(t *testing.T) {
 		type myint int
 		var x myint
@@ -275,3 +364,247 @@ func TestEnum(t *testing.T) {
 		}
 	})
 }
+
+func TestOrderedMap(t *testing.T) {
+	t.Parallel()
+
+	var pairs []ffval.Pair[int]
+	val := ffval.NewOrderedMap(&pairs)
+
+	for _, s := range []string{"zeta=1", "alpha=2", "mu=3", "alpha=4", "omega=5"} {
+		if err := val.Set(s); err != nil {
+			t.Fatalf("Set(%q): %v", s, err)
+		}
+	}
+
+	want := []ffval.Pair[int]{{Key: "zeta", Value: 1}, {Key: "alpha", Value: 4}, {Key: "mu", Value: 3}, {Key: "omega", Value: 5}}
+	if have := val.Get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Get: want %v, have %v", want, have)
+	}
+
+	if have := pairs; !reflect.DeepEqual(want, have) {
+		t.Errorf("Pointer: want %v, have %v", want, have)
+	}
+
+	if want, have := "zeta=1, alpha=4, mu=3, omega=5", val.String(); want != have {
+		t.Errorf("String: want %q, have %q", want, have)
+	}
+
+	if v, ok := val.Lookup("mu"); !ok || v != 3 {
+		t.Errorf("Lookup(mu): want 3 true, have %v %v", v, ok)
+	}
+
+	for _, s := range []string{"novalue", "=1", "x=notanint"} {
+		if err := val.Set(s); err == nil {
+			t.Errorf("Set(%q): want error, have none", s)
+		}
+	}
+
+	if err := val.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	if want, have := "", val.String(); want != have {
+		t.Errorf("String after Reset: want %q, have %q", want, have)
+	}
+
+	if err := val.Set("alpha=1"); err != nil {
+		t.Fatalf("Set after Reset: %v", err)
+	}
+
+	if want, have := "alpha=1", val.String(); want != have {
+		t.Errorf("String after Reset and Set: want %q, have %q", want, have)
+	}
+}
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	var m map[string]int
+	val := ffval.NewMap(&m)
+
+	for _, s := range []string{"zeta=1", "alpha=2", "mu=3", "alpha=4", " omega =5"} {
+		if err := val.Set(s); err != nil {
+			t.Fatalf("Set(%q): %v", s, err)
+		}
+	}
+
+	want := map[string]int{"zeta": 1, "alpha": 4, "mu": 3, "omega": 5}
+	if have := val.Get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Get: want %v, have %v", want, have)
+	}
+
+	if have := m; !reflect.DeepEqual(want, have) {
+		t.Errorf("Pointer: want %v, have %v", want, have)
+	}
+
+	if want, have := "alpha=4, mu=3, omega=5, zeta=1", val.String(); want != have {
+		t.Errorf("String: want %q, have %q", want, have)
+	}
+
+	for _, s := range []string{"novalue", "=1", "x=notanint"} {
+		if err := val.Set(s); err == nil {
+			t.Errorf("Set(%q): want error, have none", s)
+		}
+	}
+
+	if err := val.Set("novalue"); err == nil || !strings.Contains(err.Error(), "missing =") {
+		t.Errorf("Set(novalue): want missing = error, have %v", err)
+	}
+
+	if err := val.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	if want, have := "", val.String(); want != have {
+		t.Errorf("String after Reset: want %q, have %q", want, have)
+	}
+
+	t.Run("split on first equals", func(t *testing.T) {
+		var m map[string]string
+		val := ffval.NewMap(&m)
+		if err := val.Set("query=a=b"); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if want, have := "a=b", m["query"]; want != have {
+			t.Errorf("want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("int keys", func(t *testing.T) {
+		val := &ffval.Map[int, string]{}
+		if err := val.Set("10=ten"); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := val.Set("x=ten"); err == nil {
+			t.Errorf("Set(x=ten): want error, have none")
+		}
+		if want, have := map[int]string{10: "ten"}, val.Get(); !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+	})
+
+	t.Run("split", func(t *testing.T) {
+		val := &ffval.StringMap{SplitFunc: ffval.SplitCommas}
+		if err := val.Set("a=1,b=2"); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := val.Set(`c=x\,y,d=`); err != nil {
+			t.Fatalf("Set escaped: %v", err)
+		}
+		if want, have := map[string]string{"a": "1", "b": "2", "c": "x,y", "d": ""}, val.Get(); !reflect.DeepEqual(want, have) {
+			t.Errorf("want %v, have %v", want, have)
+		}
+
+		err := val.Set("e=5,oops,f=6")
+		if err == nil || !strings.Contains(err.Error(), `"oops"`) {
+			t.Errorf("Set malformed: want error naming the pair, have %v", err)
+		}
+		if _, ok := val.Get()["e"]; ok {
+			t.Errorf("Set malformed: want map unmodified, have %v", val.Get())
+		}
+	})
+}
+
+func TestIntRangeList(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		max     int
+		inputs  []string
+		want    []int
+		wantErr bool
+	}{
+		{name: "single", inputs: []string{"8000"}, want: []int{8000}},
+		{name: "range", inputs: []string{"8000-8003"}, want: []int{8000, 8001, 8002, 8003}},
+		{name: "mixed", inputs: []string{"1", "5-6", "2"}, want: []int{1, 5, 6, 2}},
+		{name: "negative", inputs: []string{"-2-1", "-5--4"}, want: []int{-2, -1, 0, 1, -5, -4}},
+		{name: "one value range", inputs: []string{"7-7"}, want: []int{7}},
+		{name: "inverted", inputs: []string{"8010-8000"}, wantErr: true},
+		{name: "too large default", inputs: []string{"0-65536"}, wantErr: true},
+		{name: "too large custom", max: 10, inputs: []string{"1-11"}, wantErr: true},
+		{name: "max custom", max: 10, inputs: []string{"1-10"}, want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{name: "extreme bounds", inputs: []string{"-2-9223372036854775807"}, wantErr: true},
+		{name: "full range", inputs: []string{"-9223372036854775808-9223372036854775807"}, wantErr: true},
+		{name: "max int", inputs: []string{"9223372036854775806-9223372036854775807"}, want: []int{9223372036854775806, 9223372036854775807}},
+		{name: "invalid", inputs: []string{"a-b"}, wantErr: true},
+		{name: "empty", inputs: []string{""}, wantErr: true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var ints []int
+			v := &ffval.IntRangeList{Pointer: &ints, MaxSpan: test.max}
+
+			var err error
+			for _, input := range test.inputs {
+				if err = v.Set(input); err != nil {
+					break
+				}
+			}
+
+			switch {
+			case test.wantErr && err == nil:
+				t.Fatalf("want error, have none")
+			case !test.wantErr && err != nil:
+				t.Fatalf("Set: %v", err)
+			case test.wantErr:
+				return
+			}
+
+			if want, have := test.want, ints; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestFixedList(t *testing.T) {
+	t.Parallel()
+
+	var rgb []int
+	v := ffval.NewFixedList(&rgb, 3)
+
+	if err := v.ValidateParsed(); err != nil {
+		t.Errorf("ValidateParsed with no values: %v", err)
+	}
+
+	for _, s := range []string{"255", "0"} {
+		if err := v.Set(s); err != nil {
+			t.Fatalf("Set(%q): %v", s, err)
+		}
+	}
+	if err := v.ValidateParsed(); err == nil || !strings.Contains(err.Error(), "too few values") {
+		t.Errorf("ValidateParsed with 2 values: want too few values error, have %v", err)
+	}
+
+	if err := v.Set("128"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := v.ValidateParsed(); err != nil {
+		t.Errorf("ValidateParsed with 3 values: %v", err)
+	}
+	if want, have := []int{255, 0, 128}, v.Get(); !reflect.DeepEqual(want, have) {
+		t.Errorf("Get: want %v, have %v", want, have)
+	}
+	if want, have := []int{255, 0, 128}, rgb; !reflect.DeepEqual(want, have) {
+		t.Errorf("pointer: want %v, have %v", want, have)
+	}
+
+	if err := v.Set("1"); err == nil || !strings.Contains(err.Error(), "too many values") {
+		t.Errorf("Set with 4 values: want too many values error, have %v", err)
+	}
+
+	if err := v.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := 0, len(v.Get()); want != have {
+		t.Errorf("after Reset: want %d values, have %d", want, have)
+	}
+	if v.IsSet() {
+		t.Errorf("after Reset: IsSet: want false, have true")
+	}
+
+	if err := (&ffval.FixedList[int]{}).ValidateSelf(); err == nil {
+		t.Errorf("ValidateSelf with zero size: want error, have none")
+	}
+}
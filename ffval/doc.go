@@ -6,5 +6,9 @@
 //
 // [List] and [UniqueList] represent a sequence of values of type T, where each
 // call to set adds a value to the end of the list. [Enum] represents one of a
-// specific set of values of type T.
+// specific set of values of type T. [IntRangeList] represents a list of ints,
+// which can be set with ranges like 8000-8010. [OrderedMap] represents
+// insertion-ordered key/value pairs. [Count] represents a counter, incremented
+// by each repetition of a flag. [OptionalBool] represents a boolean which may be
+// true, false, or unset.
 package ffval
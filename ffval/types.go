@@ -1,6 +1,7 @@
 package ffval
 
 import (
+	"net/netip"
 	"reflect"
 	"strconv"
 	"time"
@@ -13,7 +14,7 @@ import (
 // useful, which in turn allows this package to provide common and useful types
 // like [Bool], [Duration], [StringSet], etc.
 type ValueType interface {
-	bool | int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64 | float32 | float64 | string | complex64 | complex128 | time.Duration
+	bool | int | int8 | int16 | int32 | int64 | uint | uint8 | uint16 | uint32 | uint64 | float32 | float64 | string | complex64 | complex128 | time.Duration | netip.Addr | netip.AddrPort
 }
 
 // Bool is a flag value representing a bool.
@@ -83,32 +84,49 @@ type Complex128 = Value[complex128]
 // Values are parsed by [time.ParseDuration].
 type Duration = Value[time.Duration]
 
+// Addr is a flag value representing a [netip.Addr], i.e. an IP address.
+// Values are parsed by [netip.ParseAddr].
+type Addr = Value[netip.Addr]
+
+// AddrPort is a flag value representing a [netip.AddrPort], i.e. an IP address
+// and port. Values are parsed by [netip.ParseAddrPort].
+type AddrPort = Value[netip.AddrPort]
+
 // StringList is a flag value representing a sequence of strings.
 type StringList = List[string]
 
 // StringSet is a flag value representing a unique set of strings.
 type StringSet = UniqueList[string]
 
+// StringOrderedMap is a flag value representing an insertion-ordered set of
+// key/value string pairs.
+type StringOrderedMap = OrderedMap[string]
+
+// StringMap is a flag value representing a map of string keys to string values.
+type StringMap = Map[string, string]
+
 //
 //
 //
 
 var defaultParseFuncs = map[reflect.Type]any{
-	reflect.TypeOf(*new(bool)):          strconv.ParseBool,
-	reflect.TypeOf(*new(int)):           strconv.Atoi,
-	reflect.TypeOf(*new(int8)):          func(s string) (int8, error) { v, err := strconv.ParseInt(s, 0, 8); return int8(v), err },
-	reflect.TypeOf(*new(int16)):         func(s string) (int16, error) { v, err := strconv.ParseInt(s, 0, 16); return int16(v), err },
-	reflect.TypeOf(*new(int32)):         func(s string) (int32, error) { v, err := strconv.ParseInt(s, 0, 32); return int32(v), err },
-	reflect.TypeOf(*new(int64)):         func(s string) (int64, error) { v, err := strconv.ParseInt(s, 0, 64); return int64(v), err },
-	reflect.TypeOf(*new(uint)):          func(s string) (uint, error) { v, err := strconv.ParseUint(s, 0, 64); return uint(v), err },
-	reflect.TypeOf(*new(uint8)):         func(s string) (uint8, error) { v, err := strconv.ParseUint(s, 0, 8); return uint8(v), err },
-	reflect.TypeOf(*new(uint16)):        func(s string) (uint16, error) { v, err := strconv.ParseUint(s, 0, 16); return uint16(v), err },
-	reflect.TypeOf(*new(uint32)):        func(s string) (uint32, error) { v, err := strconv.ParseUint(s, 0, 32); return uint32(v), err },
-	reflect.TypeOf(*new(uint64)):        func(s string) (uint64, error) { v, err := strconv.ParseUint(s, 0, 64); return uint64(v), err },
-	reflect.TypeOf(*new(float32)):       func(s string) (float32, error) { v, err := strconv.ParseFloat(s, 32); return float32(v), err },
-	reflect.TypeOf(*new(float64)):       func(s string) (float64, error) { v, err := strconv.ParseFloat(s, 64); return float64(v), err },
-	reflect.TypeOf(*new(string)):        func(s string) (string, error) { return s, nil },
-	reflect.TypeOf(*new(complex64)):     func(s string) (complex64, error) { v, err := strconv.ParseComplex(s, 64); return complex64(v), err },
-	reflect.TypeOf(*new(complex128)):    func(s string) (complex128, error) { v, err := strconv.ParseComplex(s, 128); return complex128(v), err },
-	reflect.TypeOf(*new(time.Duration)): time.ParseDuration,
+	reflect.TypeOf(*new(bool)):           strconv.ParseBool,
+	reflect.TypeOf(*new(int)):            strconv.Atoi,
+	reflect.TypeOf(*new(int8)):           func(s string) (int8, error) { v, err := strconv.ParseInt(s, 0, 8); return int8(v), err },
+	reflect.TypeOf(*new(int16)):          func(s string) (int16, error) { v, err := strconv.ParseInt(s, 0, 16); return int16(v), err },
+	reflect.TypeOf(*new(int32)):          func(s string) (int32, error) { v, err := strconv.ParseInt(s, 0, 32); return int32(v), err },
+	reflect.TypeOf(*new(int64)):          func(s string) (int64, error) { v, err := strconv.ParseInt(s, 0, 64); return int64(v), err },
+	reflect.TypeOf(*new(uint)):           func(s string) (uint, error) { v, err := strconv.ParseUint(s, 0, 64); return uint(v), err },
+	reflect.TypeOf(*new(uint8)):          func(s string) (uint8, error) { v, err := strconv.ParseUint(s, 0, 8); return uint8(v), err },
+	reflect.TypeOf(*new(uint16)):         func(s string) (uint16, error) { v, err := strconv.ParseUint(s, 0, 16); return uint16(v), err },
+	reflect.TypeOf(*new(uint32)):         func(s string) (uint32, error) { v, err := strconv.ParseUint(s, 0, 32); return uint32(v), err },
+	reflect.TypeOf(*new(uint64)):         func(s string) (uint64, error) { v, err := strconv.ParseUint(s, 0, 64); return uint64(v), err },
+	reflect.TypeOf(*new(float32)):        func(s string) (float32, error) { v, err := strconv.ParseFloat(s, 32); return float32(v), err },
+	reflect.TypeOf(*new(float64)):        func(s string) (float64, error) { v, err := strconv.ParseFloat(s, 64); return float64(v), err },
+	reflect.TypeOf(*new(string)):         func(s string) (string, error) { return s, nil },
+	reflect.TypeOf(*new(complex64)):      func(s string) (complex64, error) { v, err := strconv.ParseComplex(s, 64); return complex64(v), err },
+	reflect.TypeOf(*new(complex128)):     func(s string) (complex128, error) { v, err := strconv.ParseComplex(s, 128); return complex128(v), err },
+	reflect.TypeOf(*new(time.Duration)):  time.ParseDuration,
+	reflect.TypeOf(*new(netip.Addr)):     netip.ParseAddr,
+	reflect.TypeOf(*new(netip.AddrPort)): netip.ParseAddrPort,
 }
@@ -3,8 +3,17 @@ package ffval
 import (
 	"flag"
 	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Value is a generic [flag.Value] that can be set from a string.
@@ -131,24 +140,1096 @@ func (v *Value[T]) Reset() error {
 	return nil
 }
 
-// String returns a string representation of the value returned by Get.
+// String returns a string representation of the value returned by Get. Invalid
+// (zero) network addresses are represented as an empty string, rather than the
+// "invalid IP" produced by their String methods.
 func (v *Value[T]) String() string {
+	switch x := any(v.Get()).(type) {
+	case netip.Addr:
+		if !x.IsValid() {
+			return ""
+		}
+	case netip.AddrPort:
+		if !x.IsValid() {
+			return ""
+		}
+	}
 	return fmt.Sprint(v.Get())
 }
 
 // IsSet returns true if the value has been explicitly set.
-func (v Value[T]) IsSet() bool {
+func (v Value[T]) IsSet() bool {
+	return v.isSet
+}
+
+// IsBoolFlag returns true if the underlying type T is bool.
+func (v Value[T]) IsBoolFlag() bool {
+	switch x := any(v.Default); x.(type) {
+	case bool:
+		return true
+	default:
+		return false
+	}
+}
+
+//
+//
+//
+
+// Count is a [flag.Value] representing a counter. Every call to Set increments
+// the counter by one, regardless of the string. SetAbsolute instead assigns the
+// counter directly from an integer string, which is how values from sources
+// other than the commandline, like env vars, are applied.
+//
+// Count reports itself as a boolean flag, so it doesn't consume a value from
+// the commandline. This allows repeated short flags like -vvv to increment the
+// counter once per repetition.
+type Count struct {
+	// Pointer is the actual int which is managed and updated by the counter. If
+	// no Pointer is provided, a new int is allocated lazily. For this reason,
+	// callers should generally access the pointer via GetPointer, rather than
+	// reading the field directly.
+	Pointer *int
+
+	// Default value, which is zero by default.
+	Default int
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*Count)(nil)
+
+// Counter is an alias for [Count].
+type Counter = Count
+
+// NewCount returns a counter which updates the given pointer ptr when set, and
+// which has a default value of zero.
+func NewCount(ptr *int) *Count {
+	v := &Count{
+		Pointer: ptr,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *Count) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(int)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set increments the counter by one. The string is ignored.
+func (v *Count) Set(string) error {
+	v.initialize()
+	*v.Pointer++
+	v.isSet = true
+	return nil
+}
+
+// SetAbsolute assigns the counter directly if the string is an integer, e.g.
+// an env var VERBOSE=3 sets the counter to 3. Otherwise, the string must parse
+// as a boolean: true increments the counter like Set, and false resets it to
+// zero. It's used for values from sources other than the commandline.
+func (v *Count) SetAbsolute(s string) error {
+	v.initialize()
+
+	if n, err := strconv.Atoi(s); err == nil {
+		*v.Pointer = n
+		v.isSet = true
+		return nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+
+	if !b {
+		*v.Pointer = 0
+		v.isSet = true
+		return nil
+	}
+
+	return v.Set(s)
+}
+
+// Get the current count.
+func (v *Count) Get() int {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying int managed by the counter.
+func (v *Count) GetPointer() *int {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the counter to its default state.
+func (v *Count) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	return nil
+}
+
+// String returns a string representation of the current count.
+func (v *Count) String() string {
+	return strconv.Itoa(v.Get())
+}
+
+// IsSet returns true if the counter has been explicitly set.
+func (v *Count) IsSet() bool {
+	return v.isSet
+}
+
+// IsBoolFlag always returns true.
+func (v *Count) IsBoolFlag() bool {
+	return true
+}
+
+// OptionalBool is a [flag.Value] representing a boolean which may be unset,
+// i.e. a tri-state of true, false, or unset. The value is managed as a *bool,
+// which is nil when unset. This allows callers to distinguish a boolean that's
+// explicitly false from one that wasn't provided at all.
+//
+// Unlike a regular boolean flag, an optional bool doesn't report itself as a
+// boolean flag, so it always requires an explicit value on the commandline,
+// e.g. --foo=true or --foo false.
+type OptionalBool struct {
+	// Pointer is the actual *bool which is managed and updated by the value. If
+	// no Pointer is provided, a new *bool is allocated lazily. For this reason,
+	// callers should generally access the pointer via GetPointer, rather than
+	// reading the field directly.
+	Pointer **bool
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*OptionalBool)(nil)
+
+// NewOptionalBool returns an optional bool which updates the given pointer ptr
+// when set, and which is initially unset (nil).
+func NewOptionalBool(ptr **bool) *OptionalBool {
+	v := &OptionalBool{
+		Pointer: ptr,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *OptionalBool) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(*bool)
+	}
+
+	*v.Pointer = nil
+
+	v.initialized = true
+}
+
+// Set parses the given string as a boolean, and assigns it.
+func (v *OptionalBool) Set(s string) error {
+	v.initialize()
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	*v.Pointer = &b
+	v.isSet = true
+	return nil
+}
+
+// Get the current value, which is nil if unset.
+func (v *OptionalBool) Get() *bool {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying *bool.
+func (v *OptionalBool) GetPointer() **bool {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the value to its initial, unset state.
+func (v *OptionalBool) Reset() error {
+	v.initialize()
+	*v.Pointer = nil
+	v.isSet = false
+	return nil
+}
+
+// String returns "true" or "false" if the value is set, or the empty string if
+// it's unset.
+func (v *OptionalBool) String() string {
+	if b := v.Get(); b != nil {
+		return strconv.FormatBool(*b)
+	}
+	return ""
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *OptionalBool) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns BOOL.
+func (v *OptionalBool) GetPlaceholder() string {
+	return "BOOL"
+}
+
+//
+//
+//
+
+// ByteSize is a [flag.Value] representing a number of bytes, which is parsed
+// from a human-readable size like "10MB" or "512KiB". The number may have a
+// fractional part, e.g. "1.5GB", as long as the result is a whole number of
+// bytes. Units are case-insensitive, and may be decimal (B, KB, MB, GB, TB) or
+// binary (KiB, MiB, GiB, TiB). A number without a unit is a number of bytes.
+// Negative sizes are rejected.
+//
+// The unit used to set the value is remembered, so that String can render the
+// size in the same family of units, decimal or binary, as the user provided.
+type ByteSize struct {
+	// Pointer is the actual int64 which is managed and updated by the value. If
+	// no Pointer is provided, a new int64 is allocated lazily. For this reason,
+	// callers should generally access the pointer via GetPointer, rather than
+	// reading the field directly.
+	Pointer *int64
+
+	// Default value, which is zero by default.
+	Default int64
+
+	initialized bool
+	isSet       bool
+	unit        string
+}
+
+var _ flag.Value = (*ByteSize)(nil)
+
+// NewByteSize returns a byte size which updates the given pointer ptr when set,
+// and which has the given default value def.
+func NewByteSize(ptr *int64, def int64) *ByteSize {
+	v := &ByteSize{
+		Pointer: ptr,
+		Default: def,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *ByteSize) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(int64)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set parses the given string as a human-readable size, and assigns it.
+func (v *ByteSize) Set(s string) error {
+	v.initialize()
+
+	n, unit, err := parseByteSize(s)
+	if err != nil {
+		return err
+	}
+
+	*v.Pointer = n
+	v.isSet = true
+	v.unit = unit
+	return nil
+}
+
+// byteSizeUnits are ordered from largest to smallest, so that String prefers
+// larger units when representations are equally compact.
+var byteSizeUnits = []struct {
+	name string
+	size int64
+}{
+	{"TiB", 1 << 40},
+	{"TB", 1e12},
+	{"GiB", 1 << 30},
+	{"GB", 1e9},
+	{"MiB", 1 << 20},
+	{"MB", 1e6},
+	{"KiB", 1 << 10},
+	{"KB", 1e3},
+	{"B", 1},
+}
+
+// parseByteSize returns the number of bytes represented by s, as well as the
+// canonical name of the unit in s, e.g. MiB, or the empty string if s has no
+// unit.
+func parseByteSize(s string) (int64, string, error) {
+	s = strings.TrimSpace(s)
+
+	split := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if split < 0 {
+		split = len(s)
+	}
+	number, unit := s[:split], strings.TrimSpace(s[split:])
+
+	if strings.HasPrefix(s, "-") {
+		return 0, "", fmt.Errorf("%s: negative size", s)
+	}
+	if number == "" {
+		return 0, "", fmt.Errorf("%s: missing number", s)
+	}
+
+	size := int64(-1)
+	if unit == "" {
+		size = 1
+	}
+	for _, u := range byteSizeUnits {
+		if strings.EqualFold(unit, u.name) {
+			size, unit = u.size, u.name
+		}
+	}
+	if size < 0 {
+		return 0, "", fmt.Errorf("%s: unknown unit %q", s, unit)
+	}
+
+	whole, frac, _ := strings.Cut(number, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %w", s, err)
+	}
+	if w > math.MaxInt64/size {
+		return 0, "", fmt.Errorf("%s: size too large", s)
+	}
+	n := w * size
+
+	if frac != "" {
+		// The fraction is computed exactly, as frac * size / 10^len(frac), in
+		// integer arithmetic, so e.g. 0.000497MB is exactly 497 bytes.
+		f, ok := new(big.Int).SetString(frac, 10)
+		if !ok || strings.ContainsAny(frac, "+-") {
+			return 0, "", fmt.Errorf("%s: invalid fraction %q", s, frac)
+		}
+		var (
+			scale    = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(len(frac))), nil)
+			fb, rem  = new(big.Int).QuoRem(f.Mul(f, big.NewInt(size)), scale, new(big.Int))
+			maxBytes = big.NewInt(math.MaxInt64 - n)
+		)
+		if rem.Sign() != 0 {
+			return 0, "", fmt.Errorf("%s: not a whole number of bytes", s)
+		}
+		if fb.Cmp(maxBytes) > 0 {
+			return 0, "", fmt.Errorf("%s: size too large", s)
+		}
+		n += fb.Int64()
+	}
+
+	return n, unit, nil
+}
+
+// Get the current value.
+func (v *ByteSize) Get() int64 {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying int64.
+func (v *ByteSize) GetPointer() *int64 {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the value to its default state.
+func (v *ByteSize) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	v.unit = ""
+	return nil
+}
+
+// GetUnit returns the canonical name of the unit used to set the value, e.g.
+// MiB for an input of "512mib". It returns the empty string if the value hasn't
+// been set, or was set without a unit.
+func (v *ByteSize) GetUnit() string {
+	return v.unit
+}
+
+// String returns the most compact human-readable form of the size which can
+// be parsed back to exactly the same value, e.g. 10MB or 512KiB. If the value
+// was set with a decimal or binary unit, only units from the same family are
+// considered, so 512MiB is rendered as 512MiB rather than e.g. 536870912B, and
+// 500MB as 500MB. A zero size is represented as "0".
+func (v *ByteSize) String() string {
+	n := v.Get()
+	if n == 0 {
+		return "0"
+	}
+
+	binary := strings.HasSuffix(v.unit, "iB")
+	decimal := v.unit != "" && v.unit != "B" && !binary
+
+	var best string
+	for _, u := range byteSizeUnits {
+		if n%u.size != 0 {
+			continue
+		}
+		if isBinary := strings.HasSuffix(u.name, "iB"); (binary && !isBinary && u.name != "B") || (decimal && isBinary) {
+			continue
+		}
+		if s := strconv.FormatInt(n/u.size, 10) + u.name; best == "" || len(s) < len(best) {
+			best = s
+		}
+	}
+	return best
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *ByteSize) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns SIZE.
+func (v *ByteSize) GetPlaceholder() string {
+	return "SIZE"
+}
+
+//
+//
+//
+
+// MaxDuration is the largest representable duration. It's a useful value for
+// sentinels like "forever" or "never" in a [DurationOrSentinel].
+const MaxDuration = time.Duration(math.MaxInt64)
+
+// DurationOrSentinel is a [flag.Value] representing a duration, which is parsed
+// via [time.ParseDuration], unless the input matches one of a set of sentinel
+// strings, e.g. "forever" or "none", each of which maps to a specific duration.
+// Sentinels are matched without regard to case, unless the input matches one
+// exactly.
+type DurationOrSentinel struct {
+	// Pointer is the actual time.Duration which is managed and updated by the
+	// value. If no Pointer is provided, a new time.Duration is allocated
+	// lazily. For this reason, callers should generally access the pointer via
+	// GetPointer, rather than reading the field directly.
+	Pointer *time.Duration
+
+	// Default value, which is zero by default.
+	Default time.Duration
+
+	// Sentinels maps sentinel strings to the durations they represent, e.g.
+	// "forever" to [MaxDuration].
+	Sentinels map[string]time.Duration
+
+	initialized bool
+	isSet       bool
+	sentinel    string
+}
+
+var _ flag.Value = (*DurationOrSentinel)(nil)
+
+// NewDurationOrSentinel returns a duration which updates the given pointer ptr
+// when set, which has the given default value def, and which accepts the given
+// sentinels in addition to regular durations.
+func NewDurationOrSentinel(ptr *time.Duration, def time.Duration, sentinels map[string]time.Duration) *DurationOrSentinel {
+	v := &DurationOrSentinel{
+		Pointer:   ptr,
+		Default:   def,
+		Sentinels: sentinels,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *DurationOrSentinel) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(time.Duration)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set parses the given string as a sentinel or a duration, and assigns it.
+func (v *DurationOrSentinel) Set(s string) error {
+	v.initialize()
+
+	// An exact match wins. Otherwise, if several sentinels differ only in case,
+	// the first one in lexical order is used, so the result doesn't depend on
+	// map iteration order.
+	name, ok := s, false
+	if _, ok = v.Sentinels[s]; !ok {
+		for candidate := range v.Sentinels {
+			if strings.EqualFold(s, candidate) && (!ok || candidate < name) {
+				name, ok = candidate, true
+			}
+		}
+	}
+	if ok {
+		*v.Pointer = v.Sentinels[name]
+		v.isSet = true
+		v.sentinel = name
+		return nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*v.Pointer = d
+	v.isSet = true
+	v.sentinel = ""
+	return nil
+}
+
+// Get the current value.
+func (v *DurationOrSentinel) Get() time.Duration {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying time.Duration.
+func (v *DurationOrSentinel) GetPointer() *time.Duration {
+	v.initialize()
+	return v.Pointer
+}
+
+// GetSentinel returns the sentinel used to set the value, and true, or the
+// empty string and false if the value hasn't been set, or was set to a regular
+// duration.
+func (v *DurationOrSentinel) GetSentinel() (string, bool) {
+	return v.sentinel, v.sentinel != ""
+}
+
+// Reset the value to its default state.
+func (v *DurationOrSentinel) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	v.sentinel = ""
+	return nil
+}
+
+// String returns the sentinel used to set the value, if any. Otherwise, if the
+// current duration is represented by a sentinel, that sentinel is returned, or
+// the first one in lexical order if there are several. Otherwise, the duration
+// is rendered via [time.Duration.String].
+func (v *DurationOrSentinel) String() string {
+	if v.sentinel != "" {
+		return v.sentinel
+	}
+
+	d := v.Get()
+	var best string
+	for name, sd := range v.Sentinels {
+		if sd == d && (best == "" || name < best) {
+			best = name
+		}
+	}
+	if best != "" {
+		return best
+	}
+
+	return d.String()
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *DurationOrSentinel) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns DURATION.
+func (v *DurationOrSentinel) GetPlaceholder() string {
+	return "DURATION"
+}
+
+//
+//
+//
+
+// Time is a [flag.Value] representing a point in time, which is parsed and
+// formatted with a configurable layout, e.g. 2024-01-02T15:04:05Z.
+type Time struct {
+	// Pointer is the actual time.Time which is managed and updated by the
+	// value. If no Pointer is provided, a new time.Time is allocated lazily.
+	// For this reason, callers should generally access the pointer via
+	// GetPointer, rather than reading the field directly.
+	Pointer *time.Time
+
+	// Default value, which is the zero time by default.
+	Default time.Time
+
+	// Layout used to parse and format the time, as per [time.Parse]. If no
+	// Layout is provided, [time.RFC3339] is used.
+	Layout string
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*Time)(nil)
+
+// NewTime returns a time which updates the given pointer ptr when set, and
+// which parses and formats values with the given layout. If layout is empty,
+// [time.RFC3339] is used. The default value is the zero time.
+func NewTime(ptr *time.Time, layout string) *Time {
+	v := &Time{
+		Pointer: ptr,
+		Layout:  layout,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *Time) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(time.Time)
+	}
+
+	if v.Layout == "" {
+		v.Layout = time.RFC3339
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set parses the given string with the layout, and assigns it. An empty string
+// is parsed as the zero time.
+func (v *Time) Set(s string) error {
+	v.initialize()
+
+	var t time.Time
+	if s != "" {
+		parsed, err := time.Parse(v.Layout, s)
+		if err != nil {
+			return err
+		}
+		t = parsed
+	}
+
+	*v.Pointer = t
+	v.isSet = true
+	return nil
+}
+
+// Get the current value.
+func (v *Time) Get() time.Time {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying time.Time.
+func (v *Time) GetPointer() *time.Time {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the value to its default state.
+func (v *Time) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	return nil
+}
+
+// String returns the time formatted with the layout. The zero time is
+// represented as the empty string.
+func (v *Time) String() string {
+	t := v.Get()
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(v.Layout)
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *Time) IsSet() bool {
 	return v.isSet
 }
 
-// IsBoolFlag returns true if the underlying type T is bool.
-func (v Value[T]) IsBoolFlag() bool {
-	switch x := any(v.Default); x.(type) {
-	case bool:
-		return true
-	default:
-		return false
+// GetPlaceholder returns TIME.
+func (v *Time) GetPlaceholder() string {
+	return "TIME"
+}
+
+//
+//
+//
+
+// Regexp is a [flag.Value] representing a compiled regular expression, which is
+// parsed via [regexp.Compile].
+type Regexp struct {
+	// Pointer is the actual *regexp.Regexp which is managed and updated by the
+	// value. If no Pointer is provided, a new one is allocated lazily. For this
+	// reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer **regexp.Regexp
+
+	// Default value, which is nil by default.
+	Default *regexp.Regexp
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*Regexp)(nil)
+
+// NewRegexp returns a regexp which updates the given pointer ptr when set, and
+// which has the given default value def.
+func NewRegexp(ptr **regexp.Regexp, def *regexp.Regexp) *Regexp {
+	v := &Regexp{
+		Pointer: ptr,
+		Default: def,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *Regexp) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(*regexp.Regexp)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set compiles the given string as a regular expression, and assigns it.
+func (v *Regexp) Set(s string) error {
+	v.initialize()
+
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return err
+	}
+
+	*v.Pointer = re
+	v.isSet = true
+	return nil
+}
+
+// Get the current value.
+func (v *Regexp) Get() *regexp.Regexp {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying *regexp.Regexp.
+func (v *Regexp) GetPointer() **regexp.Regexp {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the value to its default state.
+func (v *Regexp) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	return nil
+}
+
+// String returns the source pattern of the regexp, or the empty string if the
+// regexp is nil.
+func (v *Regexp) String() string {
+	re := v.Get()
+	if re == nil {
+		return ""
+	}
+	return re.String()
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *Regexp) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns REGEXP.
+func (v *Regexp) GetPlaceholder() string {
+	return "REGEXP"
+}
+
+//
+//
+//
+
+// URL is a [flag.Value] representing a URL, which is parsed via [url.Parse], or
+// via [url.ParseRequestURI] if RequireAbsolute is true.
+type URL struct {
+	// Pointer is the actual url.URL which is managed and updated by the value.
+	// If no Pointer is provided, a new url.URL is allocated lazily. For this
+	// reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer *url.URL
+
+	// Default value, which is the zero (empty) URL by default.
+	Default url.URL
+
+	// RequireAbsolute, if true, makes Set reject URLs which aren't absolute,
+	// i.e. which don't have a scheme.
+	RequireAbsolute bool
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*URL)(nil)
+
+// NewURL returns a URL which updates the given pointer ptr when set, and which
+// has the given default value def.
+func NewURL(ptr *url.URL, def url.URL) *URL {
+	v := &URL{
+		Pointer: ptr,
+		Default: def,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *URL) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(url.URL)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set parses the given string as a URL, and assigns it.
+func (v *URL) Set(s string) error {
+	v.initialize()
+
+	parse := url.Parse
+	if v.RequireAbsolute {
+		parse = url.ParseRequestURI
+	}
+
+	u, err := parse(s)
+	if err != nil {
+		return err
+	}
+
+	if v.RequireAbsolute && !u.IsAbs() {
+		return fmt.Errorf("%s: URL must be absolute", s)
+	}
+
+	*v.Pointer = *u
+	v.isSet = true
+	return nil
+}
+
+// Get the current value.
+func (v *URL) Get() url.URL {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying url.URL.
+func (v *URL) GetPointer() *url.URL {
+	v.initialize()
+	return v.Pointer
+}
+
+// Reset the value to its default state.
+func (v *URL) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	return nil
+}
+
+// String returns the URL as it was parsed. The zero URL is represented as the
+// empty string.
+func (v *URL) String() string {
+	v.initialize()
+	return v.Pointer.String()
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *URL) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns URL.
+func (v *URL) GetPlaceholder() string {
+	return "URL"
+}
+
+//
+//
+//
+
+// File is a [flag.Value] representing the path to a file. Set resolves the
+// given path to an absolute path via [filepath.Abs]. If MustExist is true, Set
+// also verifies that the file exists, and can be opened with Flags and Perm, so
+// that a bad path fails at parse time, with a clear error, rather than later
+// on. The file is closed immediately after the check.
+type File struct {
+	// Pointer is the actual string which is managed and updated by the value.
+	// If no Pointer is provided, a new string is allocated lazily. For this
+	// reason, callers should generally access the pointer via GetPointer,
+	// rather than reading the field directly.
+	Pointer *string
+
+	// Default path, which is the empty string by default. The default is used
+	// as-is, and isn't resolved or checked.
+	Default string
+
+	// Flags passed to [os.OpenFile] when checking the file, and by Open.
+	// Zero means [os.O_RDONLY].
+	Flags int
+
+	// Perm passed to [os.OpenFile] when checking the file, and by Open. It's
+	// only relevant when Flags includes [os.O_CREATE].
+	Perm os.FileMode
+
+	// MustExist, if true, makes Set reject paths which don't exist, or which
+	// can't be opened with Flags and Perm.
+	MustExist bool
+
+	initialized bool
+	isSet       bool
+}
+
+var _ flag.Value = (*File)(nil)
+
+// NewFile returns a file which updates the given pointer ptr when set, and
+// which has the given default path def. If mustExist is true, paths are checked
+// when set, see [File.MustExist].
+func NewFile(ptr *string, def string, mustExist bool) *File {
+	v := &File{
+		Pointer:   ptr,
+		Default:   def,
+		MustExist: mustExist,
+	}
+	v.initialize()
+	return v
+}
+
+func (v *File) initialize() {
+	if v.initialized {
+		return
+	}
+
+	if v.Pointer == nil {
+		v.Pointer = new(string)
+	}
+
+	*v.Pointer = v.Default
+
+	v.initialized = true
+}
+
+// Set resolves the given string to an absolute path, checks it if MustExist is
+// true, and assigns it.
+func (v *File) Set(s string) error {
+	v.initialize()
+
+	if s == "" {
+		return fmt.Errorf("empty path")
+	}
+
+	path, err := filepath.Abs(s)
+	if err != nil {
+		return err
+	}
+
+	if v.MustExist {
+		f, err := os.OpenFile(path, v.Flags, v.Perm)
+		if err != nil {
+			return err
+		}
+		f.Close()
 	}
+
+	*v.Pointer = path
+	v.isSet = true
+	return nil
+}
+
+// Get the current value, which is an absolute path if the value has been set.
+func (v *File) Get() string {
+	v.initialize()
+	return *v.Pointer
+}
+
+// GetPointer returns a pointer to the underlying string.
+func (v *File) GetPointer() *string {
+	v.initialize()
+	return v.Pointer
+}
+
+// Open the file at the current path with Flags and Perm, via [os.OpenFile].
+func (v *File) Open() (*os.File, error) {
+	return os.OpenFile(v.Get(), v.Flags, v.Perm)
+}
+
+// Reset the value to its default state.
+func (v *File) Reset() error {
+	v.initialize()
+	*v.Pointer = v.Default
+	v.isSet = false
+	return nil
+}
+
+// String returns the current path.
+func (v *File) String() string {
+	return v.Get()
+}
+
+// IsSet returns true if the value has been explicitly set.
+func (v *File) IsSet() bool {
+	return v.isSet
+}
+
+// GetPlaceholder returns FILE.
+func (v *File) GetPlaceholder() string {
+	return "FILE"
 }
 
 //
@@ -156,14 +1237,15 @@ func (v Value[T]) IsBoolFlag() bool {
 //
 
 type reflectValue struct {
-	set func(string) error
-	get func() string
+	set   func(string) error
+	get   func() string
+	value func() any
 
 	isBoolFlag  bool
 	placeholder string
 }
 
-var _ flag.Value = (*reflectValue)(nil)
+var _ flag.Getter = (*reflectValue)(nil)
 
 // NewValueReflect produces a simple [flag.Value] which updates ptr when set.
 // ptr must be a pointer to a supported [ValueType]. If def is non-empty, the
@@ -223,14 +1305,20 @@ func NewValueReflect(ptr any, def string) (flag.Value, error) {
 	isBoolFlag := typ.ConvertibleTo(reflect.TypeOf(*new(bool)))
 	placeholder := strings.ToUpper(typ.Name())
 
+	value := func() any {
+		return dst.Interface()
+	}
+
 	return &reflectValue{
 		set:         set,
 		get:         get,
+		value:       value,
 		isBoolFlag:  isBoolFlag,
 		placeholder: placeholder,
 	}, nil
 }
 
+func (v *reflectValue) Get() any               { return v.value() }
 func (v *reflectValue) Set(s string) error     { return v.set(s) }
 func (v *reflectValue) String() string         { return v.get() }
 func (v *reflectValue) IsBoolFlag() bool       { return v.isBoolFlag }
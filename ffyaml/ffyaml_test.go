@@ -2,6 +2,8 @@ package ffyaml_test
 
 import (
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +24,7 @@ func TestParser(t *testing.T) {
 		{
 			Name:       "basic KV pairs",
 			ConfigFile: "testdata/basic.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "hello", I: 10, B: true, D: 5 * time.Second, F: 3.14},
 		},
 		{
@@ -33,32 +36,38 @@ func TestParser(t *testing.T) {
 			Name:       "no value for s",
 			Default:    fftest.Vars{S: "xxx", I: 123, F: 9.99},
 			ConfigFile: "testdata/no_value_s.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "", I: 123, F: 9.99},
 		},
 		{
 			Name:       "no value for i",
 			Default:    fftest.Vars{S: "xxx", I: 123, F: 9.99},
 			ConfigFile: "testdata/no_value_i.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{WantParseErrorString: "parse error"},
 		},
 		{
 			Name:       "basic arrays",
 			ConfigFile: "testdata/basic_array.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "c", X: []string{"a", "b", "c"}},
 		},
 		{
 			Name:       "multiline arrays",
 			ConfigFile: "testdata/multi_line_array.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "c", X: []string{"d", "e", "f"}},
 		},
 		{
 			Name:       "line break arrays",
 			ConfigFile: "testdata/line_break_array.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{X: []string{"first string", "second string", "third"}},
 		},
 		{
 			Name:       "unquoted strings in arrays",
 			ConfigFile: "testdata/unquoted_string_array.yaml",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{X: []string{"one", "two", "three"}},
 		},
 		{
@@ -90,3 +99,23 @@ func TestParser(t *testing.T) {
 
 	testcases.Run(t)
 }
+
+func TestParse_NestedAndRepeated(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	addr := fs.StringLong("server.addr", "", "listen address")
+	peers := fs.StringListLong("peer", "peer addresses")
+
+	input := "server:\n  addr: \":8080\"\npeer:\n  - c\n  - a\n  - b\n"
+	if err := ffyaml.Parse(strings.NewReader(input), fs.Set); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if want, have := ":8080", *addr; want != have {
+		t.Errorf("server.addr: want %q, have %q", want, have)
+	}
+	if want, have := []string{"c", "a", "b"}, *peers; !reflect.DeepEqual(want, have) {
+		t.Errorf("peer: want %v, have %v", want, have)
+	}
+}
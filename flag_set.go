@@ -1,8 +1,13 @@
 package ff
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -19,9 +24,22 @@ type FlagSet struct {
 	name          string
 	flags         []*coreFlag
 	isParsed      bool
+	rawArgs       []string // args as provided to the most recent parse
 	postParseArgs []string
 	isStdAdapter  bool // stdlib package flag behavior: treat -foo the same as --foo
 	parent        *FlagSet
+	placeholderFn func(FlagConfig) string
+	multiTerm     bool                               // split post-parse args into segments on every --
+	argSegments   [][]string                         // only set if multiTerm is true
+	unknownArgs   *[]string                          // if non-nil, capture unknown long flags here
+	unknownAsArgs bool                               // keep unknown flags in the post-parse args
+	unknownKept   []string                           // only set during parseArgs
+	noClustering  bool                               // treat each -x token as a single short flag
+	transform     func(Flag, string) (string, error) // only set during Parse
+	setVia        func(*coreFlag, string) error      // only set during parseArgsVia
+	isFrozen      bool                               // if true, flag values can't be set
+	exclusive     [][]Flag                           // see MarkMutuallyExclusive
+	together      [][]Flag                           // see MarkRequiredTogether
 }
 
 var _ Flags = (*FlagSet)(nil)
@@ -33,9 +51,19 @@ func NewFlagSet(name string) *FlagSet {
 		name:          name,
 		flags:         []*coreFlag{},
 		isParsed:      false,
+		rawArgs:       nil,
 		postParseArgs: []string{},
 		isStdAdapter:  false,
 		parent:        nil,
+		placeholderFn: nil,
+		multiTerm:     false,
+		argSegments:   nil,
+		unknownArgs:   nil,
+		unknownAsArgs: false,
+		unknownKept:   nil,
+		noClustering:  false,
+		transform:     nil,
+		isFrozen:      false,
 	}
 }
 
@@ -88,6 +116,113 @@ func (fs *FlagSet) SetParent(parent *FlagSet) *FlagSet {
 	return fs
 }
 
+// SetPlaceholderFunc assigns a function which derives placeholders for flags
+// that don't have an explicit placeholder. It's called with the config of each
+// subsequently added flag that doesn't specify Placeholder or NoPlaceholder,
+// and doesn't contain a `backtick` quoted substring in its usage string. If the
+// function returns a non-empty string, that string is used as the placeholder.
+// Otherwise, the default placeholder based on the value type is used.
+//
+// The function only applies to flags added after it's set, so it should
+// typically be set immediately after construction.
+//
+// This method returns its receiver to allow for builder-style initialization.
+func (fs *FlagSet) SetPlaceholderFunc(fn func(FlagConfig) string) *FlagSet {
+	fs.placeholderFn = fn
+	return fs
+}
+
+// SetMultiTerminator controls how the flag set treats args after the first
+// `--` terminator. By default, all args after the first terminator are left
+// over after parsing, and returned as-is by GetArgs, including any subsequent
+// terminators. If multi-terminator is enabled, the left over args are split
+// into segments on every subsequent `--` terminator, which are returned by
+// [FlagSet.GetArgSegments]. In that case, GetArgs returns only the first
+// segment.
+//
+// For example, with multi-terminator enabled, the args
+//
+//	--foo=1 -- inner-cmd --inner-flag -- inner-args
+//
+// set the flag foo, and produce the segments [inner-cmd --inner-flag] and
+// [inner-args]. Flags in segments are never parsed, only collected.
+//
+// This method returns its receiver to allow for builder-style initialization.
+func (fs *FlagSet) SetMultiTerminator(enabled bool) *FlagSet {
+	fs.multiTerm = enabled
+	return fs
+}
+
+// SetShortFlagClustering controls whether short flags may be clustered in a
+// single arg. Clustering is enabled by default, following getopt(3), so -abc is
+// parsed as -a -b -c if those are boolean flags, and -sfoo is parsed as -s foo
+// if s takes a value.
+//
+// If clustering is disabled, each short flag arg must contain exactly one short
+// flag. The arg -x sets the flag x, consuming the next arg as a value if x isn't
+// a boolean flag, and -x=value sets the flag x to the explicit value. Any other
+// arg, like -abc or -sfoo, is rejected as an unknown flag, e.g. "abc". This is
+// similar to the behavior of package flag, without the need to treat -foo and
+// --foo as equivalent.
+//
+// This method returns its receiver to allow for builder-style initialization.
+func (fs *FlagSet) SetShortFlagClustering(enabled bool) *FlagSet {
+	fs.noClustering = !enabled
+	return fs
+}
+
+// CaptureUnknown tells the flag set to append unknown long flags, and their
+// values, to the given pointer during parse, rather than returning an error.
+// This can be useful for programs which forward flags they don't understand to
+// some other program.
+//
+// Unknown flags are captured verbatim, according to the following rules. A
+// flag with an explicit value, like `--foo=bar`, is captured as a single arg.
+// A flag without an explicit value, like `--foo`, consumes the next arg as its
+// value, unless that arg begins with a hyphen, or there are no more args. In
+// that case, the flag is captured as a single arg, which is typical of boolean
+// flags. Otherwise, the flag and its value are captured as two args, e.g.
+// `--foo bar` is captured as "--foo", "bar".
+//
+// Unknown short flags, and the special --help flag, are not captured, and are
+// reported as errors as usual. Resetting the flag set truncates the captured
+// args.
+//
+// This method returns its receiver to allow for builder-style initialization.
+func (fs *FlagSet) CaptureUnknown(pointer *[]string) *FlagSet {
+	fs.unknownArgs = pointer
+	return fs
+}
+
+// SetUnknownFlagsAsArgs controls how the flag set treats unknown flags. By
+// default, parse fails with [ErrUnknownFlag]. If enabled, unknown flags, long
+// or short, are instead kept in the args left over after parsing, in their
+// original order relative to any other left over args, and parsing continues.
+// This can be useful for programs which embed some other component that parses
+// its own flags from the left over args.
+//
+// An unknown flag is kept verbatim, and may consume the next arg as its value,
+// according to the following heuristic. A flag with an explicit value, like
+// `--foo=bar` or `-xbar`, never consumes the next arg. Otherwise, a flag like
+// `--foo` or `-x` consumes the next arg, unless that arg begins with a hyphen,
+// or there are no more args. It's not possible to know whether an unknown flag
+// is a boolean flag, so `--foo bar` is always treated as a flag with a value,
+// which means parsing continues after bar. In a clustered short flag arg, like
+// `-vxbar`, known flags are parsed as usual, and everything from the first
+// unknown flag onward is kept as a single arg, e.g. `-xbar`.
+//
+// If parsing is terminated by `--` after any unknown flags were kept, the
+// terminator is also kept, so that the left over args can be parsed in turn.
+// The special -h and --help flags still produce [ErrHelp]. If
+// [FlagSet.CaptureUnknown] is also set, it takes precedence for unknown long
+// flags.
+//
+// This method returns its receiver to allow for builder-style initialization.
+func (fs *FlagSet) SetUnknownFlagsAsArgs(enabled bool) *FlagSet {
+	fs.unknownAsArgs = enabled
+	return fs
+}
+
 // GetName returns the name of the flag set provided during construction.
 func (fs *FlagSet) GetName() string {
 	return fs.name
@@ -100,10 +235,16 @@ func (fs *FlagSet) GetName() string {
 // subsequent calls to parse fail with [ErrAlreadyParsed], until and unless the
 // flag set is reset.
 func (fs *FlagSet) Parse(args []string) error {
+	if fs.isFrozen {
+		return ErrFrozen
+	}
+
 	if fs.isParsed {
 		return ErrAlreadyParsed
 	}
 
+	fs.rawArgs = append([]string{}, args...)
+
 	err := fs.parseArgs(args)
 	switch {
 	case err == nil:
@@ -111,10 +252,64 @@ func (fs *FlagSet) Parse(args []string) error {
 	case err != nil:
 		fs.postParseArgs = []string{}
 	}
+
+	if err == nil && fs.multiTerm {
+		fs.argSegments = splitArgSegments(fs.postParseArgs)
+		fs.postParseArgs = fs.argSegments[0]
+	}
+
 	return err
 }
 
+func splitArgSegments(args []string) [][]string {
+	segments := [][]string{{}}
+	for _, arg := range args {
+		if arg == "--" {
+			segments = append(segments, []string{})
+			continue
+		}
+		segments[len(segments)-1] = append(segments[len(segments)-1], arg)
+	}
+	return segments
+}
+
 func (fs *FlagSet) parseArgs(args []string) (err error) {
+	// Unknown flags kept by SetUnknownFlagsAsArgs precede the left over args.
+	fs.unknownKept = nil
+	defer func() {
+		if len(fs.unknownKept) > 0 {
+			fs.postParseArgs = append(fs.unknownKept, fs.postParseArgs...)
+		}
+		fs.unknownKept = nil
+	}()
+
+	return fs.matchArgs(args)
+}
+
+// parseArgsVia matches args to flags exactly like the commandline, but passes
+// each matched flag and its value to set, rather than setting the flag. Unknown
+// flags are returned, rather than producing an error, along with any args left
+// over after the flags. The parse state of the flag set is unchanged.
+func (fs *FlagSet) parseArgsVia(args []string, set func(f *coreFlag, value string) error) (unknown, rest []string, err error) {
+	var (
+		postParseArgs = fs.postParseArgs
+		unknownArgs   = fs.unknownArgs
+		unknownAsArgs = fs.unknownAsArgs
+	)
+	fs.setVia, fs.unknownArgs, fs.unknownAsArgs, fs.unknownKept = set, nil, true, nil
+	defer func() {
+		fs.setVia, fs.unknownArgs, fs.unknownAsArgs, fs.unknownKept = nil, unknownArgs, unknownAsArgs, nil
+		fs.postParseArgs = postParseArgs
+	}()
+
+	if err := fs.matchArgs(args); err != nil {
+		return nil, nil, err
+	}
+
+	return fs.unknownKept, fs.postParseArgs, nil
+}
+
+func (fs *FlagSet) matchArgs(args []string) error {
 	// Credit where credit is due: this implementation is adapted from
 	// https://pkg.go.dev/github.com/pborman/getopt/v2.
 
@@ -135,6 +330,9 @@ func (fs *FlagSet) parseArgs(args []string) (err error) {
 
 		if arg == "--" {
 			fs.postParseArgs = args // fs.postParseArgs should not include "--"
+			if len(fs.unknownKept) > 0 {
+				fs.unknownKept = append(fs.unknownKept, arg) // unless unknown flags precede it
+			}
 			return nil
 		}
 
@@ -197,6 +395,10 @@ func (fs *FlagSet) findLongFlag(long string) *coreFlag {
 }
 
 func (fs *FlagSet) parseShortFlag(arg string, args []string) ([]string, error) {
+	if fs.noClustering {
+		return fs.parseSingleShortFlag(arg, args)
+	}
+
 	arg = strings.TrimPrefix(arg, "-")
 
 	for i, r := range arg {
@@ -207,6 +409,8 @@ func (fs *FlagSet) parseShortFlag(arg string, args []string) ([]string, error) {
 				return args, nil
 			case r == 'h':
 				return args, ErrHelp
+			case fs.unknownAsArgs:
+				return fs.keepUnknown("-"+arg[i:], len(arg[i:]) > utf8.RuneLen(r), args), nil
 			default:
 				return args, fmt.Errorf("%w %q", ErrUnknownFlag, string(r))
 			}
@@ -215,7 +419,7 @@ func (fs *FlagSet) parseShortFlag(arg string, args []string) ([]string, error) {
 		var value string
 		switch {
 		case f.isBoolFlag:
-			value = "true" // -b -> b=true
+			value = "true" // -b -> b=true, and -vvv -> v=true three times (e.g. counts)
 		default:
 			value = arg[i+1:] // -sabc -> s=abc
 			if value == "" {
@@ -227,10 +431,9 @@ func (fs *FlagSet) parseShortFlag(arg string, args []string) ([]string, error) {
 			}
 		}
 
-		if err := f.flagValue.Set(value); err != nil {
-			return args, newFlagError(f, fmt.Errorf("set %q: %w", value, err))
+		if err := fs.setFlagValue(f, value); err != nil {
+			return args, newFlagError(f, err)
 		}
-		f.isSet = true
 
 		if !f.isBoolFlag {
 			return args, nil
@@ -240,14 +443,78 @@ func (fs *FlagSet) parseShortFlag(arg string, args []string) ([]string, error) {
 	return args, nil
 }
 
+func (fs *FlagSet) parseSingleShortFlag(arg string, args []string) ([]string, error) {
+	var (
+		name     = strings.TrimPrefix(arg, "-")
+		value    string
+		hasValue bool
+	)
+
+	// Prefix flags take the rest of the arg as their value, e.g. -Xfoo=bar.
+	if short, size := utf8.DecodeRuneInString(name); len(name) > size {
+		if f := fs.findShortFlag(short); f != nil && f.isPrefix {
+			if err := fs.setFlagValue(f, name[size:]); err != nil {
+				return args, newFlagError(f, err)
+			}
+			return args, nil
+		}
+	}
+
+	if equals := strings.IndexRune(name, '='); equals > 0 {
+		name, value, hasValue = name[:equals], name[equals+1:], true
+	}
+
+	if name == "-" { // `-` == `--`
+		return args, nil
+	}
+
+	if utf8.RuneCountInString(name) != 1 {
+		if fs.unknownAsArgs {
+			return fs.keepUnknown(arg, hasValue, args), nil
+		}
+		return args, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+
+	short, _ := utf8.DecodeRuneInString(name)
+	f := fs.findShortFlag(short)
+	if f == nil {
+		switch {
+		case short == 'h':
+			return args, ErrHelp
+		case fs.unknownAsArgs:
+			return fs.keepUnknown(arg, hasValue, args), nil
+		default:
+			return args, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+	}
+
+	if !hasValue {
+		switch {
+		case f.isBoolFlag:
+			value = "true"
+		case len(args) > 0:
+			value, args = args[0], args[1:]
+		default:
+			return args, newFlagError(f, fmt.Errorf("set: missing argument"))
+		}
+	}
+
+	if err := fs.setFlagValue(f, value); err != nil {
+		return args, newFlagError(f, err)
+	}
+
+	return args, nil
+}
+
 func (fs *FlagSet) parseLongFlag(arg string, args []string) ([]string, error) {
 	var (
-		name  string
-		value string
+		name     string
+		value    string
+		hasValue bool
 	)
 
 	if equals := strings.IndexRune(arg, '='); equals > 0 {
-		arg, value = arg[:equals], arg[equals+1:]
+		arg, value, hasValue = arg[:equals], arg[equals+1:], true
 	}
 
 	name = strings.TrimPrefix(arg, "--")
@@ -259,6 +526,12 @@ func (fs *FlagSet) parseLongFlag(arg string, args []string) ([]string, error) {
 			return nil, ErrHelp
 		case fs.isStdAdapter && strings.EqualFold(name, "h"):
 			return nil, ErrHelp
+		case fs.unknownArgs != nil:
+			return fs.captureUnknown(arg, value, hasValue, args), nil
+		case fs.unknownAsArgs && hasValue:
+			return fs.keepUnknown(arg+"="+value, true, args), nil
+		case fs.unknownAsArgs:
+			return fs.keepUnknown(arg, false, args), nil
 		default:
 			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
 		}
@@ -283,19 +556,92 @@ func (fs *FlagSet) parseLongFlag(arg string, args []string) ([]string, error) {
 		}
 	}
 
-	if err := f.flagValue.Set(value); err != nil {
-		return nil, newFlagError(f, fmt.Errorf("set %q: %w", value, err))
+	if err := fs.setFlagValue(f, value); err != nil {
+		return nil, newFlagError(f, err)
 	}
-	f.isSet = true
 
 	return args, nil
 }
 
+// setFlagValue sets the flag to the value from the commandline, after passing
+// it through the transform provided via [WithValueTransform], if any.
+func (fs *FlagSet) setFlagValue(f *coreFlag, value string) error {
+	if fs.setVia != nil {
+		return fs.setVia(f, value)
+	}
+
+	if f.flagSet.isFrozen {
+		return ErrFrozen
+	}
+
+	if fs.transform != nil {
+		transformed, err := fs.transform(f, value)
+		if err != nil {
+			return fmt.Errorf("transform %q: %w", value, err)
+		}
+		value = transformed
+	}
+
+	if err := f.set(value); err != nil {
+		return fmt.Errorf("set %q: %w", value, err)
+	}
+
+	return nil
+}
+
+// captureUnknown records the unknown long flag arg, with an explicit value if
+// one was provided, or else with the next arg if it looks like a value.
+func (fs *FlagSet) captureUnknown(arg, value string, hasValue bool, args []string) []string {
+	if hasValue {
+		arg = arg + "=" + value // --foo=bar
+	}
+	var unknown []string
+	unknown, args = splitUnknown(arg, hasValue, args)
+	*fs.unknownArgs = append(*fs.unknownArgs, unknown...)
+	return args
+}
+
+// keepUnknown is like captureUnknown, but keeps the unknown flag arg, which
+// should include any explicit value, in the post-parse args.
+func (fs *FlagSet) keepUnknown(arg string, hasValue bool, args []string) []string {
+	var unknown []string
+	unknown, args = splitUnknown(arg, hasValue, args)
+	fs.unknownKept = append(fs.unknownKept, unknown...)
+	return args
+}
+
+// splitUnknown returns the unknown flag arg, followed by the next arg if the
+// flag has no explicit value and the next arg looks like a value, as well as
+// the remaining args.
+func splitUnknown(arg string, hasValue bool, args []string) (unknown, rest []string) {
+	if !hasValue && len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		return []string{arg, args[0]}, args[1:] // --foo bar
+	}
+	return []string{arg}, args // --foo or --foo=bar
+}
+
 // IsParsed returns true if the flag set has been successfully parsed.
 func (fs *FlagSet) IsParsed() bool {
 	return fs.isParsed
 }
 
+// Freeze marks the flag set as immutable. Subsequent attempts to set the value
+// of any flag defined in the flag set, whether via [Flag.SetValue], a parse, or
+// [FlagSet.InheritValues], fail with [ErrFrozen]. Freeze only affects the flags
+// defined in the receiver, and not any parent flags. It's intended to be called
+// after parsing and validation, to guard against accidental mutation later on,
+// e.g. by background goroutines in long-running programs.
+//
+// A frozen flag set is unfrozen by [FlagSet.Reset].
+func (fs *FlagSet) Freeze() {
+	fs.isFrozen = true
+}
+
+// IsFrozen returns true if the flag set has been frozen via [FlagSet.Freeze].
+func (fs *FlagSet) IsFrozen() bool {
+	return fs.isFrozen
+}
+
 // WalkFlags calls fn for every flag known to the flag set. This includes all
 // parent flags, if a parent has been set.
 func (fs *FlagSet) WalkFlags(fn func(Flag) error) error {
@@ -309,6 +655,34 @@ func (fs *FlagSet) WalkFlags(fn func(Flag) error) error {
 	return nil
 }
 
+// Visit is like [FlagSet.WalkFlags], but only calls fn for flags which have
+// been set, i.e. whose IsSet method returns true. This includes all parent
+// flags, if a parent has been set. It's similar to [flag.FlagSet.Visit].
+func (fs *FlagSet) Visit(fn func(Flag) error) error {
+	return fs.WalkFlags(func(f Flag) error {
+		if !f.IsSet() {
+			return nil
+		}
+		return fn(f)
+	})
+}
+
+// ChangedFlags returns every flag known to the flag set which has been set to a
+// value that differs from its default, i.e. whose IsSet method returns true and
+// whose GetValue differs from its GetDefault. This includes all parent flags,
+// if a parent has been set. Flags explicitly set to their default value aren't
+// considered changed.
+func (fs *FlagSet) ChangedFlags() []Flag {
+	var changed []Flag
+	fs.Visit(func(f Flag) error {
+		if f.GetValue() != f.GetDefault() {
+			changed = append(changed, f)
+		}
+		return nil
+	})
+	return changed
+}
+
 // GetFlag returns the first flag known to the flag set that matches the given
 // name. This includes all parent flags, if a parent has been set. The name is
 // compared against each flag's long name, and, if the name is a single rune,
@@ -334,11 +708,159 @@ func (fs *FlagSet) GetFlag(name string) (Flag, bool) {
 	return f, true
 }
 
-// GetArgs returns the args left over after a successful parse.
+// MarkMutuallyExclusive records a group of flags, by name, of which at most one
+// may be set. If more than one flag in the group is set after all parse stages,
+// including env vars and config files, [Parse] returns [ErrMutuallyExclusive],
+// naming the conflicting flags. Names are resolved like [FlagSet.GetFlag], so
+// the group may include parent flags. At least two names are required.
+func (fs *FlagSet) MarkMutuallyExclusive(names ...string) error {
+	group, err := fs.getFlagGroup(names)
+	if err != nil {
+		return err
+	}
+	fs.exclusive = append(fs.exclusive, group)
+	return nil
+}
+
+// MarkRequiredTogether records a group of flags, by name, which must be set
+// together, or not at all. If some, but not all, of the flags in the group are
+// set after all parse stages, including env vars and config files, [Parse]
+// returns [ErrRequiredTogether], naming the missing flags. Names are resolved
+// like [FlagSet.GetFlag], so the group may include parent flags. At least two
+// names are required.
+func (fs *FlagSet) MarkRequiredTogether(names ...string) error {
+	group, err := fs.getFlagGroup(names)
+	if err != nil {
+		return err
+	}
+	fs.together = append(fs.together, group)
+	return nil
+}
+
+func (fs *FlagSet) getFlagGroup(names []string) ([]Flag, error) {
+	if len(names) < 2 {
+		return nil, fmt.Errorf("at least two flags are required")
+	}
+	group := make([]Flag, len(names))
+	for i, name := range names {
+		f, ok := fs.GetFlag(name)
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+		group[i] = f
+	}
+	return group, nil
+}
+
+// checkFlagGroups returns an error if any group of flags recorded by the flag
+// set, or any of its parents, isn't satisfied.
+func (fs *FlagSet) checkFlagGroups() error {
+	for cursor := fs; cursor != nil; cursor = cursor.parent {
+		for _, group := range cursor.exclusive {
+			var set []string
+			for _, f := range group {
+				if f.IsSet() {
+					set = append(set, getNameString(f))
+				}
+			}
+			if len(set) > 1 {
+				return fmt.Errorf("%w: %s", ErrMutuallyExclusive, strings.Join(set, ", "))
+			}
+		}
+
+		for _, group := range cursor.together {
+			var set, unset []string
+			for _, f := range group {
+				if f.IsSet() {
+					set = append(set, getNameString(f))
+				} else {
+					unset = append(unset, getNameString(f))
+				}
+			}
+			if len(set) > 0 && len(unset) > 0 {
+				return fmt.Errorf("%w: %s requires %s", ErrRequiredTogether, strings.Join(set, ", "), strings.Join(unset, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Set assigns the given value to the first flag known to the flag set that
+// matches the given name, as per [FlagSet.GetFlag], and marks the flag as set,
+// just like parsing does. It's similar to [flag.FlagSet.Set], and is intended
+// for e.g. tests, or loading values from sources other than those supported by
+// [Parse]. If no flag matches the name, Set returns [ErrUnknownFlag].
+func (fs *FlagSet) Set(name, value string) error {
+	f, ok := fs.GetFlag(name)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownFlag, name)
+	}
+
+	if err := f.SetValue(value); err != nil {
+		return newFlagError(f, err)
+	}
+
+	return nil
+}
+
+// GetArgs returns the args left over after a successful parse. If
+// multi-terminator is enabled, only the first segment is returned.
 func (fs *FlagSet) GetArgs() []string {
 	return fs.postParseArgs
 }
 
+// GetRawArgs returns a copy of the args exactly as they were provided to the
+// most recent call to parse, including any flags which were consumed. Unlike
+// GetArgs, the raw args are recorded even if the parse fails.
+func (fs *FlagSet) GetRawArgs() []string {
+	return append([]string{}, fs.rawArgs...)
+}
+
+// GetArgSegments returns the args left over after a successful parse, split
+// into segments on `--` terminators, if multi-terminator is enabled. Otherwise,
+// it returns a single segment, equivalent to GetArgs. See
+// [FlagSet.SetMultiTerminator] for details.
+func (fs *FlagSet) GetArgSegments() [][]string {
+	if !fs.multiTerm || fs.argSegments == nil {
+		return [][]string{fs.postParseArgs}
+	}
+	return fs.argSegments
+}
+
+// InheritValues copies the value of every flag in from which has been set, to
+// the flag in fs with the same name, and marks that flag as set. Flags are
+// matched by long name if they have one, and by short name otherwise. Flags in
+// from without a matching flag in fs are ignored. This allows values to be
+// carried over between separate parse phases, e.g. global flags followed by
+// subcommand flags, without using [FlagSet.SetParent].
+//
+// Values are copied directly if both flags have values of the same underlying
+// type, as reported by [GetAny] and a GetPointer method, which is the case for
+// the values in package ffval. Otherwise, values are copied via their string
+// representation.
+func (fs *FlagSet) InheritValues(from Flags) error {
+	return from.WalkFlags(func(src Flag) error {
+		if !src.IsSet() {
+			return nil
+		}
+
+		var dst *coreFlag
+		if long, ok := src.GetLongName(); ok {
+			dst = fs.findLongFlag(long)
+		} else if short, ok := src.GetShortName(); ok {
+			dst = fs.findShortFlag(short)
+		}
+		if dst == nil || Flag(dst) == src {
+			return nil
+		}
+
+		if err := dst.inheritValue(src); err != nil {
+			return newFlagError(dst, fmt.Errorf("inherit value: %w", err))
+		}
+		return nil
+	})
+}
+
 // Reset the flag set, and all of the flags defined in the flag set, to their
 // initial state. After a successful reset, the flag set may be parsed as if it
 // were newly constructed.
@@ -350,11 +872,172 @@ func (fs *FlagSet) Reset() error {
 	}
 
 	fs.postParseArgs = fs.postParseArgs[:0]
+	fs.argSegments = nil
+	fs.rawArgs = nil
+	if fs.unknownArgs != nil {
+		*fs.unknownArgs = (*fs.unknownArgs)[:0]
+	}
 	fs.isParsed = false
+	fs.isFrozen = false
 
 	return nil
 }
 
+// Validate checks every flag known to the flag set, including all parent
+// flags, whose value implements the following interface.
+//
+//	ValidateSelf() error
+//
+// Any errors returned by those values are collected and returned together. For
+// example, an [ffval.Enum] with a default value which isn't one of its valid
+// values is reported as an error. Validate is intended to catch programmer
+// errors in flag set definitions early, e.g. in tests or at startup.
+func (fs *FlagSet) Validate() error {
+	var errs []error
+	fs.WalkFlags(func(f Flag) error {
+		cf, ok := f.(*coreFlag)
+		if !ok {
+			return nil
+		}
+		if v, ok := cf.flagValue.(interface{ ValidateSelf() error }); ok {
+			if err := v.ValidateSelf(); err != nil {
+				errs = append(errs, newFlagError(f, err))
+			}
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// ResetAll is like [FlagSet.Reset], but also resets every parent flag set,
+// recursively. Reset only affects the flags defined in the receiver, so parent
+// flags set during a parse remain set after a reset. ResetAll reverts those
+// parent flags to their initial state as well.
+func (fs *FlagSet) ResetAll() error {
+	for cursor := fs; cursor != nil; cursor = cursor.parent {
+		if err := cursor.Reset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements [json.Marshaler], producing a JSON object of the
+// current value of every flag known to the flag set, including parent flags.
+// Each flag is keyed by its long name, or its short name if it has no long
+// name. If multiple flags have the same key, the first one wins.
+//
+// Values are typed where possible: bools as JSON booleans, integers and floats
+// as JSON numbers, and strings as JSON strings. Flags which can be set multiple
+// times to accumulate values, like [ffval.List], are serialized as JSON arrays.
+// Other repeatable flags are serialized with their final value. Types which
+// implement [fmt.Stringer], like [time.Duration], are serialized as strings, as
+// are any values which can't be typed.
+//
+// Values are typed via a Get method on the flag value, like [flag.Getter] or
+// [ffval.Value.Get]. If no such method exists, the string representation of the
+// value is used.
+func (fs *FlagSet) MarshalJSON() ([]byte, error) {
+	values := map[string]any{}
+	if err := fs.WalkFlags(func(f Flag) error {
+		var key string
+		if long, ok := f.GetLongName(); ok {
+			key = long
+		} else if short, ok := f.GetShortName(); ok {
+			key = string(short)
+		}
+		if _, ok := values[key]; ok {
+			return nil
+		}
+
+		values[key] = getJSONValue(f)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(values)
+}
+
+func getJSONValue(f Flag) any {
+	cf, ok := f.(*coreFlag)
+	if !ok {
+		return f.GetValue()
+	}
+
+	v, ok := callGet(cf.flagValue)
+	if !ok {
+		return f.GetValue()
+	}
+
+	return reifyJSONValue(v, f.GetValue())
+}
+
+// callGetPointer calls the GetPointer method of the flag value, if it has one
+// which takes no arguments and returns a single non-nil pointer.
+func callGetPointer(value flag.Value) (reflect.Value, bool) {
+	get := reflect.ValueOf(value).MethodByName("GetPointer")
+	if !get.IsValid() || get.Type().NumIn() != 0 || get.Type().NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	ptr := get.Call(nil)[0]
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return reflect.Value{}, false
+	}
+	return ptr, true
+}
+
+// callGet calls the Get method of the flag value, if it has one which takes no
+// arguments and returns a single value. That includes [flag.Getter], as well as
+// the typed Get methods of the values in package ffval.
+func callGet(value flag.Value) (reflect.Value, bool) {
+	get := reflect.ValueOf(value).MethodByName("Get")
+	if !get.IsValid() || get.Type().NumIn() != 0 || get.Type().NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	return get.Call(nil)[0], true
+}
+
+func reifyJSONValue(v reflect.Value, fallback string) any {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return fallback
+		}
+		v = v.Elem()
+	}
+
+	if v.CanInterface() {
+		if _, ok := v.Interface().(fmt.Stringer); ok {
+			return fallback
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		return fallback
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		values := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			values[i] = reifyJSONValue(elem, fmt.Sprint(elem.Interface()))
+		}
+		return values
+	default:
+		return fallback
+	}
+}
+
 // FlagConfig collects the required config for a flag in a flag set.
 type FlagConfig struct {
 	// ShortName is the short form name of the flag, which can be provided as a
@@ -410,6 +1093,53 @@ type FlagConfig struct {
 	// help text. Note this does not affect the actual default value of the
 	// flag.
 	NoDefault bool
+
+	// NoEnvVar prevents the flag from being set by environment variables when
+	// parsing with e.g. [WithEnvVars]. This can be useful for sensitive flags
+	// like secrets, which shouldn't be picked up from the ambient environment.
+	// Note this does not affect config files.
+	NoEnvVar bool
+
+	// EnvSplit causes env var values for the flag to be split on the given
+	// delimiter, with each value set on the flag in order, regardless of any
+	// global delimiter provided via [WithEnvVarSplit]. This can be useful for
+	// list flags, e.g. a PATH-style value split on ":". Delimiters escaped with
+	// a backslash aren't split, as with WithEnvVarSplit.
+	EnvSplit string
+
+	// EnvVarAbsentValue is applied to the flag when parsing with env vars, e.g.
+	// via [WithEnvVars], and none of the flag's env vars are present in the
+	// environment. It's applied after env vars and config files, and only if
+	// no source set the flag, so it has lower priority than every source other
+	// than a snapshot.
+	// This can be useful for feature-flag-style bools with a default of true,
+	// which should be false unless their env var is set. An empty string, the
+	// default, means the flag isn't affected by the absence of its env vars.
+	EnvVarAbsentValue string
+
+	// Required causes parse to fail with [ErrMissingRequired] if the flag hasn't
+	// been set by any source, i.e. commandline args, env vars, or config files.
+	// Help text marks required flags which haven't been set.
+	Required bool
+
+	// Deprecated marks the flag as deprecated, with a message which typically
+	// describes its replacement, e.g. "use --new". The flag still works, but
+	// when it's set by parse, a warning is written to the writer provided by
+	// [WithDeprecationWriter]. Help text marks deprecated flags.
+	Deprecated string
+
+	// DeprecatedValues maps deprecated values of the flag to messages which
+	// typically describe their replacements, e.g. "legacy" to "use modern".
+	// Deprecated values are still accepted, but when the flag is set to one of
+	// them by parse, a warning is written to the writer provided by
+	// [WithDeprecationWriter]. Values are compared exactly, after any transform
+	// provided via [WithValueTransform].
+	DeprecatedValues map[string]string
+
+	// Hidden omits the flag from help text, but doesn't otherwise affect how
+	// the flag is parsed. This can be useful for internal or debug flags, or
+	// for deprecated flags which shouldn't be advertised to new users.
+	Hidden bool
 }
 
 func (cfg FlagConfig) isBoolFlag() bool {
@@ -419,7 +1149,7 @@ func (cfg FlagConfig) isBoolFlag() bool {
 	return false
 }
 
-func (cfg FlagConfig) getPlaceholder() string {
+func (cfg FlagConfig) getPlaceholder(placeholderFn func(FlagConfig) string) string {
 	// If a placeholder is explicitly refused, use an empty string.
 	if cfg.NoPlaceholder {
 		return ""
@@ -449,6 +1179,14 @@ func (cfg FlagConfig) getPlaceholder() string {
 		}
 	}
 
+	// If the flag set has a placeholder func which returns a non-empty string,
+	// use that. This also has lower priority than the bool flag check, above.
+	if placeholderFn != nil {
+		if p := placeholderFn(cfg); p != "" {
+			return p
+		}
+	}
+
 	// If the flag value provides its own non-empty Placeholder, use that.
 	// This has lower priority than the bool flag check, above.
 	if ph, ok := cfg.Value.(interface{ GetPlaceholder() string }); ok {
@@ -533,16 +1271,23 @@ func (fs *FlagSet) AddFlag(cfg FlagConfig) (Flag, error) {
 	}
 
 	f := &coreFlag{
-		flagSet:     fs,
-		shortName:   cfg.ShortName,
-		longName:    cfg.LongName,
-		usage:       cfg.Usage,
-		flagValue:   cfg.Value,
-		trueDefault: trueDefault,
-		isBoolFlag:  isBoolFlag,
-		isSet:       false,
-		placeholder: cfg.getPlaceholder(),
-		helpDefault: cfg.getHelpDefault(),
+		flagSet:          fs,
+		shortName:        cfg.ShortName,
+		longName:         cfg.LongName,
+		usage:            cfg.Usage,
+		flagValue:        cfg.Value,
+		trueDefault:      trueDefault,
+		isBoolFlag:       isBoolFlag,
+		isSet:            false,
+		placeholder:      cfg.getPlaceholder(fs.placeholderFn),
+		helpDefault:      cfg.getHelpDefault(),
+		noEnvVar:         cfg.NoEnvVar,
+		envSplit:         cfg.EnvSplit,
+		envAbsent:        cfg.EnvVarAbsentValue,
+		isRequired:       cfg.Required,
+		deprecated:       cfg.Deprecated,
+		deprecatedValues: cfg.DeprecatedValues,
+		isHidden:         cfg.Hidden,
 	}
 
 	for _, existing := range fs.flags {
@@ -556,10 +1301,70 @@ func (fs *FlagSet) AddFlag(cfg FlagConfig) (Flag, error) {
 	return f, nil
 }
 
+// FlagOption sets an optional field of a [FlagConfig], for use with
+// [FlagSet.Flag].
+type FlagOption func(*FlagConfig)
+
+// FlagShortName sets [FlagConfig.ShortName].
+func FlagShortName(short rune) FlagOption {
+	return func(cfg *FlagConfig) { cfg.ShortName = short }
+}
+
+// FlagLongName sets [FlagConfig.LongName].
+func FlagLongName(long string) FlagOption {
+	return func(cfg *FlagConfig) { cfg.LongName = long }
+}
+
+// FlagUsage sets [FlagConfig.Usage].
+func FlagUsage(usage string) FlagOption {
+	return func(cfg *FlagConfig) { cfg.Usage = usage }
+}
+
+// FlagPlaceholder sets [FlagConfig.Placeholder].
+func FlagPlaceholder(placeholder string) FlagOption {
+	return func(cfg *FlagConfig) { cfg.Placeholder = placeholder }
+}
+
+// FlagNoPlaceholder sets [FlagConfig.NoPlaceholder].
+func FlagNoPlaceholder() FlagOption {
+	return func(cfg *FlagConfig) { cfg.NoPlaceholder = true }
+}
+
+// FlagNoDefault sets [FlagConfig.NoDefault].
+func FlagNoDefault() FlagOption {
+	return func(cfg *FlagConfig) { cfg.NoDefault = true }
+}
+
+// FlagNoEnvVar sets [FlagConfig.NoEnvVar].
+func FlagNoEnvVar() FlagOption {
+	return func(cfg *FlagConfig) { cfg.NoEnvVar = true }
+}
+
+// Flag is like [FlagSet.AddFlag], but takes the value directly, and every other
+// field of the flag config via options, applied in order. For example,
+//
+//	fs.Flag(ffval.NewValue(&port), ff.FlagLongName("port"), ff.FlagUsage("listen port"))
+//
+// is equivalent to
+//
+//	fs.AddFlag(ff.FlagConfig{Value: ffval.NewValue(&port), LongName: "port", Usage: "listen port"})
+func (fs *FlagSet) Flag(value flag.Value, opts ...FlagOption) (Flag, error) {
+	cfg := FlagConfig{Value: value}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return fs.AddFlag(cfg)
+}
+
+// timeType identifies time.Time struct fields in AddStruct.
+var timeType = reflect.TypeOf(time.Time{})
+
 // AddStruct adds flags to the flag set from the given val, which must be a
 // pointer to a struct. Each exported field in that struct with a valid `ff:`
 // struct tag corresponds to a unique flag in the flag set. Those fields must be
-// a supported [ffval.ValueType] or implement [flag.Value].
+// a supported [ffval.ValueType], a slice of a supported ValueType, or implement
+// [flag.Value]. Slice fields become [ffval.List] flags, where each value is
+// appended to the slice; a default, if provided, is a single element.
 //
 // The `ff:` struct tag is a sequence of comma- or pipe-delimited items. An item
 // is either empty (and ignored), a key, or a key/value pair. Key/value pairs
@@ -577,9 +1382,35 @@ func (fs *FlagSet) AddFlag(cfg FlagConfig) (Flag, error) {
 //   - p, placeholder -- value must be a non-empty string
 //   - noplaceholder -- no value
 //   - nodefault -- no value
+//   - noenv -- no value
+//   - envsplit -- value must be a non-empty string
+//   - required -- no value
+//   - deprecated -- value must be a non-empty string
+//   - hidden -- no value
+//   - layout -- value must be a non-empty [time.Parse] layout, and is only
+//     valid for time.Time fields, which otherwise use [time.RFC3339]
 //
 // See the example for more detail.
 func (fs *FlagSet) AddStruct(val any) error {
+	return fs.addStruct("", val)
+}
+
+// AddStructPrefixed is like [FlagSet.AddStruct], but prefixes the long name of
+// every flag with the given prefix and a '.' separator, e.g. a field with long
+// name alpha and a prefix of comp becomes the flag --comp.alpha. Short names
+// are omitted, so that the flags of multiple structs, each with a different
+// prefix, don't collide. Consequently, every field must have a long name.
+//
+// This is useful for composing config from multiple components, where each
+// component's flags are namespaced by the prefix.
+func (fs *FlagSet) AddStructPrefixed(prefix string, val any) error {
+	if prefix == "" {
+		return fmt.Errorf("prefix is required")
+	}
+	return fs.addStruct(prefix, val)
+}
+
+func (fs *FlagSet) addStruct(prefix string, val any) error {
 	outerVal := reflect.ValueOf(val)
 	if outerVal.Kind() != reflect.Pointer {
 		return fmt.Errorf("value (%T) must be a pointer", val)
@@ -609,24 +1440,16 @@ func (fs *FlagSet) AddStruct(val any) error {
 		}
 
 		// Only care if the `ff:` tag has one or more comma-separated items.
-		var items []string
-		{
-			var quoted bool
-			items = strings.FieldsFunc(fftag, func(r rune) bool {
-				if r == '\'' {
-					quoted = !quoted
-				}
-				return !quoted && (r == ',' || r == '|')
-			})
-			if len(items) <= 0 {
-				continue
-			}
+		items := splitStructTag(fftag)
+		if len(items) <= 0 {
+			continue
 		}
 
 		// Parse the items into a flag config.
 		var (
-			cfg FlagConfig
-			def string
+			cfg    FlagConfig
+			def    string
+			layout string
 		)
 		for _, item := range items {
 			// Allow spaces for padding.
@@ -635,26 +1458,9 @@ func (fs *FlagSet) AddStruct(val any) error {
 				continue
 			}
 
-			var key, val string
-			if sep := strings.IndexAny(item, "=:"); sep < 0 {
-				key = item
-			} else {
-				key, val = item[:sep], item[sep+1:]
-			}
-			{
-				key = strings.ToLower(key)
-				key = strings.TrimSpace(key)
-				if key == "" {
-					return fmt.Errorf("%s: %q: no key", fieldName, item)
-				}
-			}
-			{
-				val = strings.TrimSpace(val)
-				if strings.HasPrefix(val, `'`) && strings.HasSuffix(val, `'`) { // treat 'single-quoted values' same as "double-quoted values"
-					val = val[1 : len(val)-1]
-				} else if v, err := strconv.Unquote(val); err == nil {
-					val = v
-				}
+			key, val := parseStructTagItem(item)
+			if key == "" {
+				return fmt.Errorf("%s: %q: no key", fieldName, item)
 			}
 
 			// Parse supported keys.
@@ -706,6 +1512,45 @@ func (fs *FlagSet) AddStruct(val any) error {
 				}
 				cfg.NoPlaceholder = true
 
+			case "noenv":
+				if val != "" {
+					return fmt.Errorf("%s: %s: noenv should not have a value", fieldName, item)
+				}
+				cfg.NoEnvVar = true
+
+			case "envsplit":
+				if val == "" {
+					return fmt.Errorf("%s: %s: invalid (empty) envsplit delimiter", fieldName, item)
+				}
+				cfg.EnvSplit = val
+
+			case "required":
+				if val != "" {
+					return fmt.Errorf("%s: %s: required should not have a value", fieldName, item)
+				}
+				cfg.Required = true
+
+			case "deprecated":
+				if val == "" {
+					return fmt.Errorf("%s: %s: invalid (empty) deprecated message", fieldName, item)
+				}
+				cfg.Deprecated = val
+
+			case "hidden":
+				if val != "" {
+					return fmt.Errorf("%s: %s: hidden should not have a value", fieldName, item)
+				}
+				cfg.Hidden = true
+
+			case "layout":
+				if val == "" {
+					return fmt.Errorf("%s: %s: invalid (empty) layout", fieldName, item)
+				}
+				if fieldTyp.Type != timeType {
+					return fmt.Errorf("%s: %s: layout is only valid for time.Time fields", fieldName, item)
+				}
+				layout = val
+
 			default:
 				return fmt.Errorf("%s: %s: unknown key", fieldName, key)
 			}
@@ -722,6 +1567,24 @@ func (fs *FlagSet) AddStruct(val any) error {
 			if fieldValAddrTyp.Implements(flagValueElemTyp) {
 				// The field implements flag.Value, we can use it directly.
 				cfg.Value = fieldValAddrIface.(flag.Value)
+			} else if fieldTyp.Type == timeType {
+				// The field is a time, which should use the layout, if given.
+				v := ffval.NewTime(fieldValAddrIface.(*time.Time), layout)
+				if def != "" {
+					if err := v.Set(def); err != nil {
+						return fmt.Errorf("%s: default: %w", fieldName, err)
+					}
+					v.Default = v.Get()
+					v.Reset()
+				}
+				cfg.Value = v
+			} else if fieldVal.Kind() == reflect.Slice {
+				// The field is a slice, which should be a list.
+				v, err := ffval.NewListReflect(fieldValAddrIface, def)
+				if err != nil {
+					return fmt.Errorf("%s: %w", fieldName, err)
+				}
+				cfg.Value = v
 			} else {
 				// Try to construct a new flag value.
 				v, err := ffval.NewValueReflect(fieldValAddrIface, def)
@@ -732,20 +1595,165 @@ func (fs *FlagSet) AddStruct(val any) error {
 			}
 		}
 
-		// Save the config to add later, after the struct is fully parsed.
-		flagConfigs = append(flagConfigs, cfg)
-	}
+		// Apply the prefix, if any, which only applies to long names.
+		if prefix != "" {
+			if cfg.LongName == "" {
+				return fmt.Errorf("%s: prefixed flags require a long name", fieldName)
+			}
+			cfg.ShortName = 0
+			cfg.LongName = prefix + "." + cfg.LongName
+		}
+
+		// Save the config to add later, after the struct is fully parsed.
+		flagConfigs = append(flagConfigs, cfg)
+	}
+
+	// Add the collected flags.
+	for _, cfg := range flagConfigs {
+		if _, err := fs.AddFlag(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cloneReflectValue returns a copy of v, which doesn't share the backing array
+// of a slice, or the underlying map of a map.
+func cloneReflectValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		return reflect.AppendSlice(reflect.MakeSlice(v.Type(), 0, v.Len()), v)
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// reflectValueString returns the string form of v, preferring a String method.
+func reflectValueString(v reflect.Value) string {
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// ApplyDefaults sets the defaults of flags in the flag set from the given val,
+// which must be a pointer to a struct. This allows defaults to be computed in
+// code, rather than expressed as strings in struct tags.
+//
+// Each exported field in that struct with a valid `ff:` struct tag, as per
+// [FlagSet.AddStruct], corresponds to the flag defined in the flag set with the
+// same long name, or, if the tag has no long name, the same short name. Parent
+// flags aren't considered. The value of the field becomes the default value of
+// the flag, which is used in help text, and restored by [FlagSet.Reset]. If the
+// flag hasn't been set, the value also becomes the current value of the flag.
+//
+// It's an error if a field doesn't correspond to a flag, or if the value of a
+// field can't be assigned to the flag.
+func (fs *FlagSet) ApplyDefaults(val any) error {
+	outerVal := reflect.ValueOf(val)
+	if outerVal.Kind() != reflect.Pointer {
+		return fmt.Errorf("value (%T) must be a pointer", val)
+	}
+
+	innerVal := outerVal.Elem()
+	innerTyp := innerVal.Type()
+	if innerVal.Kind() != reflect.Struct {
+		return fmt.Errorf("value (%T) must be a struct", innerTyp)
+	}
+
+	for i := 0; i < innerVal.NumField(); i++ {
+		var (
+			fieldVal  = innerVal.Field(i)
+			fieldTyp  = innerTyp.Field(i)
+			fieldName = fieldTyp.Name
+		)
+
+		fftag, ok := fieldTyp.Tag.Lookup("ff")
+		if !ok {
+			continue
+		}
+
+		var (
+			short rune
+			long  string
+		)
+		for _, item := range splitStructTag(fftag) {
+			switch key, val := parseStructTagItem(strings.TrimSpace(item)); key {
+			case "s", "short", "shortname":
+				short, _ = utf8.DecodeRuneInString(val)
+			case "l", "long", "longname":
+				long = val
+			}
+		}
+
+		var f *coreFlag
+		for _, candidate := range fs.flags {
+			if isValidLongName(long) && candidate.longName == long || !isValidLongName(long) && isValidShortName(short) && candidate.shortName == short {
+				f = candidate
+				break
+			}
+		}
+		if f == nil {
+			return fmt.Errorf("%s: %w", fieldName, ErrUnknownFlag)
+		}
 
-	// Add the collected flags.
-	for _, cfg := range flagConfigs {
-		if _, err := fs.AddFlag(cfg); err != nil {
-			return err
+		if err := f.applyDefault(fieldVal); err != nil {
+			return newFlagError(f, fmt.Errorf("%s: apply default: %w", fieldName, err))
 		}
 	}
 
 	return nil
 }
 
+// splitStructTag splits an `ff:` struct tag into its comma- or pipe-delimited
+// items. Delimiters within 'single quotes' are ignored.
+func splitStructTag(fftag string) []string {
+	var quoted bool
+	return strings.FieldsFunc(fftag, func(r rune) bool {
+		if r == '\'' {
+			quoted = !quoted
+		}
+		return !quoted && (r == ',' || r == '|')
+	})
+}
+
+// parseStructTagItem parses a single `ff:` struct tag item into a lowercase key
+// and an unquoted value. Both are trimmed of whitespace.
+func parseStructTagItem(item string) (key, val string) {
+	if sep := strings.IndexAny(item, "=:"); sep < 0 {
+		key = item
+	} else {
+		key, val = item[:sep], item[sep+1:]
+	}
+
+	key = strings.ToLower(key)
+	key = strings.TrimSpace(key)
+
+	val = strings.TrimSpace(val)
+	if strings.HasPrefix(val, `'`) && strings.HasSuffix(val, `'`) { // treat 'single-quoted values' same as "double-quoted values"
+		val = val[1 : len(val)-1]
+	} else if v, err := strconv.Unquote(val); err == nil {
+		val = v
+	}
+
+	return key, val
+}
+
 // Value defines a new flag in the flag set, and panics on any error.
 func (fs *FlagSet) Value(short rune, long string, value flag.Value, usage string) Flag {
 	f, err := fs.AddFlag(FlagConfig{
@@ -923,6 +1931,118 @@ func (fs *FlagSet) StringSetLong(long string, usage string) *[]string {
 	return fs.StringSet(0, long, usage)
 }
 
+// StringOrderedMapVar defines a new flag in the flag set, and panics on any
+// error.
+//
+// The flag represents an insertion-ordered set of key/value pairs, where each
+// call to Set parses a key=value string. Setting an existing key updates its
+// value in place, without changing its position.
+func (fs *FlagSet) StringOrderedMapVar(pointer *[]ffval.Pair[string], short rune, long string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewOrderedMap(pointer), usage)
+}
+
+// StringOrderedMap defines a new flag in the flag set, and panics on any error.
+// See [FlagSet.StringOrderedMapVar] for more details.
+func (fs *FlagSet) StringOrderedMap(short rune, long string, usage string) *[]ffval.Pair[string] {
+	var value []ffval.Pair[string]
+	fs.StringOrderedMapVar(&value, short, long, usage)
+	return &value
+}
+
+// StringOrderedMapShort defines a new flag in the flag set, and panics on any
+// error. See [FlagSet.StringOrderedMapVar] for more details.
+func (fs *FlagSet) StringOrderedMapShort(short rune, usage string) *[]ffval.Pair[string] {
+	return fs.StringOrderedMap(short, "", usage)
+}
+
+// StringOrderedMapLong defines a new flag in the flag set, and panics on any
+// error. See [FlagSet.StringOrderedMapVar] for more details.
+func (fs *FlagSet) StringOrderedMapLong(long string, usage string) *[]ffval.Pair[string] {
+	return fs.StringOrderedMap(0, long, usage)
+}
+
+// StringMapVar defines a new flag in the flag set, and panics on any error.
+//
+// The flag represents a map of string keys to string values, where each call
+// to Set parses a key=value string, split on the first =. Setting an existing
+// key replaces its value.
+func (fs *FlagSet) StringMapVar(pointer *map[string]string, short rune, long string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewMap(pointer), usage)
+}
+
+// StringMap defines a new flag in the flag set, and panics on any error.
+// See [FlagSet.StringMapVar] for more details.
+func (fs *FlagSet) StringMap(short rune, long string, usage string) *map[string]string {
+	var value map[string]string
+	fs.StringMapVar(&value, short, long, usage)
+	return &value
+}
+
+// StringMapShort defines a new flag in the flag set, and panics on any error.
+// See [FlagSet.StringMapVar] for more details.
+func (fs *FlagSet) StringMapShort(short rune, usage string) *map[string]string {
+	return fs.StringMap(short, "", usage)
+}
+
+// StringMapLong defines a new flag in the flag set, and panics on any error.
+// See [FlagSet.StringMapVar] for more details.
+func (fs *FlagSet) StringMapLong(long string, usage string) *map[string]string {
+	return fs.StringMap(0, long, usage)
+}
+
+// StringMapSplitVar defines a new flag in the flag set, and panics on any
+// error.
+//
+// The flag is like [FlagSet.StringMapVar], except that each call to Set may
+// provide multiple comma-separated key=value pairs, e.g. a=1,b=2. Commas within
+// keys or values can be escaped with a backslash, e.g. a=x\,y. See
+// [ffval.SplitCommas] for more details.
+func (fs *FlagSet) StringMapSplitVar(pointer *map[string]string, short rune, long string, usage string) Flag {
+	value := ffval.NewMap(pointer)
+	value.SplitFunc = ffval.SplitCommas
+	return fs.Value(short, long, value, usage)
+}
+
+// StringMapSplit defines a new flag in the flag set, and panics on any error.
+// See [FlagSet.StringMapSplitVar] for more details.
+func (fs *FlagSet) StringMapSplit(short rune, long string, usage string) *map[string]string {
+	var value map[string]string
+	fs.StringMapSplitVar(&value, short, long, usage)
+	return &value
+}
+
+// StringMapSplitShort defines a new flag in the flag set, and panics on any
+// error. See [FlagSet.StringMapSplitVar] for more details.
+func (fs *FlagSet) StringMapSplitShort(short rune, usage string) *map[string]string {
+	return fs.StringMapSplit(short, "", usage)
+}
+
+// StringMapSplitLong defines a new flag in the flag set, and panics on any
+// error. See [FlagSet.StringMapSplitVar] for more details.
+func (fs *FlagSet) StringMapSplitLong(long string, usage string) *map[string]string {
+	return fs.StringMapSplit(0, long, usage)
+}
+
+// PrefixMap defines a new short flag in the flag set, and panics on any error.
+//
+// The flag represents a map of string keys to string values, like
+// [FlagSet.StringMapVar], and is meant for compiler-style flags where the key
+// is attached directly to the flag name, e.g. -Dfoo=bar or -D foo=bar. Each
+// occurrence adds one key=value pair to the map.
+//
+// Like any other non-bool short flag, a prefix flag consumes the remainder of
+// a cluster of short flags as its value, so -vDfoo=bar sets the bool flag -v
+// and then adds foo=bar via -D. Prefix flags also accept -Dfoo=bar when short
+// flag clustering is disabled, in which case the = is not treated as a
+// separator between flag name and value.
+func (fs *FlagSet) PrefixMap(short rune, pointer *map[string]string, usage string) Flag {
+	f := fs.Value(short, "", ffval.NewMap(pointer), usage)
+	if cf, ok := f.(*coreFlag); ok {
+		cf.isPrefix = true
+	}
+	return f
+}
+
 // StringEnumVar defines a new enum in the flag set, and panics on any error.
 // The default is the first valid value. At least one valid value is required.
 func (fs *FlagSet) StringEnumVar(pointer *string, short rune, long string, usage string, valid ...string) Flag {
@@ -949,6 +2069,308 @@ func (fs *FlagSet) StringEnumLong(long string, usage string, valid ...string) *s
 	return fs.StringEnum(0, long, usage, valid...)
 }
 
+// StringEnumFoldVar defines a new enum in the flag set, and panics on any
+// error. Unlike [FlagSet.StringEnumVar], input is matched to valid values
+// without regard to case, and the matching valid value is stored. For example,
+// given the valid value `debug`, the input `DEBUG` sets the value `debug`. The
+// default is the first valid value. At least one valid value is required.
+func (fs *FlagSet) StringEnumFoldVar(pointer *string, short rune, long string, usage string, valid ...string) Flag {
+	value := ffval.NewEnum(pointer, valid...)
+	value.CaseInsensitive = true
+	return fs.Value(short, long, value, usage)
+}
+
+// StringEnumFold defines a new case-insensitive enum in the flag set, and
+// panics on any error. See [FlagSet.StringEnumFoldVar] for more details.
+func (fs *FlagSet) StringEnumFold(short rune, long string, usage string, valid ...string) *string {
+	var value string
+	fs.StringEnumFoldVar(&value, short, long, usage, valid...)
+	return &value
+}
+
+// StringEnumFoldShort defines a new case-insensitive enum in the flag set, and
+// panics on any error. See [FlagSet.StringEnumFoldVar] for more details.
+func (fs *FlagSet) StringEnumFoldShort(short rune, usage string, valid ...string) *string {
+	return fs.StringEnumFold(short, "", usage, valid...)
+}
+
+// StringEnumFoldLong defines a new case-insensitive enum in the flag set, and
+// panics on any error. See [FlagSet.StringEnumFoldVar] for more details.
+func (fs *FlagSet) StringEnumFoldLong(long string, usage string, valid ...string) *string {
+	return fs.StringEnumFold(0, long, usage, valid...)
+}
+
+// OptionalBoolVar defines a new optional bool flag in the flag set, and panics
+// on any error. The pointer is nil until the flag is set, which allows callers
+// to distinguish a flag that's explicitly false from one that wasn't provided.
+// Unlike other bool flags, an explicit value is always required, e.g.
+// --foo=false.
+func (fs *FlagSet) OptionalBoolVar(pointer **bool, short rune, long string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewOptionalBool(pointer), usage)
+}
+
+// OptionalBool defines a new optional bool flag in the flag set, and panics on
+// any error. See [FlagSet.OptionalBoolVar] for more details.
+func (fs *FlagSet) OptionalBool(short rune, long string, usage string) **bool {
+	var value *bool
+	fs.OptionalBoolVar(&value, short, long, usage)
+	return &value
+}
+
+// OptionalBoolShort defines a new optional bool flag in the flag set, and
+// panics on any error. See [FlagSet.OptionalBoolVar] for more details.
+func (fs *FlagSet) OptionalBoolShort(short rune, usage string) **bool {
+	return fs.OptionalBool(short, "", usage)
+}
+
+// OptionalBoolLong defines a new optional bool flag in the flag set, and panics
+// on any error. See [FlagSet.OptionalBoolVar] for more details.
+func (fs *FlagSet) OptionalBoolLong(long string, usage string) **bool {
+	return fs.OptionalBool(0, long, usage)
+}
+
+// CounterVar defines a new counter flag in the flag set, and panics on any
+// error. The flag doesn't require a value, and every occurrence increments the
+// counter by one, regardless of any value, so e.g. -vvv produces 3, and so does
+// -v --verbose=5 -v. An integer value from any other source, e.g. an env var
+// VERBOSE=1, sets the counter directly. See [ffval.Count] for more details.
+func (fs *FlagSet) CounterVar(pointer *int, short rune, long string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewCount(pointer), usage)
+}
+
+// Counter defines a new counter flag in the flag set, and panics on any error.
+// See [FlagSet.CounterVar] for more details.
+func (fs *FlagSet) Counter(short rune, long string, usage string) *int {
+	var value int
+	fs.CounterVar(&value, short, long, usage)
+	return &value
+}
+
+// CounterShort defines a new counter flag in the flag set, and panics on any
+// error. See [FlagSet.CounterVar] for more details.
+func (fs *FlagSet) CounterShort(short rune, usage string) *int {
+	return fs.Counter(short, "", usage)
+}
+
+// CounterLong defines a new counter flag in the flag set, and panics on any
+// error. See [FlagSet.CounterVar] for more details.
+func (fs *FlagSet) CounterLong(long string, usage string) *int {
+	return fs.Counter(0, long, usage)
+}
+
+// IntRangeListVar defines a new int range list flag in the flag set, and
+// panics on any error. Each value is either a single int, or an inclusive range
+// of ints like 8000-8010, which is expanded and appended to the list. See
+// [ffval.IntRangeList] for more details.
+func (fs *FlagSet) IntRangeListVar(pointer *[]int, short rune, long string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewIntRangeList(pointer), usage)
+}
+
+// IntRangeList defines a new int range list flag in the flag set, and panics on
+// any error. See [FlagSet.IntRangeListVar] for more details.
+func (fs *FlagSet) IntRangeList(short rune, long string, usage string) *[]int {
+	var value []int
+	fs.IntRangeListVar(&value, short, long, usage)
+	return &value
+}
+
+// IntRangeListShort defines a new int range list flag in the flag set, and
+// panics on any error. See [FlagSet.IntRangeListVar] for more details.
+func (fs *FlagSet) IntRangeListShort(short rune, usage string) *[]int {
+	return fs.IntRangeList(short, "", usage)
+}
+
+// IntRangeListLong defines a new int range list flag in the flag set, and
+// panics on any error. See [FlagSet.IntRangeListVar] for more details.
+func (fs *FlagSet) IntRangeListLong(long string, usage string) *[]int {
+	return fs.IntRangeList(0, long, usage)
+}
+
+// IntFixedListVar defines a new fixed list flag in the flag set, and panics on
+// any error. The flag must be provided exactly size times, or not at all, e.g.
+// --color 255 --color 0 --color 0 for a size of 3. See [ffval.FixedList] for
+// more details.
+func (fs *FlagSet) IntFixedListVar(pointer *[]int, short rune, long string, size int, usage string) Flag {
+	value := ffval.NewFixedList(pointer, size)
+	if err := value.ValidateSelf(); err != nil {
+		panic(err)
+	}
+	return fs.Value(short, long, value, usage)
+}
+
+// IntFixedList defines a new fixed list flag in the flag set, and panics on any
+// error. See [FlagSet.IntFixedListVar] for more details.
+func (fs *FlagSet) IntFixedList(short rune, long string, size int, usage string) *[]int {
+	var value []int
+	fs.IntFixedListVar(&value, short, long, size, usage)
+	return &value
+}
+
+// IntFixedListShort defines a new fixed list flag in the flag set, and panics
+// on any error. See [FlagSet.IntFixedListVar] for more details.
+func (fs *FlagSet) IntFixedListShort(short rune, size int, usage string) *[]int {
+	return fs.IntFixedList(short, "", size, usage)
+}
+
+// IntFixedListLong defines a new fixed list flag in the flag set, and panics on
+// any error. See [FlagSet.IntFixedListVar] for more details.
+func (fs *FlagSet) IntFixedListLong(long string, size int, usage string) *[]int {
+	return fs.IntFixedList(0, long, size, usage)
+}
+
+// ByteSizeVar defines a new byte size flag in the flag set, and panics on any
+// error. Values are human-readable sizes like 10MB or 512KiB, which are stored
+// as a number of bytes. See [ffval.ByteSize] for more details.
+func (fs *FlagSet) ByteSizeVar(pointer *int64, short rune, long string, def int64, usage string) Flag {
+	return fs.Value(short, long, ffval.NewByteSize(pointer, def), usage)
+}
+
+// ByteSize defines a new byte size flag in the flag set, and panics on any
+// error. See [FlagSet.ByteSizeVar] for more details.
+func (fs *FlagSet) ByteSize(short rune, long string, def int64, usage string) *int64 {
+	var value int64
+	fs.ByteSizeVar(&value, short, long, def, usage)
+	return &value
+}
+
+// ByteSizeShort defines a new byte size flag in the flag set, and panics on any
+// error. See [FlagSet.ByteSizeVar] for more details.
+func (fs *FlagSet) ByteSizeShort(short rune, def int64, usage string) *int64 {
+	return fs.ByteSize(short, "", def, usage)
+}
+
+// ByteSizeLong defines a new byte size flag in the flag set, and panics on any
+// error. See [FlagSet.ByteSizeVar] for more details.
+func (fs *FlagSet) ByteSizeLong(long string, def int64, usage string) *int64 {
+	return fs.ByteSize(0, long, def, usage)
+}
+
+// DurationOrSentinelVar defines a new duration flag in the flag set, which also
+// accepts the given sentinel strings, and panics on any error. For example, a
+// sentinel of "forever" could map to [ffval.MaxDuration]. See
+// [ffval.DurationOrSentinel] for more details.
+func (fs *FlagSet) DurationOrSentinelVar(pointer *time.Duration, short rune, long string, def time.Duration, sentinels map[string]time.Duration, usage string) Flag {
+	return fs.Value(short, long, ffval.NewDurationOrSentinel(pointer, def, sentinels), usage)
+}
+
+// DurationOrSentinel defines a new duration flag in the flag set, which also
+// accepts the given sentinel strings, and panics on any error. See
+// [FlagSet.DurationOrSentinelVar] for more details.
+func (fs *FlagSet) DurationOrSentinel(short rune, long string, def time.Duration, sentinels map[string]time.Duration, usage string) *time.Duration {
+	var value time.Duration
+	fs.DurationOrSentinelVar(&value, short, long, def, sentinels, usage)
+	return &value
+}
+
+// DurationOrSentinelShort defines a new duration flag in the flag set, which
+// also accepts the given sentinel strings, and panics on any error. See
+// [FlagSet.DurationOrSentinelVar] for more details.
+func (fs *FlagSet) DurationOrSentinelShort(short rune, def time.Duration, sentinels map[string]time.Duration, usage string) *time.Duration {
+	return fs.DurationOrSentinel(short, "", def, sentinels, usage)
+}
+
+// DurationOrSentinelLong defines a new duration flag in the flag set, which
+// also accepts the given sentinel strings, and panics on any error. See
+// [FlagSet.DurationOrSentinelVar] for more details.
+func (fs *FlagSet) DurationOrSentinelLong(long string, def time.Duration, sentinels map[string]time.Duration, usage string) *time.Duration {
+	return fs.DurationOrSentinel(0, long, def, sentinels, usage)
+}
+
+// FileVar defines a new file flag in the flag set, and panics on any error.
+// Values are resolved to absolute paths, and must name a file which exists and
+// can be opened for reading, so that bad paths fail at parse time. The default
+// path def is used as-is. See [ffval.File] for more details.
+func (fs *FlagSet) FileVar(pointer *string, short rune, long string, def string, usage string) Flag {
+	return fs.Value(short, long, ffval.NewFile(pointer, def, true), usage)
+}
+
+// File defines a new file flag in the flag set, and panics on any error. See
+// [FlagSet.FileVar] for more details.
+func (fs *FlagSet) File(short rune, long string, def string, usage string) *string {
+	var value string
+	fs.FileVar(&value, short, long, def, usage)
+	return &value
+}
+
+// FileShort defines a new file flag in the flag set, and panics on any error.
+// See [FlagSet.FileVar] for more details.
+func (fs *FlagSet) FileShort(short rune, def string, usage string) *string {
+	return fs.File(short, "", def, usage)
+}
+
+// FileLong defines a new file flag in the flag set, and panics on any error.
+// See [FlagSet.FileVar] for more details.
+func (fs *FlagSet) FileLong(long string, def string, usage string) *string {
+	return fs.File(0, long, def, usage)
+}
+
+// RegexpVar defines a new regexp flag in the flag set, and panics on any error.
+// Values are compiled via [regexp.Compile]. A non-empty def is compiled via
+// [regexp.MustCompile] and used as the default; otherwise the default is nil.
+func (fs *FlagSet) RegexpVar(pointer **regexp.Regexp, short rune, long string, def string, usage string) Flag {
+	var defRegexp *regexp.Regexp
+	if def != "" {
+		defRegexp = regexp.MustCompile(def)
+	}
+	return fs.Value(short, long, ffval.NewRegexp(pointer, defRegexp), usage)
+}
+
+// Regexp defines a new regexp flag in the flag set, and panics on any error.
+// See [FlagSet.RegexpVar] for more details.
+func (fs *FlagSet) Regexp(short rune, long string, def string, usage string) **regexp.Regexp {
+	var value *regexp.Regexp
+	fs.RegexpVar(&value, short, long, def, usage)
+	return &value
+}
+
+// RegexpShort defines a new regexp flag in the flag set, and panics on any
+// error. See [FlagSet.RegexpVar] for more details.
+func (fs *FlagSet) RegexpShort(short rune, def string, usage string) **regexp.Regexp {
+	return fs.Regexp(short, "", def, usage)
+}
+
+// RegexpLong defines a new regexp flag in the flag set, and panics on any
+// error. See [FlagSet.RegexpVar] for more details.
+func (fs *FlagSet) RegexpLong(long string, def string, usage string) **regexp.Regexp {
+	return fs.Regexp(0, long, def, usage)
+}
+
+// URLVar defines a new URL flag in the flag set, and panics on any error.
+// Values are parsed via [url.Parse]. A non-empty def is parsed the same way and
+// used as the default; otherwise the default is the empty URL. To require
+// absolute URLs, use an [ffval.URL] with RequireAbsolute set.
+func (fs *FlagSet) URLVar(pointer *url.URL, short rune, long string, def string, usage string) Flag {
+	var defURL url.URL
+	if def != "" {
+		u, err := url.Parse(def)
+		if err != nil {
+			panic(fmt.Errorf("default %q: %w", def, err))
+		}
+		defURL = *u
+	}
+	return fs.Value(short, long, ffval.NewURL(pointer, defURL), usage)
+}
+
+// URL defines a new URL flag in the flag set, and panics on any error. See
+// [FlagSet.URLVar] for more details.
+func (fs *FlagSet) URL(short rune, long string, def string, usage string) *url.URL {
+	var value url.URL
+	fs.URLVar(&value, short, long, def, usage)
+	return &value
+}
+
+// URLShort defines a new URL flag in the flag set, and panics on any error.
+// See [FlagSet.URLVar] for more details.
+func (fs *FlagSet) URLShort(short rune, def string, usage string) *url.URL {
+	return fs.URL(short, "", def, usage)
+}
+
+// URLLong defines a new URL flag in the flag set, and panics on any error. See
+// [FlagSet.URLVar] for more details.
+func (fs *FlagSet) URLLong(long string, def string, usage string) *url.URL {
+	return fs.URL(0, long, def, usage)
+}
+
 // Float64Var defines a new flag in the flag set, and panics on any error.
 func (fs *FlagSet) Float64Var(pointer *float64, short rune, long string, def float64, usage string) Flag {
 	return fs.Value(short, long, ffval.NewValueDefault(pointer, def), usage)
@@ -1147,6 +2569,72 @@ func (fs *FlagSet) DurationConfig(cfg FlagConfig, def time.Duration) *time.Durat
 	return &value
 }
 
+// AddrVar defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrVar(pointer *netip.Addr, short rune, long string, def netip.Addr, usage string) Flag {
+	return fs.Value(short, long, ffval.NewValueDefault(pointer, def), usage)
+}
+
+// Addr defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) Addr(short rune, long string, def netip.Addr, usage string) *netip.Addr {
+	var value netip.Addr
+	fs.AddrVar(&value, short, long, def, usage)
+	return &value
+}
+
+// AddrShort defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrShort(short rune, def netip.Addr, usage string) *netip.Addr {
+	return fs.Addr(short, "", def, usage)
+}
+
+// AddrLong defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrLong(long string, def netip.Addr, usage string) *netip.Addr {
+	return fs.Addr(0, long, def, usage)
+}
+
+// AddrConfig defines a new flag in the flag set, and panics on any error.
+// The value field of the provided config is overwritten.
+func (fs *FlagSet) AddrConfig(cfg FlagConfig, def netip.Addr) *netip.Addr {
+	var value netip.Addr
+	cfg.Value = ffval.NewValueDefault(&value, def)
+	if _, err := fs.AddFlag(cfg); err != nil {
+		panic(err)
+	}
+	return &value
+}
+
+// AddrPortVar defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrPortVar(pointer *netip.AddrPort, short rune, long string, def netip.AddrPort, usage string) Flag {
+	return fs.Value(short, long, ffval.NewValueDefault(pointer, def), usage)
+}
+
+// AddrPort defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrPort(short rune, long string, def netip.AddrPort, usage string) *netip.AddrPort {
+	var value netip.AddrPort
+	fs.AddrPortVar(&value, short, long, def, usage)
+	return &value
+}
+
+// AddrPortShort defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrPortShort(short rune, def netip.AddrPort, usage string) *netip.AddrPort {
+	return fs.AddrPort(short, "", def, usage)
+}
+
+// AddrPortLong defines a new flag in the flag set, and panics on any error.
+func (fs *FlagSet) AddrPortLong(long string, def netip.AddrPort, usage string) *netip.AddrPort {
+	return fs.AddrPort(0, long, def, usage)
+}
+
+// AddrPortConfig defines a new flag in the flag set, and panics on any error.
+// The value field of the provided config is overwritten.
+func (fs *FlagSet) AddrPortConfig(cfg FlagConfig, def netip.AddrPort) *netip.AddrPort {
+	var value netip.AddrPort
+	cfg.Value = ffval.NewValueDefault(&value, def)
+	if _, err := fs.AddFlag(cfg); err != nil {
+		panic(err)
+	}
+	return &value
+}
+
 // Func defines a new flag in the flag set, and panics on any error.
 func (fs *FlagSet) Func(short rune, long string, fn func(string) error, usage string) {
 	stdfs := flag.NewFlagSet("flagset-name", flag.ContinueOnError)
@@ -1180,6 +2668,16 @@ type coreFlag struct {
 	isSet       bool
 	placeholder string
 	helpDefault string // string used in help text
+	noEnvVar    bool
+	envSplit    string
+	envAbsent   string // see [FlagConfig.EnvVarAbsentValue]
+	isPrefix    bool   // see [FlagSet.PrefixMap]
+	isRequired  bool
+	deprecated  string
+	isHidden    bool
+
+	deprecatedValues map[string]string // see [FlagConfig.DeprecatedValues]
+	deprecatedSet    []string          // deprecated values set since the last warning
 }
 
 var _ Flag = (*coreFlag)(nil)
@@ -1202,17 +2700,144 @@ func (f *coreFlag) GetUsage() string {
 }
 
 func (f *coreFlag) SetValue(s string) error {
-	if err := f.flagValue.Set(s); err != nil {
+	if f.flagSet.isFrozen {
+		return ErrFrozen
+	}
+	return f.set(s)
+}
+
+// setAbsolute is like SetValue, but counter flags treat integers as absolute
+// counts rather than booleans, see [ffval.Count.SetAbsolute]. Parse uses it for
+// values from sources other than the commandline, e.g. env vars.
+func (f *coreFlag) setAbsolute(s string) error {
+	c, ok := f.flagValue.(*ffval.Count)
+	if !ok {
+		return f.SetValue(s)
+	}
+	if f.flagSet.isFrozen {
+		return ErrFrozen
+	}
+	return f.setWith(c.SetAbsolute, s)
+}
+
+// set sets the flag value, and records the value if it's deprecated, so that
+// parse can warn about it.
+func (f *coreFlag) set(s string) error {
+	return f.setWith(f.flagValue.Set, s)
+}
+
+func (f *coreFlag) setWith(set func(string) error, s string) error {
+	if err := set(s); err != nil {
 		return err
 	}
 	f.isSet = true
+	if _, ok := f.deprecatedValues[s]; ok {
+		f.deprecatedSet = append(f.deprecatedSet, s)
+	}
 	return nil
 }
 
+// takeDeprecatedValues returns the distinct deprecated values which have been
+// set since the last call, along with their messages, in the order they were
+// set.
+func (f *coreFlag) takeDeprecatedValues() (values, messages []string) {
+	seen := map[string]bool{}
+	for _, v := range f.deprecatedSet {
+		if !seen[v] {
+			seen[v] = true
+			values = append(values, v)
+			messages = append(messages, f.deprecatedValues[v])
+		}
+	}
+	f.deprecatedSet = nil
+	return values, messages
+}
+
 func (f *coreFlag) GetValue() string {
 	return f.flagValue.String()
 }
 
+func (f *coreFlag) inheritValue(src Flag) error {
+	if f.flagSet.isFrozen {
+		return ErrFrozen
+	}
+	if ptr, ok := callGetPointer(f.flagValue); ok {
+		if v := reflect.ValueOf(GetAny(src)); v.IsValid() && v.Type() == ptr.Elem().Type() {
+			ptr.Elem().Set(cloneReflectValue(v))
+			f.isSet = true
+			return nil
+		}
+	}
+	return f.SetValue(src.GetValue())
+}
+
+// applyDefault makes def the default value of the flag, and, if the flag hasn't
+// been set, its current value as well.
+func (f *coreFlag) applyDefault(def reflect.Value) error {
+	// Values with a Default field of the same type, like those in package
+	// ffval, can take the default directly.
+	var typed bool
+	if rv := reflect.ValueOf(f.flagValue); rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Struct {
+		if field := rv.Elem().FieldByName("Default"); field.IsValid() && field.CanSet() && field.Type() == def.Type() {
+			field.Set(cloneReflectValue(def))
+			typed = true
+		}
+	}
+
+	str := reflectValueString(def)
+	switch {
+	case f.isSet:
+		// Keep the current value.
+	case typed:
+		if r, ok := f.flagValue.(Resetter); ok {
+			if err := r.Reset(); err != nil {
+				return err
+			}
+		}
+		str = f.flagValue.String()
+	default:
+		if err := f.flagValue.Set(str); err != nil {
+			return err
+		}
+		str = f.flagValue.String()
+	}
+
+	f.trueDefault = str
+	f.helpDefault = str
+	if f.isBoolFlag {
+		if b, err := strconv.ParseBool(str); err == nil && !b {
+			f.helpDefault = ""
+		}
+	}
+
+	return nil
+}
+
+func (f *coreFlag) GetAny() any {
+	v, ok := callGet(f.flagValue)
+	if !ok || (v.Kind() == reflect.Interface && v.IsNil()) {
+		return f.flagValue.String()
+	}
+	return v.Interface()
+}
+
+func (f *coreFlag) GetValid() []any {
+	get := reflect.ValueOf(f.flagValue).MethodByName("GetValid")
+	if !get.IsValid() || get.Type().NumIn() != 0 || get.Type().NumOut() != 1 {
+		return nil
+	}
+	valid := get.Call(nil)[0]
+	if valid.Kind() != reflect.Slice {
+		return nil
+	}
+	values := make([]any, valid.Len())
+	for i := range values {
+		elem := valid.Index(i)
+		values[i] = reifyJSONValue(elem, fmt.Sprint(elem.Interface()))
+	}
+	return values
+}
+
 func (f *coreFlag) IsSet() bool {
 	return f.isSet
 }
@@ -1229,6 +2854,7 @@ func (f *coreFlag) Reset() error {
 	}
 
 	f.isSet = false
+	f.deprecatedSet = nil
 	return nil
 }
 
@@ -1244,6 +2870,47 @@ func (f *coreFlag) IsStdFlag() bool {
 	return f.flagSet.isStdAdapter
 }
 
+func (f *coreFlag) IsNoEnvVar() bool {
+	return f.noEnvVar
+}
+
+func (f *coreFlag) GetEnvSplit() string {
+	return f.envSplit
+}
+
+func (f *coreFlag) GetEnvVarAbsentValue() string {
+	return f.envAbsent
+}
+
+func (f *coreFlag) IsRequired() bool {
+	return f.isRequired
+}
+
+func (f *coreFlag) GetDeprecated() string {
+	return f.deprecated
+}
+
+func (f *coreFlag) IsHidden() bool {
+	return f.isHidden
+}
+
+// ValidateParsed calls the method of the same name on the flag value, if it's
+// implemented, e.g. by [ffval.FixedList].
+func (f *coreFlag) ValidateParsed() error {
+	if v, ok := f.flagValue.(interface{ ValidateParsed() error }); ok {
+		return v.ValidateParsed()
+	}
+	return nil
+}
+
+func (f *coreFlag) IsBoolFlag() bool {
+	return f.isBoolFlag
+}
+
+func (f *coreFlag) IsStringFlag() bool {
+	return isStringFlag(f)
+}
+
 func isDuplicate(incoming, existing *coreFlag) bool {
 	var (
 		sameShortName = isValidShortName(incoming.shortName) && isValidShortName(existing.shortName) && incoming.shortName == existing.shortName
@@ -1,9 +1,13 @@
 package ff_test
 
 import (
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -111,6 +115,54 @@ func TestFlagSet_Bool(t *testing.T) {
 	}
 }
 
+func TestFlagSet_Count(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		args     []string
+		wantV    int
+		wantO    string
+		wantQ    bool
+		wantArgs []string
+	}{
+		{args: []string{}, wantV: 0},
+		{args: []string{"-v"}, wantV: 1},
+		{args: []string{"-vvv"}, wantV: 3},
+		{args: []string{"-vv", "-v"}, wantV: 3},
+		{args: []string{"-vqv"}, wantV: 2, wantQ: true},
+		{args: []string{"-vvo", "out.txt"}, wantV: 2, wantO: "out.txt"},
+		{args: []string{"-vvoout.txt", "-v"}, wantV: 3, wantO: "out.txt"},
+		{args: []string{"-vvvo", "out.txt", "arg"}, wantV: 3, wantO: "out.txt", wantArgs: []string{"arg"}},
+		{args: []string{"--verbose", "--verbose", "-v"}, wantV: 3},
+		{args: []string{"--verbose=5", "-v"}, wantV: 2},
+		{args: []string{"-vv", "--verbose=false"}, wantV: 3},
+	} {
+		t.Run(strings.Join(test.args, " "), func(t *testing.T) {
+			var verbosity int
+			fs := ff.NewFlagSet(t.Name())
+			fs.Value('v', "verbose", ffval.NewCount(&verbosity), "verbosity level")
+			output := fs.StringShort('o', "", "output file")
+			quiet := fs.BoolShort('q', "quiet mode")
+
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.wantV, verbosity; want != have {
+				t.Errorf("v: want %d, have %d", want, have)
+			}
+			if want, have := test.wantO, *output; want != have {
+				t.Errorf("o: want %q, have %q", want, have)
+			}
+			if want, have := test.wantQ, *quiet; want != have {
+				t.Errorf("q: want %v, have %v", want, have)
+			}
+			if want, have := test.wantArgs, fs.GetArgs(); len(want) > 0 && !reflect.DeepEqual(want, have) {
+				t.Errorf("args: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
 func TestStdFlags_Bool(t *testing.T) {
 	t.Parallel()
 
@@ -163,107 +215,1571 @@ func TestStdFlags_Bool(t *testing.T) {
 	}
 }
 
-func TestFlagSet_HelpFlag(t *testing.T) {
+func TestFlagSet_HelpFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	helpflag := fs.BoolLong("help", "alternative help flag")
+
+	// -h should still trigger ErrHelp.
+	if err := fs.Parse([]string{"-h"}); !errors.Is(err, ff.ErrHelp) {
+		t.Errorf("Parse(-h): want %v, have %v", ff.ErrHelp, err)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset(): %v", err)
+	}
+
+	// --help should not.
+	if err := fs.Parse([]string{"--help"}); err != nil {
+		t.Errorf("Parse(--help): error: %v", err)
+	}
+
+	// It should set the flag we defined.
+	if want, have := true, *helpflag; want != have {
+		t.Errorf("h: want %v, have %v", want, have)
+	}
+}
+
+func TestFlagSet_GetFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	fs.IntLong("foo", 0, "first flag")
+	fs.IntShort('f', 0, "second flag")
+
+	f1, ok := fs.GetFlag("foo")
+	if !ok {
+		t.Fatalf(`GetFlag("foo"): returned not-OK`)
+	}
+	if want, have := "first flag", f1.GetUsage(); want != have {
+		t.Errorf(`GetFlag("foo"): GetUsage: want %q, have %q`, want, have)
+	}
+
+	f2, ok := fs.GetFlag("f")
+	if !ok {
+		t.Fatalf(`GetFlag("f"): returned not-OK`)
+	}
+	if want, have := "second flag", f2.GetUsage(); want != have {
+		t.Errorf(`GetFlag("f"): GetUsage: want %q, have %q`, want, have)
+	}
+}
+
+func TestFlagSet_NoDefault(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	alpha, _ := fs.AddFlag(ff.FlagConfig{LongName: "alpha", Value: &ffval.Duration{}, Usage: "zero duration"})
+	beta, _ := fs.AddFlag(ff.FlagConfig{LongName: "beta", Value: &ffval.Duration{}, Usage: "zero duration with NoDefault", NoDefault: true})
+
+	if want, have := "0s", alpha.GetDefault(); want != have {
+		t.Errorf("alpha: default: want %q, have %q", want, have)
+	}
+
+	if want, have := "", beta.GetDefault(); want != have {
+		t.Errorf("beta: default: want %q, have %q", want, have)
+	}
+}
+
+func TestFlagSet_NoPlaceholder(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	alpha, _ := fs.AddFlag(ff.FlagConfig{LongName: "alpha", Value: &ffval.Bool{}, Usage: "alpha", NoPlaceholder: true})
+	beta, _ := fs.AddFlag(ff.FlagConfig{LongName: "beta", Value: ffval.NewValueDefault(new(bool), true), Usage: "beta", NoPlaceholder: true})
+	delta, _ := fs.AddFlag(ff.FlagConfig{LongName: "delta", Value: ffval.NewValueDefault(new(bool), true), Usage: "delta `D` flag", NoPlaceholder: true})
+	kappa, _ := fs.AddFlag(ff.FlagConfig{LongName: "kappa", Value: ffval.NewValue(new(bool)), Usage: "kappa `K` flag", NoPlaceholder: true})
+
+	for _, f := range []ff.Flag{alpha, beta, delta, kappa} {
+		if want, have := "", f.GetPlaceholder(); want != have {
+			t.Errorf("%s: want %q, have %q", ffhelp.WrapFlag(f), want, have)
+		}
+	}
+}
+
+func TestFlagSet_PlaceholderFunc(t *testing.T) {
+	t.Parallel()
+
+	type customStringSlice []string
+
+	fs := ff.NewFlagSet(t.Name()).SetPlaceholderFunc(func(cfg ff.FlagConfig) string {
+		switch cfg.Value.(type) {
+		case *ffval.List[customStringSlice]:
+			return "ROOT"
+		case *ffval.Duration:
+			return "TIMEOUT"
+		default:
+			return ""
+		}
+	})
+
+	roots, _ := fs.AddFlag(ff.FlagConfig{LongName: "roots", Value: ffval.NewListParser(func(s string) (customStringSlice, error) { return customStringSlice{s}, nil })})
+	alpha, _ := fs.AddFlag(ff.FlagConfig{LongName: "alpha", Value: &ffval.Duration{}})
+	beta, _ := fs.AddFlag(ff.FlagConfig{LongName: "beta", Value: &ffval.Duration{}, Placeholder: "DUR"})
+	delta, _ := fs.AddFlag(ff.FlagConfig{LongName: "delta", Value: &ffval.Duration{}, Usage: "delta `D` flag"})
+	kappa, _ := fs.AddFlag(ff.FlagConfig{LongName: "kappa", Value: &ffval.Duration{}, NoPlaceholder: true})
+	gamma, _ := fs.AddFlag(ff.FlagConfig{LongName: "gamma", Value: &ffval.Int{}})
+	omega, _ := fs.AddFlag(ff.FlagConfig{LongName: "omega", Value: &ffval.Bool{}})
+
+	for _, test := range []struct {
+		flag ff.Flag
+		want string
+	}{
+		{roots, "ROOT"},
+		{alpha, "TIMEOUT"},
+		{beta, "DUR"},
+		{delta, "D"},
+		{kappa, ""},
+		{gamma, "INT"},
+		{omega, ""},
+	} {
+		if want, have := test.want, test.flag.GetPlaceholder(); want != have {
+			t.Errorf("%s: want %q, have %q", ffhelp.WrapFlag(test.flag), want, have)
+		}
+	}
+}
+
+func TestFlagSet_Get(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	f, err := fs.AddFlag(ff.FlagConfig{
+		LongName:    "alpha",
+		Value:       new(ffval.Int),
+		Placeholder: "X",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := "0", f.GetDefault(); want != have {
+		t.Errorf("GetDefault: want %q, have %q", want, have)
+	}
+
+	if want, have := "X", f.GetPlaceholder(); want != have {
+		t.Errorf("GetPlaceholder: want %q, have %q", want, have)
+	}
+}
+
+func TestFlagSet_StringOrderedMap(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	headers := fs.StringOrderedMap('H', "header", "HTTP header")
+
+	if err := fs.Parse([]string{"-H", "X-B=2", "--header=X-A=1", "-HX-C=3", "--header", "X-B=22"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []ffval.Pair[string]{{Key: "X-B", Value: "22"}, {Key: "X-A", Value: "1"}, {Key: "X-C", Value: "3"}}
+	if have := *headers; !reflect.DeepEqual(want, have) {
+		t.Errorf("headers: want %v, have %v", want, have)
+	}
+}
+
+func TestFlagSet_StringMap(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	labels := fs.StringMap('l', "label", "resource label")
+
+	if err := fs.Parse([]string{"-l", "env=prod", "--label=team=core", "--label", "env=staging"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := map[string]string{"env": "staging", "team": "core"}
+	if have := *labels; !reflect.DeepEqual(want, have) {
+		t.Errorf("labels: want %v, have %v", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := fs.Parse([]string{"--label=novalue"}); err == nil {
+		t.Errorf("want error, have none")
+	}
+}
+
+func TestFlagSet_StringMapSplit(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	meta := fs.StringMapSplitLong("meta", "metadata")
+
+	if err := fs.Parse([]string{"--meta", "a=1,b=2", `--meta=note=x\,y`}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := map[string]string{"a": "1", "b": "2", "note": "x,y"}
+	if have := *meta; !reflect.DeepEqual(want, have) {
+		t.Errorf("meta: want %v, have %v", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	err := fs.Parse([]string{"--meta=a=1,bad"})
+	if err == nil || !strings.Contains(err.Error(), `"bad"`) {
+		t.Errorf("want error naming the pair, have %v", err)
+	}
+}
+
+func TestFlagSet_PrefixMap(t *testing.T) {
+	t.Parallel()
+
+	for _, clustering := range []bool{true, false} {
+		t.Run(fmt.Sprintf("clustering=%v", clustering), func(t *testing.T) {
+			var defs map[string]string
+			fs := ff.NewFlagSet(t.Name()).SetShortFlagClustering(clustering)
+			verbose := fs.BoolShort('v', "verbose")
+			fs.PrefixMap('X', &defs, "definitions")
+
+			args := []string{"-Xa=1", "-Xb=2", "-X", "c=3"}
+			if clustering {
+				args = append(args, "-vXd=4")
+			} else {
+				args = append(args, "-v", "-Xd=4")
+			}
+			if err := fs.Parse(args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			want := map[string]string{"a": "1", "b": "2", "c": "3", "d": "4"}
+			if have := defs; !reflect.DeepEqual(want, have) {
+				t.Errorf("defs: want %v, have %v", want, have)
+			}
+			if !*verbose {
+				t.Errorf("verbose: want true, have false")
+			}
+		})
+	}
+}
+
+func TestFlagSet_ResetAll(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	pflag := parent.StringLong("pflag", "pdef", "parent string")
+
+	child := ff.NewFlagSet("child").SetParent(parent)
+	cflag := child.StringLong("cflag", "cdef", "child string")
+
+	args := []string{"--pflag=pval", "--cflag=cval"}
+
+	if err := child.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if err := child.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := "cdef", *cflag; want != have {
+		t.Errorf("after Reset: cflag: want %q, have %q", want, have)
+	}
+	if want, have := "pval", *pflag; want != have {
+		t.Errorf("after Reset: pflag: want %q, have %q", want, have)
+	}
+
+	if err := child.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if err := child.ResetAll(); err != nil {
+		t.Fatalf("ResetAll: %v", err)
+	}
+	if want, have := "cdef", *cflag; want != have {
+		t.Errorf("after ResetAll: cflag: want %q, have %q", want, have)
+	}
+	if want, have := "pdef", *pflag; want != have {
+		t.Errorf("after ResetAll: pflag: want %q, have %q", want, have)
+	}
+	if f, _ := child.GetFlag("pflag"); f.IsSet() {
+		t.Errorf("after ResetAll: pflag: IsSet: want false, have true")
+	}
+	if parent.IsParsed() || child.IsParsed() {
+		t.Errorf("after ResetAll: want unparsed flag sets")
+	}
+}
+
+func TestFlagSet_StringEnumFold(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	level := fs.StringEnumFold('l', "log", "log level", "info", "debug", "error")
+
+	if want, have := "info", *level; want != have {
+		t.Errorf("default: want %q, have %q", want, have)
+	}
+	if err := fs.Parse([]string{"--log=DEBUG"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := "debug", *level; want != have {
+		t.Errorf("after parse: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := fs.Parse([]string{"-l", "warn"}); !errors.Is(err, ffval.ErrInvalidValue) {
+		t.Errorf("invalid value: want %v, have %v", ffval.ErrInvalidValue, err)
+	}
+}
+
+func TestGetAny(t *testing.T) {
+	t.Parallel()
+
+	stdfs := flag.NewFlagSet("std", flag.ContinueOnError)
+	stdfs.Int("port", 8080, "port number")
+	stdfs.Func("func", "func flag", func(string) error { return nil })
+
+	fs := ff.NewFlagSet(t.Name())
+	fs.Int('i', "int", 0, "int flag")
+	fs.StringList('l', "list", "list flag")
+	fs.DurationLong("dur", time.Second, "duration flag")
+	fs.SetParent(ff.NewFlagSetFrom("std", stdfs))
+
+	if err := fs.Parse([]string{"-i", "3", "-l", "a", "-l", "b", "--port=9090"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for _, test := range []struct {
+		name string
+		want any
+	}{
+		{"int", 3},
+		{"list", []string{"a", "b"}},
+		{"dur", time.Second},
+		{"port", 9090},
+		{"func", ""},
+	} {
+		f, ok := fs.GetFlag(test.name)
+		if !ok {
+			t.Errorf("%s: flag not found", test.name)
+			continue
+		}
+		if want, have := test.want, ff.GetAny(f); !reflect.DeepEqual(want, have) {
+			t.Errorf("%s: want %#v (%T), have %#v (%T)", test.name, want, want, have, have)
+		}
+	}
+}
+
+func TestFlagSet_InheritValues(t *testing.T) {
+	t.Parallel()
+
+	global := ff.NewFlagSet("global")
+	global.String('c', "config", "", "config file")
+	global.Bool('v', "verbose", "verbose logging")
+	global.StringList('t', "tag", "tags")
+	global.IntShort('n', 1, "count")
+	global.StringLong("unmatched", "", "not in the subcommand")
+	if err := global.Parse([]string{"--config=app.conf", "-v", "-t", "a", "-t", "b", "-n", "9", "--unmatched=x"}); err != nil {
+		t.Fatalf("global Parse: %v", err)
+	}
+
+	sub := ff.NewFlagSet("sub")
+	config := sub.StringLong("config", "default.conf", "config file")
+	verbose := sub.BoolLong("verbose", "verbose logging")
+	tags := sub.StringListLong("tag", "tags")
+	count := sub.IntShort('n', 0, "count")
+	other := sub.StringLong("other", "def", "not in the global set")
+
+	if err := sub.InheritValues(global); err != nil {
+		t.Fatalf("InheritValues: %v", err)
+	}
+
+	if want, have := "app.conf", *config; want != have {
+		t.Errorf("config: want %q, have %q", want, have)
+	}
+	if want, have := true, *verbose; want != have {
+		t.Errorf("verbose: want %v, have %v", want, have)
+	}
+	if want, have := []string{"a", "b"}, *tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("tags: want %q, have %q", want, have)
+	}
+	if want, have := 9, *count; want != have {
+		t.Errorf("count: want %d, have %d", want, have)
+	}
+	if want, have := "def", *other; want != have {
+		t.Errorf("other: want %q, have %q", want, have)
+	}
+
+	for _, name := range []string{"config", "verbose", "tag", "n"} {
+		if f, _ := sub.GetFlag(name); !f.IsSet() {
+			t.Errorf("%s: want set, have unset", name)
+		}
+	}
+	if f, _ := sub.GetFlag("other"); f.IsSet() {
+		t.Errorf("other: want unset, have set")
+	}
+
+	if err := sub.Parse([]string{"--tag=c"}); err != nil {
+		t.Fatalf("sub Parse: %v", err)
+	}
+	if want, have := []string{"a", "b", "c"}, *tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("tags after Parse: want %q, have %q", want, have)
+	}
+	globalTag, _ := global.GetFlag("tag")
+	if want, have := []string{"a", "b"}, ff.GetAny(globalTag); !reflect.DeepEqual(want, have) {
+		t.Errorf("global tags: want %q, have %q", want, have)
+	}
+}
+
+func TestFlagSet_Set(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	debug := parent.BoolLong("debug", "debug mode")
+
+	fs := ff.NewFlagSet(t.Name()).SetParent(parent)
+	port := fs.Int('p', "port", 8080, "listen port")
+	host := fs.StringLong("host", "localhost", "listen host")
+
+	for _, test := range []struct {
+		name, value string
+	}{
+		{"p", "9090"},
+		{"host", "example.com"},
+		{"debug", "true"},
+	} {
+		if err := fs.Set(test.name, test.value); err != nil {
+			t.Errorf("Set(%q, %q): %v", test.name, test.value, err)
+		}
+	}
+
+	if want, have := 9090, *port; want != have {
+		t.Errorf("port: want %d, have %d", want, have)
+	}
+	if want, have := "example.com", *host; want != have {
+		t.Errorf("host: want %q, have %q", want, have)
+	}
+	if want, have := true, *debug; want != have {
+		t.Errorf("debug: want %v, have %v", want, have)
+	}
+	for _, name := range []string{"port", "host", "debug"} {
+		if f, _ := fs.GetFlag(name); !f.IsSet() {
+			t.Errorf("%s: want set, have unset", name)
+		}
+	}
+
+	if err := fs.Set("nope", "1"); !errors.Is(err, ff.ErrUnknownFlag) {
+		t.Errorf("Set unknown: want %v, have %v", ff.ErrUnknownFlag, err)
+	}
+	if err := fs.Set("port", "abc"); err == nil {
+		t.Errorf("Set invalid: want error, have none")
+	}
+}
+
+func TestFlagSet_Freeze(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	debug := parent.BoolLong("debug", "debug mode")
+
+	fs := ff.NewFlagSet(t.Name()).SetParent(parent)
+	port := fs.IntLong("port", 8080, "listen port")
+
+	if err := fs.Parse([]string{"--port=9090"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if fs.IsFrozen() {
+		t.Fatalf("want not frozen before Freeze")
+	}
+
+	fs.Freeze()
+	if !fs.IsFrozen() {
+		t.Fatalf("want frozen after Freeze")
+	}
+
+	portFlag, _ := fs.GetFlag("port")
+	if err := portFlag.SetValue("1"); !errors.Is(err, ff.ErrFrozen) {
+		t.Errorf("SetValue: want %v, have %v", ff.ErrFrozen, err)
+	}
+	if want, have := 9090, *port; want != have {
+		t.Errorf("port: want %d, have %d", want, have)
+	}
+	if err := fs.Parse([]string{"--port=1"}); !errors.Is(err, ff.ErrFrozen) {
+		t.Errorf("Parse: want %v, have %v", ff.ErrFrozen, err)
+	}
+
+	debugFlag, _ := fs.GetFlag("debug")
+	if err := debugFlag.SetValue("true"); err != nil {
+		t.Errorf("parent SetValue: %v", err)
+	}
+	if want, have := true, *debug; want != have {
+		t.Errorf("debug: want %v, have %v", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if fs.IsFrozen() {
+		t.Errorf("want not frozen after Reset")
+	}
+	if err := portFlag.SetValue("1234"); err != nil {
+		t.Errorf("SetValue after Reset: %v", err)
+	}
+	if want, have := 1234, *port; want != have {
+		t.Errorf("port after Reset: want %d, have %d", want, have)
+	}
+}
+
+func TestFlagSet_FlagGroups(t *testing.T) {
+	t.Parallel()
+
+	key := "TEST_FLAG_GROUPS_YAML"
+	defer os.Setenv(key, os.Getenv(key))
+	os.Setenv(key, "true")
+
+	newFlagSet := func(t *testing.T) *ff.FlagSet {
+		t.Helper()
+		parent := ff.NewFlagSet("parent")
+		parent.BoolLong("json", "JSON output")
+		fs := ff.NewFlagSet("child").SetParent(parent)
+		fs.BoolLong("yaml", "YAML output")
+		fs.StringLong("cert", "", "TLS cert")
+		fs.StringLong("key", "", "TLS key")
+		if err := fs.MarkMutuallyExclusive("json", "yaml"); err != nil {
+			t.Fatalf("MarkMutuallyExclusive: %v", err)
+		}
+		if err := fs.MarkRequiredTogether("cert", "key"); err != nil {
+			t.Fatalf("MarkRequiredTogether: %v", err)
+		}
+		return fs
+	}
+
+	for _, test := range []struct {
+		name    string
+		args    []string
+		options []ff.Option
+		wantErr string
+	}{
+		{"none", []string{}, nil, ""},
+		{"one exclusive", []string{"--json"}, nil, ""},
+		{"both exclusive", []string{"--json", "--yaml"}, nil, "mutually exclusive flags: --json, --yaml"},
+		{"exclusive across sources", []string{"--json"}, []ff.Option{ff.WithEnvVarPrefix("TEST_FLAG_GROUPS")}, "mutually exclusive flags: --json, --yaml"},
+		{"together", []string{"--cert=c", "--key=k"}, nil, ""},
+		{"not together", []string{"--cert=c"}, nil, "flags must be set together: --cert requires --key"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			err := ff.Parse(newFlagSet(t), test.args, test.options...)
+			if want, have := test.wantErr, fmt.Sprint(err); test.wantErr != "" && want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+			if test.wantErr == "" && err != nil {
+				t.Errorf("Parse: %v", err)
+			}
+		})
+	}
+
+	fs := ff.NewFlagSet(t.Name())
+	fs.BoolLong("json", "JSON output")
+	if err := fs.MarkMutuallyExclusive("json", "yaml"); !errors.Is(err, ff.ErrUnknownFlag) {
+		t.Errorf("unknown flag: want %v, have %v", ff.ErrUnknownFlag, err)
+	}
+	if err := fs.MarkRequiredTogether("json"); err == nil {
+		t.Errorf("single flag: want error, have none")
+	}
+}
+
+func TestFlagSet_IntFixedList(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		args    []string
+		want    []int
+		wantErr string
+	}{
+		{"exact", []string{"--color", "255", "--color", "0", "--color", "0"}, []int{255, 0, 0}, ""},
+		{"none", []string{}, nil, ""},
+		{"fewer", []string{"--color", "255", "--color", "0"}, nil, "--color: too few values (want 3, have 2)"},
+		{"more", []string{"--color=1", "--color=2", "--color=3", "--color=4"}, nil, "too many values (want 3)"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name())
+			color := fs.IntFixedListLong("color", 3, "RGB color")
+			err := ff.Parse(fs, test.args)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("want error containing %q, have %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, *color; !reflect.DeepEqual(want, have) {
+				t.Errorf("color: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestFlag_IsBoolFlag(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	fs.Bool('b', "bool", "bool flag")
+	fs.String('s', "str", "", "string flag")
+	fs.Value('c', "count", new(ffval.Count), "count flag")
+
+	stdfs := flag.NewFlagSet("std", flag.ContinueOnError)
+	stdfs.Bool("stdbool", false, "std bool flag")
+	stdfs.String("stdstr", "", "std string flag")
+	fs.SetParent(ff.NewFlagSetFrom("std", stdfs))
+
+	for name, want := range map[string]bool{
+		"bool":    true,
+		"str":     false,
+		"count":   true,
+		"stdbool": true,
+		"stdstr":  false,
+	} {
+		f, ok := fs.GetFlag(name)
+		if !ok {
+			t.Fatalf("GetFlag(%q): not found", name)
+		}
+		if have := f.IsBoolFlag(); want != have {
+			t.Errorf("%s: IsBoolFlag: want %v, have %v", name, want, have)
+		}
+	}
+}
+
+func TestFlagSet_Visit(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	parent.BoolLong("debug", "debug mode")
+	parent.StringLong("log", "info", "log level")
+
+	fs := ff.NewFlagSet(t.Name()).SetParent(parent)
+	fs.IntLong("port", 8080, "listen port")
+	fs.StringLong("host", "localhost", "listen host")
+
+	if err := fs.Parse([]string{"--port=9090", "--debug"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var visited []string
+	if err := fs.Visit(func(f ff.Flag) error {
+		long, _ := f.GetLongName()
+		visited = append(visited, long+"="+f.GetValue())
+		return nil
+	}); err != nil {
+		t.Fatalf("Visit: %v", err)
+	}
+	if want, have := []string{"port=9090", "debug=true"}, visited; !reflect.DeepEqual(want, have) {
+		t.Errorf("Visit: want %v, have %v", want, have)
+	}
+
+	var walked int
+	fs.WalkFlags(func(ff.Flag) error { walked++; return nil })
+	if want, have := 4, walked; want != have {
+		t.Errorf("WalkFlags: want %d, have %d", want, have)
+	}
+
+	errStop := errors.New("stop")
+	if err := fs.Visit(func(ff.Flag) error { return errStop }); !errors.Is(err, errStop) {
+		t.Errorf("Visit error: want %v, have %v", errStop, err)
+	}
+}
+
+func TestFlagSet_ChangedFlags(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	parent.BoolLong("debug", "debug mode")
+	parent.StringLong("log", "info", "log level")
+
+	fs := ff.NewFlagSet(t.Name()).SetParent(parent)
+	fs.IntLong("port", 8080, "listen port")
+	fs.StringLong("host", "localhost", "listen host")
+
+	if err := fs.Parse([]string{"--port=9090", "--host=localhost", "--log=debug"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var changed []string
+	for _, f := range fs.ChangedFlags() {
+		long, _ := f.GetLongName()
+		changed = append(changed, long+"="+f.GetValue())
+	}
+	if want, have := []string{"port=9090", "log=debug"}, changed; !reflect.DeepEqual(want, have) {
+		t.Errorf("ChangedFlags: want %v, have %v", want, have)
+	}
+
+	host, _ := fs.GetFlag("host")
+	if !host.IsSet() {
+		t.Errorf("host: want IsSet true, have false")
+	}
+}
+
+func TestFlagSet_GetRawArgs(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	fs.Bool('v', "verbose", "verbose output")
+	fs.StringLong("name", "", "name string")
+
+	args := []string{"-v", "--name=foo", "a", "--", "b"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	args[0] = "modified"
+
+	if want, have := []string{"-v", "--name=foo", "a", "--", "b"}, fs.GetRawArgs(); !reflect.DeepEqual(want, have) {
+		t.Errorf("GetRawArgs: want %q, have %q", want, have)
+	}
+	if want, have := []string{"a", "--", "b"}, fs.GetArgs(); !reflect.DeepEqual(want, have) {
+		t.Errorf("GetArgs: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := 0, len(fs.GetRawArgs()); want != have {
+		t.Errorf("after Reset: GetRawArgs: want %d args, have %d", want, have)
+	}
+
+	if err := fs.Parse([]string{"--undefined"}); err == nil {
+		t.Fatalf("Parse: want error, have none")
+	}
+	if want, have := []string{"--undefined"}, fs.GetRawArgs(); !reflect.DeepEqual(want, have) {
+		t.Errorf("after failed parse: GetRawArgs: want %q, have %q", want, have)
+	}
+}
+
+func TestFlagSet_FlagOptions(t *testing.T) {
+	t.Parallel()
+
+	var port1, port2 int
+
+	fs1 := ff.NewFlagSet("test")
+	if _, err := fs1.AddFlag(ff.FlagConfig{
+		ShortName:   'p',
+		LongName:    "port",
+		Usage:       "listen port",
+		Value:       ffval.NewValue(&port1),
+		Placeholder: "N",
+		NoDefault:   true,
+		NoEnvVar:    true,
+	}); err != nil {
+		t.Fatalf("AddFlag: %v", err)
+	}
+
+	fs2 := ff.NewFlagSet("test")
+	f2, err := fs2.Flag(ffval.NewValue(&port2),
+		ff.FlagShortName('p'),
+		ff.FlagLongName("port"),
+		ff.FlagUsage("listen port"),
+		ff.FlagPlaceholder("N"),
+		ff.FlagNoDefault(),
+		ff.FlagNoEnvVar(),
+	)
+	if err != nil {
+		t.Fatalf("Flag: %v", err)
+	}
+
+	if want, have := ffhelp.Flags(fs1).String(), ffhelp.Flags(fs2).String(); want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+	if want, have := "N", f2.GetPlaceholder(); want != have {
+		t.Errorf("GetPlaceholder: want %q, have %q", want, have)
+	}
+	if noEnv, ok := f2.(interface{ IsNoEnvVar() bool }); !ok || !noEnv.IsNoEnvVar() {
+		t.Errorf("IsNoEnvVar: want true")
+	}
+
+	if err := fs2.Parse([]string{"-p", "8080"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := 8080, port2; want != have {
+		t.Errorf("port: want %d, have %d", want, have)
+	}
+
+	if _, err := fs2.Flag(ffval.NewValue(new(int)), ff.FlagUsage("no names")); err == nil {
+		t.Errorf("Flag without names: want error, have none")
+	}
+}
+
+func TestFlagSet_CaptureUnknown(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name        string
+		args        []string
+		wantVerbose bool
+		wantName    string
+		wantUnknown []string
+		wantArgs    []string
+		wantErr     error
+	}{
+		{
+			name:        "none",
+			args:        []string{"-v", "--name=foo"},
+			wantVerbose: true,
+			wantName:    "foo",
+			wantUnknown: []string{},
+			wantArgs:    []string{},
+		},
+		{
+			name:        "separate and explicit values",
+			args:        []string{"--unknown", "x", "-v", "--other=y", "--name", "foo", "a"},
+			wantVerbose: true,
+			wantName:    "foo",
+			wantUnknown: []string{"--unknown", "x", "--other=y"},
+			wantArgs:    []string{"a"},
+		},
+		{
+			name:        "no value",
+			args:        []string{"--force", "--name=foo", "--dry-run"},
+			wantName:    "foo",
+			wantUnknown: []string{"--force", "--dry-run"},
+			wantArgs:    []string{},
+		},
+		{
+			name:        "empty explicit value",
+			args:        []string{"--empty=", "a"},
+			wantUnknown: []string{"--empty="},
+			wantArgs:    []string{"a"},
+		},
+		{
+			name:        "terminator",
+			args:        []string{"--unknown", "--", "--other=y"},
+			wantUnknown: []string{"--unknown"},
+			wantArgs:    []string{"--other=y"},
+		},
+		{
+			name:        "short flag",
+			args:        []string{"-z"},
+			wantUnknown: []string{},
+			wantErr:     ff.ErrUnknownFlag,
+		},
+		{
+			name:        "help",
+			args:        []string{"--help"},
+			wantUnknown: []string{},
+			wantErr:     ff.ErrHelp,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			unknown := []string{}
+			fs := ff.NewFlagSet(t.Name()).CaptureUnknown(&unknown)
+			verbose := fs.Bool('v', "verbose", "verbose output")
+			name := fs.StringLong("name", "", "name string")
+
+			if err := fs.Parse(test.args); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Parse: want %v, have %v", test.wantErr, err)
+			}
+			if test.wantErr != nil {
+				return
+			}
+			if want, have := test.wantVerbose, *verbose; want != have {
+				t.Errorf("verbose: want %v, have %v", want, have)
+			}
+			if want, have := test.wantName, *name; want != have {
+				t.Errorf("name: want %q, have %q", want, have)
+			}
+			if want, have := test.wantUnknown, unknown; !reflect.DeepEqual(want, have) {
+				t.Errorf("unknown: want %q, have %q", want, have)
+			}
+			if want, have := test.wantArgs, fs.GetArgs(); !reflect.DeepEqual(want, have) {
+				t.Errorf("args: want %q, have %q", want, have)
+			}
+
+			if err := fs.Reset(); err != nil {
+				t.Fatalf("Reset: %v", err)
+			}
+			if want, have := 0, len(unknown); want != have {
+				t.Errorf("after Reset: unknown: want %d, have %d", want, have)
+			}
+		})
+	}
+}
+
+func TestFlagSet_UnknownFlagsAsArgs(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name         string
+		args         []string
+		noClustering bool
+		wantVerbose  bool
+		wantName     string
+		wantArgs     []string
+		wantErr      error
+	}{
+		{
+			name:        "none",
+			args:        []string{"-v", "--name=foo", "a"},
+			wantVerbose: true,
+			wantName:    "foo",
+			wantArgs:    []string{"a"},
+		},
+		{
+			name:        "long flags",
+			args:        []string{"--unknown", "x", "-v", "--other=y", "--name", "foo", "a", "--name=bar"},
+			wantVerbose: true,
+			wantName:    "foo",
+			wantArgs:    []string{"--unknown", "x", "--other=y", "a", "--name=bar"},
+		},
+		{
+			name:     "no value",
+			args:     []string{"--force", "--name=foo", "--dry-run"},
+			wantName: "foo",
+			wantArgs: []string{"--force", "--dry-run"},
+		},
+		{
+			name:     "short flags",
+			args:     []string{"-z", "1", "-yfoo", "--name", "foo", "-x"},
+			wantName: "foo",
+			wantArgs: []string{"-z", "1", "-yfoo", "-x"},
+		},
+		{
+			name:        "clustered short flags",
+			args:        []string{"-vzq", "a"},
+			wantVerbose: true,
+			wantArgs:    []string{"-zq", "a"},
+		},
+		{
+			name:         "short flags without clustering",
+			args:         []string{"-abc", "x", "-z=1", "-v"},
+			noClustering: true,
+			wantVerbose:  true,
+			wantArgs:     []string{"-abc", "x", "-z=1"},
+		},
+		{
+			name:     "terminator",
+			args:     []string{"--unknown", "--", "--other=y"},
+			wantArgs: []string{"--unknown", "--", "--other=y"},
+		},
+		{
+			name:     "terminator without unknown flags",
+			args:     []string{"--name=foo", "--", "--other=y"},
+			wantName: "foo",
+			wantArgs: []string{"--other=y"},
+		},
+		{
+			name:    "help",
+			args:    []string{"--unknown", "-h"},
+			wantErr: ff.ErrHelp,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name()).SetUnknownFlagsAsArgs(true).SetShortFlagClustering(!test.noClustering)
+			verbose := fs.Bool('v', "verbose", "verbose output")
+			name := fs.StringLong("name", "", "name string")
+
+			if err := fs.Parse(test.args); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Parse: want %v, have %v", test.wantErr, err)
+			}
+			if test.wantErr != nil {
+				return
+			}
+			if want, have := test.wantVerbose, *verbose; want != have {
+				t.Errorf("verbose: want %v, have %v", want, have)
+			}
+			if want, have := test.wantName, *name; want != have {
+				t.Errorf("name: want %q, have %q", want, have)
+			}
+			if want, have := test.wantArgs, fs.GetArgs(); !reflect.DeepEqual(want, have) {
+				t.Errorf("args: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestFlagSet_Validate(t *testing.T) {
+	t.Parallel()
+
+	parent := ff.NewFlagSet("parent")
+	parent.Value(0, "mode", &ffval.Enum[string]{Valid: []string{"fast", "slow"}, Default: "fats"}, "mode")
+
+	child := ff.NewFlagSet("child").SetParent(parent)
+	child.StringEnum('l', "log", "log level", "info", "debug")
+	child.Value(0, "level", &ffval.Enum[int]{Valid: []int{1, 2, 3}, Default: 4}, "level")
+
+	if err := ff.NewFlagSet("empty").Validate(); err != nil {
+		t.Errorf("empty: Validate: %v", err)
+	}
+
+	err := child.Validate()
+	if !errors.Is(err, ffval.ErrInvalidValue) {
+		t.Fatalf("Validate: want %v, have %v", ffval.ErrInvalidValue, err)
+	}
+	if want, have := "--level: default 4: invalid value\n--mode: default fats: invalid value", err.Error(); want != have {
+		t.Errorf("Validate: want %q, have %q", want, have)
+	}
+}
+
+func TestFlagsByOwner(t *testing.T) {
+	t.Parallel()
+
+	root := ff.NewFlagSet("root")
+	root.Bool('v', "verbose", "verbose logging")
+	root.StringLong("config", "", "config file")
+
+	foo := ff.NewFlagSet("foo").SetParent(root)
+	foo.Int('a', "alpha", 10, "alpha integer")
+
+	bar := ff.NewFlagSet("bar").SetParent(foo)
+	bar.Duration('d', "delta", time.Second, "delta duration")
+	bar.Float64('e', "epsilon", 3.21, "epsilon float")
+
+	names := func(groups []ff.FlagGroup) []string {
+		var res []string
+		for _, g := range groups {
+			var flags []string
+			for _, f := range g.Flags {
+				long, _ := f.GetLongName()
+				flags = append(flags, long)
+			}
+			res = append(res, g.Name+": "+strings.Join(flags, " "))
+		}
+		return res
+	}
+
+	for _, test := range []struct {
+		fs   ff.Flags
+		want []string
+	}{
+		{fs: root, want: []string{"root: verbose config"}},
+		{fs: foo, want: []string{"foo: alpha", "root: verbose config"}},
+		{fs: bar, want: []string{"bar: delta epsilon", "foo: alpha", "root: verbose config"}},
+		{fs: ff.NewFlagSet("empty"), want: nil},
+	} {
+		t.Run(test.fs.GetName(), func(t *testing.T) {
+			if want, have := test.want, names(ff.FlagsByOwner(test.fs)); !reflect.DeepEqual(want, have) {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+
+	t.Run("owner identity", func(t *testing.T) {
+		for _, g := range ff.FlagsByOwner(bar) {
+			for _, f := range g.Flags {
+				if want, have := g.Name, f.GetFlags().GetName(); want != have {
+					long, _ := f.GetLongName()
+					t.Errorf("%s: owner: want %q, have %q", long, want, have)
+				}
+			}
+		}
+	})
+}
+
+func TestFlagSet_Addr(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fs          = ff.NewFlagSet(t.Name())
+		listen      netip.Addr
+		listenFlag  = fs.AddrVar(&listen, 'l', "listen", netip.MustParseAddr("0.0.0.0"), "listen address")
+		peer        = fs.AddrLong("peer", netip.Addr{}, "peer address")
+		admin       netip.AddrPort
+		adminFlag   = fs.AddrPortVar(&admin, 0, "admin", netip.MustParseAddrPort("127.0.0.1:9090"), "admin address")
+		peerFlag, _ = fs.GetFlag("peer")
+	)
+
+	if want, have := "ADDR", listenFlag.GetPlaceholder(); want != have {
+		t.Errorf("listen placeholder: want %q, have %q", want, have)
+	}
+	if want, have := "ADDRPORT", adminFlag.GetPlaceholder(); want != have {
+		t.Errorf("admin placeholder: want %q, have %q", want, have)
+	}
+	if want, have := "0.0.0.0", listenFlag.GetDefault(); want != have {
+		t.Errorf("listen default: want %q, have %q", want, have)
+	}
+	if want, have := "", peerFlag.GetDefault(); want != have {
+		t.Errorf("peer default: want %q, have %q", want, have)
+	}
+
+	if err := fs.Parse([]string{"--listen", "10.0.0.1", "--admin=[::1]:8080"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := netip.MustParseAddr("10.0.0.1"), listen; want != have {
+		t.Errorf("listen: want %v, have %v", want, have)
+	}
+	if want, have := (netip.Addr{}), *peer; want != have {
+		t.Errorf("peer: want %v, have %v", want, have)
+	}
+	if want, have := netip.MustParseAddrPort("[::1]:8080"), admin; want != have {
+		t.Errorf("admin: want %v, have %v", want, have)
+	}
+
+	fs = ff.NewFlagSet(t.Name())
+	fs.AddrLong("listen", netip.Addr{}, "listen address")
+	err := fs.Parse([]string{"--listen=localhost"})
+	if want := `ParseAddr("localhost")`; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("want error containing %q, have %v", want, err)
+	}
+}
+
+func TestFlagSet_OptionalBool(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name string
+		args []string
+		want *bool
+	}{
+		{name: "unset", args: []string{}, want: nil},
+		{name: "true", args: []string{"--tls=true"}, want: &[]bool{true}[0]},
+		{name: "false", args: []string{"--tls", "false"}, want: &[]bool{false}[0]},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name())
+			tls := fs.OptionalBoolLong("tls", "use TLS")
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, *tls; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %v, have %v", want, have)
+			}
+		})
+	}
+
+	t.Run("explicit value required", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.OptionalBoolLong("tls", "use TLS")
+		if err := fs.Parse([]string{"--tls"}); err == nil {
+			t.Errorf("want error, have none")
+		}
+	})
+}
+
+func TestFlagSet_ByteSize(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	maxSize := fs.ByteSizeLong("max-size", 1<<20, "maximum size")
+	bufSize := fs.ByteSize('b', "buf-size", 0, "buffer size")
+
+	if err := fs.Parse([]string{"--max-size", "10MB", "-b512KiB"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := int64(10_000_000), *maxSize; want != have {
+		t.Errorf("max-size: want %d, have %d", want, have)
+	}
+	if want, have := int64(512<<10), *bufSize; want != have {
+		t.Errorf("buf-size: want %d, have %d", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := fs.Parse([]string{"--max-size=10XB"}); err == nil {
+		t.Errorf("want error, have none")
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  TestFlagSet_ByteSize
+
+		FLAGS
+		      --max-size SIZE   maximum size (default: 1MiB)
+		  -b, --buf-size SIZE   buffer size (default: 0)
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestFlagSet_DurationOrSentinel(t *testing.T) {
+	t.Parallel()
+
+	sentinels := map[string]time.Duration{"forever": ffval.MaxDuration}
+	fs := ff.NewFlagSet(t.Name())
+	timeout := fs.DurationOrSentinelLong("timeout", time.Second, sentinels, "request timeout")
+	retry := fs.DurationOrSentinel('r', "retry", 0, sentinels, "retry interval")
+
+	if err := fs.Parse([]string{"--timeout", "forever", "-r250ms"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := ffval.MaxDuration, *timeout; want != have {
+		t.Errorf("timeout: want %v, have %v", want, have)
+	}
+	if want, have := 250*time.Millisecond, *retry; want != have {
+		t.Errorf("retry: want %v, have %v", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if err := fs.Parse([]string{"--timeout=never"}); err == nil {
+		t.Errorf("want error, have none")
+	}
+}
+
+func TestFlagSet_File(t *testing.T) {
+	t.Parallel()
+
+	input := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(input, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	fs := ff.NewFlagSet(t.Name())
+	inputFile := fs.File('i', "input", "", "input file")
+
+	if err := fs.Parse([]string{"-i", input}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := input, *inputFile; want != have {
+		t.Errorf("input: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	err := fs.Parse([]string{"--input", input + ".missing"})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: want %v, have %v", os.ErrNotExist, err)
+	}
+}
+
+func TestFlagSet_Regexp(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	match := fs.RegexpLong("match", "", "filter pattern")
+	skip := fs.Regexp('s', "skip", "^_", "skip pattern")
+
+	if *match != nil {
+		t.Errorf("match: want nil default, have %v", *match)
+	}
+	if err := fs.Parse([]string{"--match", "^foo.*bar$"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if *match == nil || !(*match).MatchString("foo-bar") || (*match).MatchString("bar-foo") {
+		t.Errorf("match: unexpected %v", *match)
+	}
+	if want, have := "^_", (*skip).String(); want != have {
+		t.Errorf("skip: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	err := fs.Parse([]string{"--match=a(b"})
+	if err == nil {
+		t.Fatalf("want error, have none")
+	}
+	if want, have := "match", err.Error(); !strings.Contains(have, want) {
+		t.Errorf("error: want %q in %q", want, have)
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  TestFlagSet_Regexp
+
+		FLAGS
+		      --match REGEXP   filter pattern
+		  -s, --skip REGEXP    skip pattern (default: ^_)
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestFlagSet_URL(t *testing.T) {
+	t.Parallel()
+
+	fs := ff.NewFlagSet(t.Name())
+	endpoint := fs.URLLong("endpoint", "http://localhost:8080/api", "API endpoint")
+	proxy := fs.URL('p', "proxy", "", "proxy URL")
+
+	if want, have := "localhost:8080", endpoint.Host; want != have {
+		t.Errorf("endpoint default host: want %q, have %q", want, have)
+	}
+	if err := fs.Parse([]string{"--endpoint=https://example.com/v1?x=1", "-p", "socks5://127.0.0.1:1080"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := "https://example.com/v1?x=1", endpoint.String(); want != have {
+		t.Errorf("endpoint: want %q, have %q", want, have)
+	}
+	if want, have := "socks5", proxy.Scheme; want != have {
+		t.Errorf("proxy scheme: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := "", proxy.String(); want != have {
+		t.Errorf("proxy after Reset: want %q, have %q", want, have)
+	}
+	if err := fs.Parse([]string{"--proxy=http://[::1"}); err == nil {
+		t.Errorf("want error, have none")
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  TestFlagSet_URL
+
+		FLAGS
+		      --endpoint URL   API endpoint (default: http://localhost:8080/api)
+		  -p, --proxy URL      proxy URL
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+}
+
+func TestFlagSet_Counter(t *testing.T) {
 	t.Parallel()
 
-	fs := ff.NewFlagSet(t.Name())
-	helpflag := fs.BoolLong("help", "alternative help flag")
+	for _, test := range []struct {
+		name string
+		args []string
+		env  string
+		want int
+	}{
+		{name: "none", args: []string{}, want: 0},
+		{name: "single", args: []string{"-v"}, want: 1},
+		{name: "cluster", args: []string{"-vvv"}, want: 3},
+		{name: "repeated", args: []string{"-v", "--verbose", "-vv"}, want: 4},
+		{name: "explicit", args: []string{"--verbose=5"}, want: 1},
+		{name: "env", env: "3", want: 3},
+		{name: "args over env", args: []string{"-vv"}, env: "3", want: 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name())
+			verbose := fs.Counter('v', "verbose", "verbosity")
+			fs.BoolShort('q', "quiet")
 
-	// -h should still trigger ErrHelp.
-	if err := fs.Parse([]string{"-h"}); !errors.Is(err, ff.ErrHelp) {
-		t.Errorf("Parse(-h): want %v, have %v", ff.ErrHelp, err)
-	}
+			key := "TEST_COUNTER_" + strings.ToUpper(strings.ReplaceAll(test.name, " ", "_"))
+			defer func(old string) { os.Setenv(key+"_VERBOSE", old) }(os.Getenv(key + "_VERBOSE"))
+			os.Setenv(key+"_VERBOSE", test.env)
 
-	if err := fs.Reset(); err != nil {
-		t.Fatalf("Reset(): %v", err)
+			if err := ff.Parse(fs, test.args, ff.WithEnvVarPrefix(key)); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, *verbose; want != have {
+				t.Errorf("want %d, have %d", want, have)
+			}
+		})
 	}
 
-	// --help should not.
-	if err := fs.Parse([]string{"--help"}); err != nil {
-		t.Errorf("Parse(--help): error: %v", err)
-	}
+	t.Run("env with default", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		counter := &ffval.Counter{Default: 2}
+		fs.Value('v', "verbose", counter, "verbosity")
 
-	// It should set the flag we defined.
-	if want, have := true, *helpflag; want != have {
-		t.Errorf("h: want %v, have %v", want, have)
-	}
+		defer func(old string) { os.Setenv("TEST_COUNTER_DEFAULT_VERBOSE", old) }(os.Getenv("TEST_COUNTER_DEFAULT_VERBOSE"))
+		os.Setenv("TEST_COUNTER_DEFAULT_VERBOSE", "1")
+		if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_COUNTER_DEFAULT")); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := 1, counter.Get(); want != have {
+			t.Errorf("want %d, have %d", want, have)
+		}
+	})
+
+	t.Run("other sources with default", func(t *testing.T) {
+		for name, option := range map[string]ff.Option{
+			"snapshot":     ff.WithSnapshot([]byte(`{"verbose":1}`)),
+			"env var list": ff.WithEnvVarList("TEST_COUNTER_LIST"),
+		} {
+			defer func(old string) { os.Setenv("TEST_COUNTER_LIST", old) }(os.Getenv("TEST_COUNTER_LIST"))
+			os.Setenv("TEST_COUNTER_LIST", "--verbose=1")
+
+			fs := ff.NewFlagSet(t.Name())
+			counter := &ffval.Counter{Default: 2}
+			fs.Value('v', "verbose", counter, "verbosity")
+			if err := ff.Parse(fs, []string{}, option); err != nil {
+				t.Fatalf("%s: Parse: %v", name, err)
+			}
+			if want, have := 1, counter.Get(); want != have {
+				t.Errorf("%s: want %d, have %d", name, want, have)
+			}
+		}
+	})
 }
 
-func TestFlagSet_GetFlag(t *testing.T) {
+func TestFlagSet_IntRangeList(t *testing.T) {
 	t.Parallel()
 
 	fs := ff.NewFlagSet(t.Name())
-	fs.IntLong("foo", 0, "first flag")
-	fs.IntShort('f', 0, "second flag")
-
-	f1, ok := fs.GetFlag("foo")
-	if !ok {
-		t.Fatalf(`GetFlag("foo"): returned not-OK`)
+	ports := fs.IntRangeList('p', "ports", "ports to scan")
+	if err := fs.Parse([]string{"-p", "22", "--ports=8000-8002"}); err != nil {
+		t.Fatalf("Parse: %v", err)
 	}
-	if want, have := "first flag", f1.GetUsage(); want != have {
-		t.Errorf(`GetFlag("foo"): GetUsage: want %q, have %q`, want, have)
+	if want, have := []int{22, 8000, 8001, 8002}, *ports; !reflect.DeepEqual(want, have) {
+		t.Errorf("want %v, have %v", want, have)
 	}
 
-	f2, ok := fs.GetFlag("f")
-	if !ok {
-		t.Fatalf(`GetFlag("f"): returned not-OK`)
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
 	}
-	if want, have := "second flag", f2.GetUsage(); want != have {
-		t.Errorf(`GetFlag("f"): GetUsage: want %q, have %q`, want, have)
+	if err := fs.Parse([]string{"--ports=9-1"}); err == nil {
+		t.Errorf("inverted range: want error, have none")
 	}
 }
 
-func TestFlagSet_NoDefault(t *testing.T) {
+func TestFlagSet_ShortFlagClustering(t *testing.T) {
 	t.Parallel()
 
-	fs := ff.NewFlagSet(t.Name())
-	alpha, _ := fs.AddFlag(ff.FlagConfig{LongName: "alpha", Value: &ffval.Duration{}, Usage: "zero duration"})
-	beta, _ := fs.AddFlag(ff.FlagConfig{LongName: "beta", Value: &ffval.Duration{}, Usage: "zero duration with NoDefault", NoDefault: true})
+	for _, test := range []struct {
+		name       string
+		clustering bool
+		args       []string
+		wantA      bool
+		wantB      bool
+		wantC      bool
+		wantS      string
+		wantErr    error
+	}{
+		{name: "clustered -abc", clustering: true, args: []string{"-abc"}, wantA: true, wantB: true, wantC: true},
+		{name: "clustered -sfoo", clustering: true, args: []string{"-sfoo"}, wantS: "foo"},
+		{name: "clustered -asfoo", clustering: true, args: []string{"-asfoo"}, wantA: true, wantS: "foo"},
+		{name: "non-clustered -abc", clustering: false, args: []string{"-abc"}, wantErr: ff.ErrUnknownFlag},
+		{name: "non-clustered -sfoo", clustering: false, args: []string{"-sfoo"}, wantErr: ff.ErrUnknownFlag},
+		{name: "non-clustered -a -b -c", clustering: false, args: []string{"-a", "-b", "-c"}, wantA: true, wantB: true, wantC: true},
+		{name: "non-clustered -s foo", clustering: false, args: []string{"-s", "foo"}, wantS: "foo"},
+		{name: "non-clustered -s=foo", clustering: false, args: []string{"-s=foo", "-b=false"}, wantS: "foo"},
+		{name: "non-clustered -s", clustering: false, args: []string{"-s"}, wantErr: errors.New("any")},
+		{name: "non-clustered -h", clustering: false, args: []string{"-h"}, wantErr: ff.ErrHelp},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name()).SetShortFlagClustering(test.clustering)
+			a := fs.BoolShort('a', "a bool")
+			b := fs.BoolShort('b', "b bool")
+			c := fs.BoolShort('c', "c bool")
+			s := fs.StringShort('s', "", "s string")
 
-	if want, have := "0s", alpha.GetDefault(); want != have {
-		t.Errorf("alpha: default: want %q, have %q", want, have)
-	}
+			err := fs.Parse(test.args)
+			switch {
+			case test.wantErr == nil && err != nil:
+				t.Fatalf("Parse: %v", err)
+			case test.wantErr != nil && err == nil:
+				t.Fatalf("Parse: want error, have none")
+			case test.wantErr != nil && test.wantErr.Error() != "any" && !errors.Is(err, test.wantErr):
+				t.Fatalf("Parse: want %v, have %v", test.wantErr, err)
+			case test.wantErr != nil:
+				return
+			}
 
-	if want, have := "", beta.GetDefault(); want != have {
-		t.Errorf("beta: default: want %q, have %q", want, have)
+			if want, have := test.wantA, *a; want != have {
+				t.Errorf("a: want %v, have %v", want, have)
+			}
+			if want, have := test.wantB, *b; want != have {
+				t.Errorf("b: want %v, have %v", want, have)
+			}
+			if want, have := test.wantC, *c; want != have {
+				t.Errorf("c: want %v, have %v", want, have)
+			}
+			if want, have := test.wantS, *s; want != have {
+				t.Errorf("s: want %q, have %q", want, have)
+			}
+		})
 	}
 }
 
-func TestFlagSet_NoPlaceholder(t *testing.T) {
+func TestFlagSet_MultiTerminator(t *testing.T) {
 	t.Parallel()
 
-	fs := ff.NewFlagSet(t.Name())
-	alpha, _ := fs.AddFlag(ff.FlagConfig{LongName: "alpha", Value: &ffval.Bool{}, Usage: "alpha", NoPlaceholder: true})
-	beta, _ := fs.AddFlag(ff.FlagConfig{LongName: "beta", Value: ffval.NewValueDefault(new(bool), true), Usage: "beta", NoPlaceholder: true})
-	delta, _ := fs.AddFlag(ff.FlagConfig{LongName: "delta", Value: ffval.NewValueDefault(new(bool), true), Usage: "delta `D` flag", NoPlaceholder: true})
-	kappa, _ := fs.AddFlag(ff.FlagConfig{LongName: "kappa", Value: ffval.NewValue(new(bool)), Usage: "kappa `K` flag", NoPlaceholder: true})
+	for _, test := range []struct {
+		name         string
+		args         []string
+		wantFoo      string
+		wantArgs     []string
+		wantSegments [][]string
+	}{
+		{
+			name:         "no terminator",
+			args:         []string{"--foo=1", "a", "b"},
+			wantFoo:      "1",
+			wantArgs:     []string{"a", "b"},
+			wantSegments: [][]string{{"a", "b"}},
+		},
+		{
+			name:         "two segments",
+			args:         []string{"--foo=1", "--", "inner", "--foo=2", "--", "x", "y"},
+			wantFoo:      "1",
+			wantArgs:     []string{"inner", "--foo=2"},
+			wantSegments: [][]string{{"inner", "--foo=2"}, {"x", "y"}},
+		},
+		{
+			name:         "three segments",
+			args:         []string{"--foo=1", "--", "a", "--", "-b", "--", "c"},
+			wantFoo:      "1",
+			wantArgs:     []string{"a"},
+			wantSegments: [][]string{{"a"}, {"-b"}, {"c"}},
+		},
+		{
+			name:         "empty segments",
+			args:         []string{"--", "--", "--"},
+			wantFoo:      "def",
+			wantArgs:     []string{},
+			wantSegments: [][]string{{}, {}, {}},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name()).SetMultiTerminator(true)
+			foo := fs.StringLong("foo", "def", "foo string")
 
-	for _, f := range []ff.Flag{alpha, beta, delta, kappa} {
-		if want, have := "", f.GetPlaceholder(); want != have {
-			t.Errorf("%s: want %q, have %q", ffhelp.WrapFlag(f), want, have)
-		}
+			if err := fs.Parse(test.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.wantFoo, *foo; want != have {
+				t.Errorf("foo: want %q, have %q", want, have)
+			}
+			if want, have := test.wantArgs, fs.GetArgs(); !reflect.DeepEqual(want, have) {
+				t.Errorf("GetArgs: want %q, have %q", want, have)
+			}
+			if want, have := test.wantSegments, fs.GetArgSegments(); !reflect.DeepEqual(want, have) {
+				t.Errorf("GetArgSegments: want %q, have %q", want, have)
+			}
+		})
 	}
+
+	t.Run("disabled", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		if err := fs.Parse([]string{"--", "a", "--", "b"}); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := []string{"a", "--", "b"}, fs.GetArgs(); !reflect.DeepEqual(want, have) {
+			t.Errorf("GetArgs: want %q, have %q", want, have)
+		}
+		if want, have := [][]string{{"a", "--", "b"}}, fs.GetArgSegments(); !reflect.DeepEqual(want, have) {
+			t.Errorf("GetArgSegments: want %q, have %q", want, have)
+		}
+	})
 }
 
-func TestFlagSet_Get(t *testing.T) {
+func TestFlagSet_MarshalJSON(t *testing.T) {
 	t.Parallel()
 
-	fs := ff.NewFlagSet(t.Name())
-	f, err := fs.AddFlag(ff.FlagConfig{
-		LongName:    "alpha",
-		Value:       new(ffval.Int),
-		Placeholder: "X",
-	})
-	if err != nil {
-		t.Fatal(err)
+	var flags struct {
+		Port  int    `ff:"long=port"`
+		Debug bool   `ff:"long=debug"`
+		Level string `ff:"short=l"`
 	}
 
-	if want, have := "0", f.GetDefault(); want != have {
-		t.Errorf("GetDefault: want %q, have %q", want, have)
+	parent := ff.NewFlagSet("parent")
+	parent.StringLong("name", "default", "name string")
+
+	fs := ff.NewFlagSetFrom(t.Name(), &flags).SetParent(parent)
+	fs.BoolLong("verbose", "verbose bool")
+	fs.StringListLong("tag", "tag strings")
+	fs.Float64Long("ratio", 0.5, "ratio float")
+	fs.DurationLong("timeout", time.Second, "timeout duration")
+	fs.Value('v', "", ffval.NewCount(new(int)), "verbosity count")
+
+	if err := fs.Parse([]string{"--port=8080", "--debug", "-l", "info", "--verbose", "--tag=a", "--tag=b", "-vv"}); err != nil {
+		t.Fatalf("Parse: %v", err)
 	}
 
-	if want, have := "X", f.GetPlaceholder(); want != have {
-		t.Errorf("GetPlaceholder: want %q, have %q", want, have)
+	buf, err := json.Marshal(fs)
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+
+	want := `{"debug":true,"l":"info","name":"default","port":8080,"ratio":0.5,"tag":["a","b"],"timeout":"1s","v":2,"verbose":true}`
+	if have := string(buf); want != have {
+		t.Errorf("\nwant %s\nhave %s", want, have)
 	}
 }
 
@@ -526,6 +2042,275 @@ func TestFlagSet_StructIgnoreReset(t *testing.T) {
 	}
 }
 
+func TestFlagSet_StructNoEnv(t *testing.T) {
+	t.Parallel()
+
+	var flags struct {
+		Host     string `ff:"long=host,     usage=hostname"`
+		Port     int    `ff:"long=port,     usage=port number"`
+		Password string `ff:"long=password, usage=secret password, noenv"`
+	}
+
+	fs := ff.NewFlagSetFrom(t.Name(), &flags)
+
+	for k, v := range map[string]string{
+		"TEST_NOENV_HOST":     "localhost",
+		"TEST_NOENV_PORT":     "8080",
+		"TEST_NOENV_PASSWORD": "hunter2",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_NOENV")); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if want, have := "localhost", flags.Host; want != have {
+		t.Errorf("host: want %q, have %q", want, have)
+	}
+	if want, have := 8080, flags.Port; want != have {
+		t.Errorf("port: want %d, have %d", want, have)
+	}
+	if want, have := "", flags.Password; want != have {
+		t.Errorf("password: want %q, have %q", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	if err := ff.Parse(fs, []string{"--password=swordfish"}, ff.WithEnvVarPrefix("TEST_NOENV")); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if want, have := "swordfish", flags.Password; want != have {
+		t.Errorf("password: want %q, have %q", want, have)
+	}
+
+	var invalid struct {
+		Secret string `ff:"long=secret, noenv=true"`
+	}
+	if err := ff.NewFlagSet(t.Name()).AddStruct(&invalid); err == nil {
+		t.Errorf("noenv with value: want error, have none")
+	}
+}
+
+func TestFlagSet_AddStructPrefixed(t *testing.T) {
+	t.Parallel()
+
+	type component struct {
+		Alpha string `ff:"short=a, long=alpha, usage=alpha string"`
+		Count int    `ff:"short=c, long=count, usage=count int, default=1"`
+	}
+
+	var comp1, comp2 component
+	fs := ff.NewFlagSet(t.Name())
+	if err := fs.AddStructPrefixed("comp", &comp1); err != nil {
+		t.Fatalf("AddStructPrefixed(comp): %v", err)
+	}
+	if err := fs.AddStructPrefixed("other", &comp2); err != nil {
+		t.Fatalf("AddStructPrefixed(other): %v", err)
+	}
+
+	f, ok := fs.GetFlag("comp.alpha")
+	if !ok {
+		t.Fatalf("comp.alpha: flag not found")
+	}
+	if short, ok := f.GetShortName(); ok {
+		t.Errorf("comp.alpha: want no short name, have %q", short)
+	}
+
+	if err := fs.Parse([]string{"--comp.alpha=x", "--other.alpha=y", "--other.count=2"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := (component{Alpha: "x", Count: 1}), comp1; want != have {
+		t.Errorf("comp: want %+v, have %+v", want, have)
+	}
+	if want, have := (component{Alpha: "y", Count: 2}), comp2; want != have {
+		t.Errorf("other: want %+v, have %+v", want, have)
+	}
+
+	if err := ff.NewFlagSet(t.Name()).AddStructPrefixed("", &comp1); err == nil {
+		t.Errorf("empty prefix: want error, have none")
+	}
+
+	var shortOnly struct {
+		Verbose bool `ff:"short=v, usage=verbose"`
+	}
+	if err := ff.NewFlagSet(t.Name()).AddStructPrefixed("comp", &shortOnly); err == nil {
+		t.Errorf("short only: want error, have none")
+	}
+}
+
+func TestFlagSet_StructSlices(t *testing.T) {
+	t.Parallel()
+
+	var flags struct {
+		Hosts []string        `ff:"long=host,  usage=hostname"`
+		Ports []int           `ff:"long=port,  usage=port number, default=80"`
+		Waits []time.Duration `ff:"short=w,    usage=wait durations"`
+	}
+
+	fs := ff.NewFlagSet(t.Name())
+	if err := fs.AddStruct(&flags); err != nil {
+		t.Fatalf("AddStruct: %v", err)
+	}
+
+	if want, have := []int{80}, flags.Ports; !reflect.DeepEqual(want, have) {
+		t.Errorf("ports before parse: want %v, have %v", want, have)
+	}
+
+	args := []string{"--host=a", "--host", "b", "--port=8080", "--port=9090", "-w1s", "-w", "2s"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if want, have := []string{"a", "b"}, flags.Hosts; !reflect.DeepEqual(want, have) {
+		t.Errorf("hosts: want %q, have %q", want, have)
+	}
+	if want, have := []int{8080, 9090}, flags.Ports; !reflect.DeepEqual(want, have) {
+		t.Errorf("ports: want %v, have %v", want, have)
+	}
+	if want, have := []time.Duration{time.Second, 2 * time.Second}, flags.Waits; !reflect.DeepEqual(want, have) {
+		t.Errorf("waits: want %v, have %v", want, have)
+	}
+
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := []int{80}, flags.Ports; !reflect.DeepEqual(want, have) {
+		t.Errorf("ports after reset: want %v, have %v", want, have)
+	}
+
+	var invalid struct {
+		Things []struct{} `ff:"long=thing"`
+	}
+	if err := ff.NewFlagSet(t.Name()).AddStruct(&invalid); err == nil {
+		t.Errorf("unsupported slice: want error, have none")
+	}
+}
+
+func TestFlagSet_StructTime(t *testing.T) {
+	t.Parallel()
+
+	var flags struct {
+		Since time.Time `ff:"long: since, layout: 2006-01-02, default: 2020-06-01"`
+		Until time.Time `ff:"long: until"`
+	}
+
+	fs := ff.NewFlagSet(t.Name())
+	if err := fs.AddStruct(&flags); err != nil {
+		t.Fatalf("AddStruct: %v", err)
+	}
+
+	if want, have := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), flags.Since; !want.Equal(have) {
+		t.Errorf("since default: want %v, have %v", want, have)
+	}
+	if f, _ := fs.GetFlag("since"); f.IsSet() {
+		t.Errorf("since: want unset after default, have set")
+	}
+
+	if err := fs.Parse([]string{"--since", "2024-01-02", "--until=2024-03-04T05:06:07Z"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), flags.Since; !want.Equal(have) {
+		t.Errorf("since: want %v, have %v", want, have)
+	}
+	if want, have := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC), flags.Until; !want.Equal(have) {
+		t.Errorf("until: want %v, have %v", want, have)
+	}
+
+	var invalid struct {
+		Name string `ff:"long: name, layout: 2006-01-02"`
+	}
+	if err := ff.NewFlagSet(t.Name()).AddStruct(&invalid); err == nil {
+		t.Errorf("layout on string field: want error, have none")
+	}
+}
+
+func TestFlagSet_ApplyDefaults(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Host    string        `ff:"long=host,    usage=listen host"`
+		Port    int           `ff:"short=p,      usage=listen port"`
+		Timeout time.Duration `ff:"long=timeout, usage=request timeout"`
+		Tags    []string      `ff:"long=tag,     usage=tags"`
+		Debug   bool          `ff:"long=debug,   usage=debug mode"`
+	}
+
+	var cfg config
+	fs := ff.NewFlagSet(t.Name())
+	if err := fs.AddStruct(&cfg); err != nil {
+		t.Fatalf("AddStruct: %v", err)
+	}
+	level := fs.StringLong("level", "info", "log level")
+
+	defaults := config{
+		Host:    "example.com",
+		Port:    8080,
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b"},
+	}
+	if err := fs.ApplyDefaults(&defaults); err != nil {
+		t.Fatalf("ApplyDefaults: %v", err)
+	}
+	if err := fs.ApplyDefaults(&struct {
+		Level string `ff:"long=level"`
+	}{"warn"}); err != nil {
+		t.Fatalf("ApplyDefaults: %v", err)
+	}
+
+	if want, have := defaults, cfg; !reflect.DeepEqual(want, have) {
+		t.Errorf("after ApplyDefaults: want %+v, have %+v", want, have)
+	}
+	if want, have := "warn", *level; want != have {
+		t.Errorf("level: want %q, have %q", want, have)
+	}
+
+	defaults.Tags[0] = "modified"
+	if want, have := "a", cfg.Tags[0]; want != have {
+		t.Errorf("tags aliased: want %q, have %q", want, have)
+	}
+
+	want := fftest.UnindentString(`
+		NAME
+		  TestFlagSet_ApplyDefaults
+
+		FLAGS
+		      --host STRING        listen host (default: example.com)
+		  -p INT                   listen port (default: 8080)
+		      --timeout DURATION   request timeout (default: 5s)
+		      --tag STRING         tags (default: a, b)
+		      --debug              debug mode
+		      --level STRING       log level (default: warn)
+	`)
+	have := fftest.UnindentString(ffhelp.Flags(fs).String())
+	if want != have {
+		t.Error(fftest.DiffString(want, have))
+	}
+
+	if err := fs.Parse([]string{"--host=other", "-p", "9", "--timeout=1s", "--tag=c", "--debug", "--level=error"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := fs.Reset(); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if want, have := (config{Host: "example.com", Port: 8080, Timeout: 5 * time.Second, Tags: []string{"a", "b"}}), cfg; !reflect.DeepEqual(want, have) {
+		t.Errorf("after Reset: want %+v, have %+v", want, have)
+	}
+	if want, have := "warn", *level; want != have {
+		t.Errorf("level after Reset: want %q, have %q", want, have)
+	}
+
+	if err := fs.ApplyDefaults(&struct {
+		Missing string `ff:"long=missing"`
+	}{}); !errors.Is(err, ff.ErrUnknownFlag) {
+		t.Errorf("unknown flag: want %v, have %v", ff.ErrUnknownFlag, err)
+	}
+}
+
 func TestFlagSet_StructEmbedded(t *testing.T) {
 	t.Parallel()
 
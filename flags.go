@@ -93,6 +93,15 @@ type Flag interface {
 
 	// IsSet should return true if SetValue has been called successfully.
 	IsSet() bool
+
+	// IsBoolFlag should return true if the flag is a boolean flag, which
+	// doesn't require an explicit value, e.g. --verbose rather than
+	// --verbose=true. Help text typically omits placeholders for such flags.
+	IsBoolFlag() bool
+
+	// IsHidden should return true if the flag should be omitted from help
+	// text. Hidden flags are otherwise parsed like any other flag.
+	IsHidden() bool
 }
 
 // Resetter may optionally be implemented by [Flags].
@@ -104,7 +113,82 @@ type Resetter interface {
 	Reset() error
 }
 
+// FlagGroup is a set of flags defined directly in the same owning flag set.
+type FlagGroup struct {
+	// Name of the owning flag set.
+	Name string
+
+	// Flags defined directly in the owning flag set, in the order they were
+	// walked. Flags inherited from parent flag sets are not included.
+	Flags []Flag
+}
+
+// FlagsByOwner groups every flag known to fs according to the flag set in which
+// each flag is actually defined, as reported by [Flag.GetFlags]. Groups are
+// returned in walk order, which for a [FlagSet] means the flag set itself,
+// followed by each parent in turn. This is the basis for help text which shows
+// flags per command, e.g. in a hierarchy of subcommands.
+func FlagsByOwner(fs Flags) []FlagGroup {
+	var (
+		owners []Flags
+		groups []FlagGroup
+	)
+	fs.WalkFlags(func(f Flag) error {
+		owner := f.GetFlags()
+		for i := range owners {
+			if owners[i] == owner {
+				groups[i].Flags = append(groups[i].Flags, f)
+				return nil
+			}
+		}
+		owners = append(owners, owner)
+		groups = append(groups, FlagGroup{Name: owner.GetName(), Flags: []Flag{f}})
+		return nil
+	})
+	return groups
+}
+
 var (
 	_ flag.Value = (*ffval.Value[any])(nil)
 	_ Resetter   = (*ffval.Value[any])(nil)
 )
+
+// GetFlagByEnvKey returns the first flag known to fs with a name that maps to
+// the given environment variable key, e.g. FOO_BAR for --foo-bar, when parsing
+// with [WithEnvVarPrefix] and the given prefix. Every name of a flag is
+// considered, including its short name, which is consistent with the keys
+// consulted by [Parse] when reading the environment. An empty prefix behaves
+// like [WithEnvVars].
+func GetFlagByEnvKey(fs Flags, key, envVarPrefix string) (Flag, bool) {
+	var found Flag
+	fs.WalkFlags(func(f Flag) error {
+		if found != nil {
+			return nil
+		}
+		for _, k := range getEnvVarKeys(f, envVarPrefix) {
+			if k == key {
+				found = f
+				break
+			}
+		}
+		return nil
+	})
+	return found, found != nil
+}
+
+// GetAny returns the current value of the flag as a typed Go value, e.g. an int
+// for an int flag, or a []string for a string list flag. This allows generic
+// tooling to read flag values without parsing the strings returned by
+// [Flag.GetValue].
+//
+// Flags may provide typed values by implementing `GetAny() any`. Flags created
+// by [FlagSet] do so by calling the Get method of their underlying flag value,
+// which is the case for [flag.Getter], and every value in package ffval. If the
+// flag doesn't implement GetAny, or its flag value doesn't have a Get method,
+// GetAny falls back to the string returned by GetValue.
+func GetAny(f Flag) any {
+	if g, ok := f.(interface{ GetAny() any }); ok {
+		return g.GetAny()
+	}
+	return f.GetValue()
+}
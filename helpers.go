@@ -61,3 +61,48 @@ func getNameString(f Flag) string {
 	}
 	return strings.Join(names, ", ")
 }
+
+// defaultSuggestDistance returns the maximum edit distance between an unknown
+// name and a candidate for the candidate to be suggested. It allows roughly one
+// edit per three characters, within reason.
+func defaultSuggestDistance(name string) int {
+	maxDist := len(name) / 3
+	switch {
+	case maxDist < 1:
+		maxDist = 1
+	case maxDist > 3:
+		maxDist = 3
+	}
+	return maxDist
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	var (
+		ra   = []rune(a)
+		rb   = []rune(b)
+		prev = make([]int, len(rb)+1)
+		curr = make([]int, len(rb)+1)
+	)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j] + 1 // deletion
+			if n := curr[j-1] + 1; n < curr[j] {
+				curr[j] = n // insertion
+			}
+			if n := prev[j-1] + cost; n < curr[j] {
+				curr[j] = n // substitution
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 )
 
 // TraverseMap recursively walks the given map, calling set for each value. If the
 // value is a slice, set is called for each element of the slice. The keys of
-// nested maps are joined with the given delimiter.
+// nested maps are joined with the given delimiter. Times are formatted as per
+// RFC 3339, and other values which implement fmt.Stringer use their String
+// method.
 func TraverseMap(m map[string]any, delimiter string, set func(name, value string) error) error {
 	return traverseMap("", m, delimiter, set)
 }
@@ -31,6 +34,10 @@ func traverseMap(key string, val any, delimiter string, set func(name, value str
 		return set(key, strconv.FormatBool(v))
 	case nil:
 		return set(key, "")
+	case time.Time:
+		return set(key, v.Format(time.RFC3339Nano))
+	case fmt.Stringer: // e.g. TOML local dates and times
+		return set(key, v.String())
 	case []any:
 		for _, v := range v {
 			if err := traverseMap(key, v, delimiter, set); err != nil {
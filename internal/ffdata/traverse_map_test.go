@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/peterbourgon/ff/v4/internal/ffdata"
 )
@@ -95,6 +96,19 @@ func TestTraverseMap(t *testing.T) {
 				"m-m2-i=123": {},
 			},
 		},
+		{
+			Name: "times and stringers",
+			M: map[string]any{
+				"t":   time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+				"d":   5 * time.Second,
+				"str": stringer("custom"),
+			},
+			Want: map[string]struct{}{
+				"t=2024-01-02T15:04:05Z": {},
+				"d=5s":                   {},
+				"str=custom":             {},
+			},
+		},
 		{
 			Name: "nested map[any]any",
 			M: map[string]any{
@@ -133,3 +147,7 @@ func TestTraverseMap(t *testing.T) {
 		})
 	}
 }
+
+type stringer string
+
+func (s stringer) String() string { return string(s) }
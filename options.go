@@ -3,6 +3,8 @@ package ff
 import (
 	"io"
 	iofs "io/fs"
+	"os"
+	"time"
 )
 
 // Option controls some aspect of parsing behavior.
@@ -14,12 +16,45 @@ type ParseContext struct {
 	envVarPrefix  string
 	envVarSplit   string
 
-	configFileName             string
+	envVarLowercase      bool
+	envVarListName       string
+	envVarPrefixFromName bool
+
+	envVarIgnoreInvalid       bool
+	envVarIgnoreInvalidWriter io.Writer
+
+	configFileNames            []string
+	xdgAppName                 string
+	xdgGetenv                  func(string) string
 	configFlagName             string
+	configFileVia              *string
+	configEnvVarKey            string
 	configParseFunc            ConfigFileParseFunc
+	configParser               ConfigFileParser
 	configOpenFunc             func(string) (iofs.File, error)
 	configAllowMissingFile     bool
 	configIgnoreUndefinedFlags bool
+	configMatchShortNames      bool
+	configFileSection          string
+	configFileUsed             *string
+	configBeforeEnv            bool
+	configTrimSpace            bool
+	configValueExec            bool
+	configValueExecFunc        func(command string) (string, error)
+
+	snapshot []byte
+
+	booleanPresenceTrue bool
+	flagTemplates       bool
+	enumCaseInsensitive bool
+	valueTransform      func(f Flag, rawValue string) (string, error)
+	decimalSeparator    rune
+	afterParse          []func(fs Flags) error
+	requiredHelp        bool
+	stageTimings        *ParseTimings
+
+	deprecationWriter    io.Writer
+	deprecationWriterSet bool
 }
 
 // ConfigFileParseFunc is a function that consumes the provided reader as a config
@@ -27,14 +62,61 @@ type ParseContext struct {
 // discovers.
 type ConfigFileParseFunc func(r io.Reader, set func(name, value string) error) error
 
+// ConfigFileParser is like [ConfigFileParseFunc], but also receives information
+// about the active parse. This can be useful for parsers which need to know
+// e.g. the env var prefix, or the defined flags, in order to produce correct
+// names. Use [WithConfigFileParserInfo] to provide a ConfigFileParser.
+type ConfigFileParser interface {
+	Parse(r io.Reader, set func(name, value string) error, info ParseInfo) error
+}
+
+// ParseInfo is a read-only view of the parse, provided to a [ConfigFileParser].
+type ParseInfo struct {
+	// EnvVarPrefix is the prefix provided via [WithEnvVarPrefix], if any.
+	EnvVarPrefix string
+
+	// Flags is the flag set being parsed.
+	Flags Flags
+
+	// BooleanPresenceTrue is true if [WithBooleanPresenceTrue] was provided.
+	BooleanPresenceTrue bool
+}
+
+// ParseTimings records how long each stage of a parse took. Use
+// [WithStageTimings] to populate a ParseTimings.
+type ParseTimings struct {
+	// ArgsDuration is the time spent parsing commandline args, including any
+	// args provided via [WithEnvVarList].
+	ArgsDuration time.Duration
+
+	// EnvDuration is the time spent setting flags from environment variables.
+	EnvDuration time.Duration
+
+	// ConfigDuration is the time spent locating, reading, and parsing config
+	// files.
+	ConfigDuration time.Duration
+}
+
 // WithConfigFile tells [Parse] to read the provided filename as a config file.
 // Requires [WithConfigFileParser], and overrides [WithConfigFileFlag].
 //
 // Because config files should generally be user-specifiable, this option should
 // rarely be used; prefer [WithConfigFileFlag].
 func WithConfigFile(filename string) Option {
+	return WithConfigFiles(filename)
+}
+
+// WithConfigFiles is like [WithConfigFile], but reads multiple config files, in
+// order. Later files have higher priority than earlier files, so that e.g. a
+// system-wide config file can be overridden by a per-user config file, given
+// in that order. All config files have lower priority than args, and, by
+// default, env vars. Each file which doesn't exist is an error, unless
+// [WithConfigAllowMissingFile] is provided, in which case it's skipped.
+//
+// WithConfigFile(filename) is equivalent to WithConfigFiles(filename).
+func WithConfigFiles(filenames ...string) Option {
 	return func(pc *ParseContext) {
-		pc.configFileName = filename
+		pc.configFileNames = filenames
 	}
 }
 
@@ -43,13 +125,73 @@ func WithConfigFile(filename string) Option {
 // Requires [WithConfigFileParser], and is overridden by [WithConfigFile].
 //
 // To specify a default config file, provide it as the default value of the
-// corresponding flag.
+// corresponding flag. If the flag is a list of strings, e.g. [FlagSet.StringList],
+// each value is read as a config file, as per [WithConfigFiles].
 func WithConfigFileFlag(flagname string) Option {
 	return func(pc *ParseContext) {
 		pc.configFlagName = flagname
 	}
 }
 
+// WithConfigFileVia tells [Parse] to read the config file path from the given
+// pointer, typically returned by a flag definition like [FlagSet.StringLong].
+// The pointer is dereferenced after the commandline args have been parsed, so
+// it reflects the value given by the user, or the default value of the flag.
+// This is like [WithConfigFileFlag], but doesn't require looking up the flag by
+// name.
+//
+// Requires [WithConfigFileParser]. It's overridden by [WithConfigFile], and by
+// a non-empty value from [WithConfigFileFlag]. If the pointer is nil, or the
+// path it points to is empty, no config file is parsed. A missing file is an
+// error, unless [WithConfigAllowMissingFile] is provided.
+func WithConfigFileVia(p *string) Option {
+	return func(pc *ParseContext) {
+		pc.configFileVia = p
+	}
+}
+
+// WithConfigFileEnvVar tells [Parse] to read the config file path from the
+// environment variable with the given key, without requiring a corresponding
+// flag. The key is transformed like a flag name, and includes the prefix from
+// [WithEnvVarPrefix], if any; for example, the key "config" with the prefix
+// "MYPROG" reads MYPROG_CONFIG. The environment variable is consulted even if
+// [WithEnvVars] wasn't provided.
+//
+// Requires [WithConfigFileParser]. It's overridden by [WithConfigFile], and by
+// a non-empty value from [WithConfigFileFlag]. If the environment variable is
+// unset or empty, no config file is parsed.
+func WithConfigFileEnvVar(key string) Option {
+	return func(pc *ParseContext) {
+		pc.configEnvVarKey = key
+	}
+}
+
+// WithXDGConfig tells [Parse] to read a config file from the standard XDG
+// location for the given app name, using the given parser, which is equivalent
+// to [WithConfigFileParser]. The candidate locations are, in order,
+// $XDG_CONFIG_HOME/<appName>/config and $HOME/.config/<appName>/config, and the
+// first one that exists is used. If none exist, the parse fails, unless
+// [WithConfigAllowMissingFile] is provided.
+//
+// XDG config locations are only consulted if no config file was specified by
+// any other option, like [WithConfigFile] or [WithConfigFileFlag]. Environment
+// variables are read via [os.Getenv], unless [WithXDGGetenv] is provided.
+func WithXDGConfig(appName string, parser ConfigFileParseFunc) Option {
+	return func(pc *ParseContext) {
+		pc.xdgAppName = appName
+		pc.configParseFunc = parser
+	}
+}
+
+// WithXDGGetenv tells [Parse] to use the given function, rather than
+// [os.Getenv], to read the environment variables which determine the XDG config
+// locations used by [WithXDGConfig]. It's mostly useful for tests.
+func WithXDGGetenv(getenv func(key string) string) Option {
+	return func(pc *ParseContext) {
+		pc.xdgGetenv = getenv
+	}
+}
+
 // WithConfigFileParser tells [Parse] how to interpret a config file. This
 // option must be explicitly provided in order to parse config files.
 //
@@ -60,6 +202,47 @@ func WithConfigFileParser(pf ConfigFileParseFunc) Option {
 	}
 }
 
+// WithConfigFileSection tells [Parse] to only consider config file keys under
+// the given top-level section, and to ignore all other keys. This allows a
+// single config file to be shared by multiple programs, each with its own
+// section.
+//
+// Sections are identified by key prefix: the section name followed by a period.
+// That's how parsers like ffjson, fftoml, and ffyaml represent nested tables
+// with their default delimiter, so for example, the TOML key `port` in the table
+// `[mytool]` is provided as `mytool.port`, and would set the flag `port` under
+// the section "mytool". Keys in nested tables under the section keep their
+// remaining prefix, e.g. `mytool.http.port` sets the flag `http.port`.
+//
+// By default, all keys in the config file are considered.
+func WithConfigFileSection(name string) Option {
+	return func(pc *ParseContext) {
+		pc.configFileSection = name
+	}
+}
+
+// WithConfigFileParserInfo is like [WithConfigFileParser], but takes a
+// [ConfigFileParser], which receives information about the active parse. It
+// overrides WithConfigFileParser.
+func WithConfigFileParserInfo(p ConfigFileParser) Option {
+	return func(pc *ParseContext) {
+		pc.configParser = p
+	}
+}
+
+// WithConfigFileUsed tells [Parse] to store the path of the config file that
+// was successfully opened and parsed in the given pointer. If no config file
+// was parsed, for example because none was specified, or because the file was
+// missing and [WithConfigAllowMissingFile] was provided, the empty string is
+// stored. If multiple config files were parsed, e.g. via [WithConfigFiles], the
+// path of the one with the highest priority is stored. This can be useful for
+// diagnostic messages.
+func WithConfigFileUsed(path *string) Option {
+	return func(pc *ParseContext) {
+		pc.configFileUsed = path
+	}
+}
+
 // WithConfigAllowMissingFile tells [Parse] to ignore config files that are
 // specified but don't exist.
 //
@@ -81,6 +264,103 @@ func WithConfigIgnoreUndefinedFlags() Option {
 	}
 }
 
+// WithConfigMatchShortNames tells [Parse] to allow single-character keys in
+// config files to match the short names of flags. For example, with this
+// option, the config file key `v` would match a flag defined with the short
+// name 'v' and the long name "verbose".
+//
+// By default, config file keys only match long names, or the env var form of
+// any flag name. This avoids surprises where a single-character key in a config
+// file unexpectedly sets a flag which only has that short name.
+func WithConfigMatchShortNames() Option {
+	return func(pc *ParseContext) {
+		pc.configMatchShortNames = true
+	}
+}
+
+// WithConfigBeforeEnv tells [Parse] to give config files priority over
+// environment variables. Commandline args always have the highest priority.
+//
+// By default, the priority order is args, then env vars, then config files. A
+// flag set by an env var can't be overridden by a config file. With this
+// option, the priority order is args, then config files, then env vars. A flag
+// set by a config file can't be overridden by an env var.
+//
+// Note that the config file is parsed before env vars are considered, so when
+// combined with [WithConfigFileFlag], the config file flag can't be set by an
+// env var.
+func WithConfigBeforeEnv() Option {
+	return func(pc *ParseContext) {
+		pc.configBeforeEnv = true
+	}
+}
+
+// WithConfigTrimSpace tells [Parse] to trim leading and trailing whitespace
+// from every config file value, via [strings.TrimSpace], before setting the
+// corresponding flag. This applies uniformly to any config file parser.
+//
+// Trimming happens on the value as it's provided by the parser, i.e. after any
+// decoding. For example, the JSON value "  foo  " is decoded by the JSON parser
+// to `  foo  ` and then trimmed to `foo`. But [PlainParser] doesn't decode
+// quoted values, so the plain value "  foo  " is passed through with its quotes
+// and inner whitespace intact.
+//
+// By default, config file values are provided to flags as-is.
+func WithConfigTrimSpace() Option {
+	return func(pc *ParseContext) {
+		pc.configTrimSpace = true
+	}
+}
+
+// WithConfigValueExec tells [Parse] to treat config file values beginning with
+// an exclamation point ! as commands to run. The command is everything after
+// the !, and the output of the command, trimmed of leading and trailing
+// whitespace, is used as the flag value. This can be useful for dynamic values
+// like secrets, e.g. `password !op read op://vault/item/password`. To provide a
+// literal value beginning with !, use two exclamation points, e.g. !!foo sets
+// the value `!foo`.
+//
+// Commands are run by [WithConfigValueExecFunc], if provided, or else via
+// `sh -c` on the host. Running arbitrary commands from a config file is
+// powerful, but also dangerous. Only use this option with trusted config files.
+//
+// By default, config file values are always used literally.
+func WithConfigValueExec() Option {
+	return func(pc *ParseContext) {
+		pc.configValueExec = true
+	}
+}
+
+// WithConfigValueExecFunc tells [Parse] how to run commands specified by config
+// file values when [WithConfigValueExec] is provided. The function is called
+// with the command string, and should return the output of that command.
+//
+// By default, commands are run via `sh -c` on the host.
+func WithConfigValueExecFunc(fn func(command string) (string, error)) Option {
+	return func(pc *ParseContext) {
+		pc.configValueExecFunc = fn
+	}
+}
+
+// WithSnapshot tells [Parse] to set flags from data, which should be a JSON
+// object as produced by [FlagSet.MarshalJSON]. Each key is matched to a flag by
+// its long name, or its short name if the key is a single character. JSON
+// arrays are applied by setting the flag once for each element, JSON objects by
+// setting the flag once for each key=value pair, and null values are skipped.
+// Values equal to the flag's current value, which includes the defaults of
+// flags that weren't set when the snapshot was taken, are also skipped, so those
+// flags remain unset, and don't conflict with e.g. mutually exclusive flags.
+//
+// Flags set via the snapshot have the lowest priority, so commandline args, env
+// vars, and config files all take precedence. Keys which don't match a defined
+// flag produce a parse error, unless [WithConfigIgnoreUndefinedFlags] is also
+// given.
+func WithSnapshot(data []byte) Option {
+	return func(pc *ParseContext) {
+		pc.snapshot = data
+	}
+}
+
 // WithEnvVars tells [Parse] to set flags from environment variables. Flags are
 // matched to environment variables by capitalizing the flag name, and replacing
 // separator characters like periods or hyphens with underscores.
@@ -103,6 +383,61 @@ func WithEnvVarPrefix(prefix string) Option {
 	return func(pc *ParseContext) {
 		pc.envVarEnabled = true
 		pc.envVarPrefix = prefix
+		pc.envVarPrefixFromName = false
+	}
+}
+
+// WithEnvVarPrefixFromName is like [WithEnvVarPrefix], but derives the prefix
+// from the name of the flag set being parsed, as returned by GetName. This
+// keeps the prefix in sync with the program name, without repeating it.
+//
+// The name is sanitized as follows: letters are uppercased, every character
+// other than an ASCII letter, digit, or underscore is replaced by an
+// underscore, and leading and trailing underscores are removed. For example, a
+// flag set named `my-prog` yields the prefix `MY_PROG`, and the env var
+// `MY_PROG_FOO` matches a flag named `foo`.
+//
+// By default, flags are not parsed from environment variables at all.
+func WithEnvVarPrefixFromName() Option {
+	return func(pc *ParseContext) {
+		pc.envVarEnabled = true
+		pc.envVarPrefixFromName = true
+	}
+}
+
+// WithEnvVarLowercase is like [WithEnvVars], but matches flags to lowercase
+// env var keys, rather than the default uppercase keys. The env var prefix, if
+// any, is lowercased as well. For example, with the env var prefix `MYPROG`,
+// the env var `myprog_foo` matches a flag named `foo`, and `MYPROG_FOO` doesn't
+// match anything. This is useful for systems which mandate lowercase env vars.
+//
+// Env var keys are always either all uppercase or all lowercase; there is no
+// case-sensitive or case-insensitive matching mode.
+func WithEnvVarLowercase() Option {
+	return func(pc *ParseContext) {
+		pc.envVarEnabled = true
+		pc.envVarLowercase = true
+	}
+}
+
+// WithEnvVarList tells [Parse] to read args from the environment variable with
+// the given name, e.g. MYAPP_FLAGS="--timeout=5s --verbose". The value is split
+// into args on whitespace, respecting simple shell-style quoting, and those
+// args are matched to flags by the same parser as commandline args, so e.g.
+// --name=value, --name value, and clustered short flags like -vq are supported.
+// Every arg must be a flag, or the value of a flag. The env var list requires
+// the flag set to be a [FlagSet].
+//
+// Flags set via the env var list have a lower priority than flags set via the
+// commandline, and a higher priority than flags set via individual environment
+// variables or config files. An empty or unset env var is ignored. Unknown
+// flags produce a parse error, unless [WithEnvVarIgnoreInvalid] is also given.
+//
+// The env var name is used as-is, regardless of any env var prefix. By default,
+// no env var list is read.
+func WithEnvVarList(name string) Option {
+	return func(pc *ParseContext) {
+		pc.envVarListName = name
 	}
 }
 
@@ -120,7 +455,9 @@ func WithEnvVarPrefix(prefix string) Option {
 // `a` and `b,c`. Or, `FOO=axxxb\xxxc` with a delimiter of `xxx` would yield
 // values `a` and `bxxxc`.
 //
-// By default, no splitting of environment variable values occurs.
+// Flags with a delimiter of their own, via [FlagConfig.EnvSplit], are split on
+// that delimiter instead. By default, no splitting of environment variable
+// values occurs.
 func WithEnvVarSplit(delimiter string) Option {
 	return func(pc *ParseContext) {
 		pc.envVarEnabled = true
@@ -128,6 +465,136 @@ func WithEnvVarSplit(delimiter string) Option {
 	}
 }
 
+// WithEnvVarIgnoreInvalid tells [Parse] to skip environment variables whose
+// values can't be set on their corresponding flags, rather than failing the
+// parse. Each skipped env var is reported as a single line written to w, which
+// may be nil to skip env vars silently. Unknown flags in the env var list given
+// by [WithEnvVarList] are skipped in the same way.
+//
+// This can be useful for programs which run in shared environments, where
+// stray env vars may coincidentally match flag names.
+//
+// By default, an invalid env var value produces a parse error.
+func WithEnvVarIgnoreInvalid(w io.Writer) Option {
+	return func(pc *ParseContext) {
+		pc.envVarIgnoreInvalid = true
+		pc.envVarIgnoreInvalidWriter = w
+	}
+}
+
+// WithEnumCaseInsensitive tells [Parse] to match input to the valid values of
+// every string enum flag without regard to case, as if each [ffval.Enum] had
+// CaseInsensitive set to true. This only applies to flags defined in a
+// [FlagSet] whose value is an *ffval.Enum[string], which includes flags defined
+// via e.g. [FlagSet.StringEnum]. The option only affects the parse it's given
+// to: subsequent parses without it match input exactly, as before.
+//
+// By default, enums match input exactly, unless configured otherwise.
+func WithEnumCaseInsensitive() Option {
+	return func(pc *ParseContext) {
+		pc.enumCaseInsensitive = true
+	}
+}
+
+// WithValueTransform tells [Parse] to pass every value through fn before it's
+// set on its flag, regardless of source. That includes values from the
+// commandline, environment variables, and config files. This is useful for
+// normalization which should apply uniformly, such as trimming whitespace, or
+// expanding a leading `~/` to a home directory. If fn returns an error, the
+// flag isn't set, and parsing fails.
+//
+// Environment variable values are split via [WithEnvVarSplit] before fn is
+// called, so fn receives each individual value. Config file values are trimmed
+// via [WithConfigTrimSpace], and executed via [WithConfigValueExec], before fn
+// is called. Commandline values are only transformed when the flags being
+// parsed are a [FlagSet]; other implementations of [Flags] parse args on their
+// own, and so can't be intercepted.
+//
+// By default, values are set exactly as provided.
+func WithValueTransform(fn func(f Flag, rawValue string) (string, error)) Option {
+	return func(pc *ParseContext) {
+		pc.valueTransform = fn
+	}
+}
+
+// WithDecimalSeparator tells [Parse] to treat r as the decimal separator in the
+// values of float flags, including lists of floats, regardless of source. Each
+// occurrence of r is replaced with a '.' before the value is set, so that e.g.
+// 3,14 is parsed as 3.14 when r is ','. Other flags are unaffected. The
+// normalization is applied before any transform from [WithValueTransform].
+//
+// Values are split before they're normalized. In particular, env var values
+// split via [WithEnvVarSplit] with the same delimiter as r are split into
+// separate values first, so 3,14 would become the two values 3 and 14. Use a
+// different delimiter, e.g. ";", when combining the two options.
+//
+// By default, float values must use '.' as the decimal separator.
+func WithDecimalSeparator(r rune) Option {
+	return func(pc *ParseContext) {
+		pc.decimalSeparator = r
+	}
+}
+
+// WithDeprecationWriter tells [Command.Parse] to write warnings about selected
+// commands that are deprecated, via [Command.DeprecatedBy], to w. It also tells
+// [Parse] to write warnings about deprecated flags, via [FlagConfig.Deprecated],
+// which are set by commandline args, env vars, or config files. A nil writer
+// disables the warnings.
+//
+// By default, warnings are written to os.Stderr.
+func WithDeprecationWriter(w io.Writer) Option {
+	return func(pc *ParseContext) {
+		pc.deprecationWriter = w
+		pc.deprecationWriterSet = true
+	}
+}
+
+func (pc ParseContext) getDeprecationWriter() io.Writer {
+	if pc.deprecationWriterSet {
+		return pc.deprecationWriter
+	}
+	return os.Stderr
+}
+
+// WithBooleanPresenceTrue tells [Parse] to set boolean flags to true when their
+// key is present with an empty value, either as an environment variable, or in
+// a config file. For example, with this option, `DEBUG=` in the environment,
+// or in a .env file parsed by ffenv.Parser, sets the boolean flag debug to
+// true.
+//
+// This unifies the semantics of presence-only boolean keys across sources.
+// [PlainParser] already treats a line with a key and no value as true, but env
+// vars with empty values are ignored, and ffenv.Parser rejects empty values
+// unless this option is provided.
+//
+// By default, empty values aren't treated specially.
+func WithBooleanPresenceTrue() Option {
+	return func(pc *ParseContext) {
+		pc.booleanPresenceTrue = true
+	}
+}
+
+// WithFlagTemplates tells [Parse] to treat the values of string flags as
+// [text/template] templates, which can reference the values of other flags. For
+// example, a flag `--log-file` with value `{{.DataDir}}/app.log` would be set to
+// `/var/lib/app/app.log` if the flag `--data-dir` had value `/var/lib/app`.
+//
+// Templates are rendered after all other sources (args, env vars, config files)
+// have been applied. Flags are referenced by the CamelCase form of their names,
+// so `--data-dir` is `.DataDir`, and `-v` is `.V`. Template data includes every
+// flag, represented by its string value, but only string-valued flags are
+// themselves rendered as templates. Templates may reference other templated
+// flags, which are rendered first; cycles produce an error, as do references to
+// undefined flags. Rendered flags are set via SetValue, and therefore report
+// true for IsSet.
+//
+// By default, flag values are never treated as templates.
+func WithFlagTemplates() Option {
+	return func(pc *ParseContext) {
+		pc.flagTemplates = true
+	}
+}
+
 // WithFilesystem tells [Parse] to use the provided filesystem when accessing
 // files on disk, typically when reading a config file.
 //
@@ -137,3 +604,41 @@ func WithFilesystem(fs iofs.FS) Option {
 		pc.configOpenFunc = fs.Open
 	}
 }
+
+// WithAfterParse tells [Parse] to call fn with the parsed flag set, after every
+// stage of the parse (args, env vars, config files, and flag templates) has
+// completed successfully. At that point, every flag has its final value. This
+// can be useful for validation, or for computing derived config. If fn returns
+// an error, Parse returns that error.
+//
+// If WithAfterParse is provided multiple times, each fn is called in order, and
+// the first error stops the parse.
+func WithAfterParse(fn func(fs Flags) error) Option {
+	return func(pc *ParseContext) {
+		pc.afterParse = append(pc.afterParse, fn)
+	}
+}
+
+// WithRequiredHelp causes parse errors for missing required flags to also match
+// [ErrHelp], via [errors.Is]. Programs which print help text when parse returns
+// ErrHelp will therefore also print help when a required flag is missing, and
+// package ffhelp marks each required flag which hasn't been set.
+//
+// By default, missing required flags produce an error which matches
+// [ErrMissingRequired] only.
+func WithRequiredHelp() Option {
+	return func(pc *ParseContext) {
+		pc.requiredHelp = true
+	}
+}
+
+// WithStageTimings tells [Parse] to record how long each stage of the parse
+// took in the given timings. The timings are reset at the start of every parse,
+// and stages which aren't reached, e.g. because an earlier stage failed, are
+// left at zero. Stages which are reached but have nothing to do, e.g. the env
+// var stage without [WithEnvVars], record negligible durations.
+func WithStageTimings(timings *ParseTimings) Option {
+	return func(pc *ParseContext) {
+		pc.stageTimings = timings
+	}
+}
@@ -2,13 +2,23 @@ package ff
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	iofs "io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v4/ffval"
 )
 
 // FlagSetAny must be either a [Flags] interface, or a concrete [*flag.FlagSet].
@@ -38,12 +48,42 @@ func parse(fs Flags, args []string, options ...Option) error {
 		option(&pc)
 	}
 
+	// Float values may need their decimal separator normalized, before any
+	// other transform is applied.
+	if sep := pc.decimalSeparator; sep != 0 && sep != '.' {
+		transform := pc.valueTransform
+		pc.valueTransform = func(f Flag, value string) (string, error) {
+			if isFloatFlag(f) {
+				value = strings.ReplaceAll(value, string(sep), ".")
+			}
+			if transform != nil {
+				return transform(f, value)
+			}
+			return value, nil
+		}
+	}
+
+	// The env var prefix may be derived from the flag set name.
+	if pc.envVarPrefixFromName {
+		pc.envVarPrefix = envVarPrefixFromName(fs.GetName())
+	}
+
+	// Env var keys are uppercase, unless lowercase keys were requested.
+	envVarKeys := func(f Flag) []string {
+		keys := getEnvVarKeys(f, pc.envVarPrefix)
+		if pc.envVarLowercase {
+			for i := range keys {
+				keys[i] = strings.ToLower(keys[i])
+			}
+		}
+		return keys
+	}
+
 	// Index valid flags by env var key, to support .env config files (below).
 	env2flag := map[string]Flag{}
 	{
 		if err := fs.WalkFlags(func(f Flag) error {
-			for _, name := range getNameStrings(f) {
-				key := getEnvVarKey(name, pc.envVarPrefix)
+			for _, key := range envVarKeys(f) {
 				if existing, ok := env2flag[key]; ok {
 					return fmt.Errorf("%s: %w (%s)", getNameString(f), ErrDuplicateFlag, getNameString(existing))
 				}
@@ -55,6 +95,16 @@ func parse(fs Flags, args []string, options ...Option) error {
 		}
 	}
 
+	// Flags which are already set, e.g. parent flags set by a parent command,
+	// have already been warned about if they're deprecated.
+	var preset flagSetSlice
+	fs.WalkFlags(func(f Flag) error {
+		if f.IsSet() {
+			preset.add(f)
+		}
+		return nil
+	})
+
 	// After each stage of parsing, record the flags that have been provided.
 	// Subsequent lower-priority stages can't set these already-provided flags.
 	var provided flagSetSlice
@@ -67,8 +117,48 @@ func parse(fs Flags, args []string, options ...Option) error {
 		})
 	}
 
+	// String enums may need to be made case-insensitive, before any stage.
+	// They're restored after parsing, so the option only affects this parse.
+	if pc.enumCaseInsensitive {
+		var enums []*ffval.Enum[string]
+		fs.WalkFlags(func(f Flag) error {
+			if cf, ok := f.(*coreFlag); ok {
+				if e, ok := cf.flagValue.(*ffval.Enum[string]); ok && !e.CaseInsensitive {
+					e.CaseInsensitive = true
+					enums = append(enums, e)
+				}
+			}
+			return nil
+		})
+		defer func() {
+			for _, e := range enums {
+				e.CaseInsensitive = false
+			}
+		}()
+	}
+
+	// Stage timings are recorded if requested, and discarded otherwise.
+	timings := pc.stageTimings
+	if timings == nil {
+		timings = &ParseTimings{}
+	}
+	*timings = ParseTimings{}
+	timeStage := func(d *time.Duration, stage func() error) error {
+		begin := time.Now()
+		err := stage()
+		*d = time.Since(begin)
+		return err
+	}
+
 	// First priority: the commandline, i.e. the user.
+	argsBegin := time.Now()
 	{
+		// A FlagSet can apply the value transform to args directly.
+		if ffs, ok := fs.(*FlagSet); ok && pc.valueTransform != nil {
+			ffs.transform = pc.valueTransform
+			defer func() { ffs.transform = nil }()
+		}
+
 		if err := fs.Parse(args); err != nil {
 			return fmt.Errorf("parse args: %w", err)
 		}
@@ -76,61 +166,213 @@ func parse(fs Flags, args []string, options ...Option) error {
 		markProvided()
 	}
 
-	// Second priority: the environment, i.e. the session.
-	{
-		if pc.envVarEnabled {
-			if err := fs.WalkFlags(func(f Flag) error {
-				// If the flag has already been set, we can't do anything.
-				if provided.has(f) {
-					return nil
+	// Second priority: the env var list, i.e. args provided via the session.
+	if pc.envVarListName != "" {
+		if err := parseEnvVarList(fs, pc, provided); err != nil {
+			return fmt.Errorf("parse env var list: %w", err)
+		}
+
+		markProvided()
+	}
+	timings.ArgsDuration = time.Since(argsBegin)
+
+	// Environment variables, i.e. the session. Flags whose env vars are all
+	// absent are collected in envAbsent.
+	var envAbsent []Flag
+	parseEnv := func() error {
+		if !pc.envVarEnabled {
+			return nil
+		}
+
+		if err := fs.WalkFlags(func(f Flag) error {
+			// If the flag has already been set, we can't do anything.
+			if provided.has(f) {
+				return nil
+			}
+
+			// If the flag has opted out of env vars, skip it.
+			if isNoEnvVar(f) {
+				return nil
+			}
+
+			// Look in the environment for each of the flag's env var keys.
+			var anyPresent bool
+			for _, key := range envVarKeys(f) {
+				// Look up the value from the environment. A boolean flag may
+				// be set by the mere presence of its env var.
+				val, present := os.LookupEnv(key)
+				anyPresent = anyPresent || present
+				if val == "" && present && pc.booleanPresenceTrue && f.IsBoolFlag() {
+					val = "true"
+				}
+				if val == "" {
+					continue
+				}
+
+				// The value may need to be split, preferably by the flag's
+				// own delimiter, otherwise by the global delimiter.
+				vals := []string{val}
+				if split := getEnvSplit(f, pc.envVarSplit); split != "" {
+					vals = splitEscape(val, split)
 				}
 
-				// Look in the environment for each of the flag names.
-				for _, name := range getNameStrings(f) {
-					// Transform the flag name to an env var key.
-					key := getEnvVarKey(name, pc.envVarPrefix)
+				// Set the flag to the value(s).
+				for _, v := range vals {
+					if err := setTransformedValue(f, v, pc.valueTransform); err != nil {
+						err = fmt.Errorf("%s=%q: %w", key, val, newFlagError(f, withValidValues(f, err)))
+						if !pc.envVarIgnoreInvalid {
+							return err
+						}
+						if pc.envVarIgnoreInvalidWriter != nil {
+							fmt.Fprintf(pc.envVarIgnoreInvalidWriter, "ignoring invalid env var: %v\n", err)
+						}
+						break
+					}
+				}
+			}
+
+			// If none of the env vars were present, the flag may have a value
+			// to apply in their absence, once config files have had a chance
+			// to set it.
+			if !anyPresent && getEnvVarAbsentValue(f) != "" {
+				envAbsent = append(envAbsent, f)
+			}
+
+			return nil
+		}); err != nil {
+			return fmt.Errorf("parse environment: %w", err)
+		}
+
+		return nil
+	}
 
-					// Look up the value from the environment.
-					val := os.Getenv(key)
-					if val == "" {
-						continue
+	// parseConfigFile parses a single config file, and reports whether it was
+	// used, i.e. whether it existed and was successfully parsed.
+	parseConfigFile := func(configFile string) (bool, error) {
+		f, err := pc.configOpenFunc(configFile)
+		switch {
+		case err == nil:
+			defer f.Close()
+			if err := pc.configParseFunc(f, func(name, value string) error {
+				// If a section was specified, only consider keys in that
+				// section, and strip the section prefix from those keys.
+				if pc.configFileSection != "" {
+					var ok bool
+					if name, ok = strings.CutPrefix(name, pc.configFileSection+"."); !ok {
+						return nil
 					}
+				}
 
-					// The value may need to be split.
-					vals := []string{val}
-					if pc.envVarSplit != "" {
-						vals = splitEscape(val, pc.envVarSplit)
+				// The parser calls us with a name=value pair. We want to
+				// allow the name to be either the actual flag name, or its
+				// env var representation (to support .env files).
+				var (
+					setFlag, fromSet = getConfigFlag(fs, name, pc.configMatchShortNames)
+					envFlag, fromEnv = env2flag[name]
+					target           Flag
+				)
+				switch {
+				case fromSet:
+					target = setFlag
+				case !fromSet && fromEnv:
+					target = envFlag
+				case !fromSet && !fromEnv && pc.configIgnoreUndefinedFlags:
+					return nil
+				case !fromSet && !fromEnv && !pc.configIgnoreUndefinedFlags:
+					if suggestion := getConfigSuggestion(fs, env2flag, name); suggestion != "" {
+						return fmt.Errorf("%s: %w (did you mean %q?)", name, ErrUnknownFlag, suggestion)
 					}
+					return fmt.Errorf("%s: %w", name, ErrUnknownFlag)
+				}
 
-					// Set the flag to the value(s).
-					for _, v := range vals {
-						if err := f.SetValue(v); err != nil {
-							return fmt.Errorf("%s=%q: %w", key, val, err)
+				// If the flag was already provided by a higher priority
+				// stage, then don't set it again. But be sure to allow
+				// config files to specify the same flag multiple times.
+				if provided.has(target) {
+					return nil
+				}
+
+				// The value may need to be trimmed.
+				if pc.configTrimSpace {
+					value = strings.TrimSpace(value)
+				}
+
+				// A boolean flag may be set by the mere presence of its key.
+				if value == "" && pc.booleanPresenceTrue && target.IsBoolFlag() {
+					value = "true"
+				}
+
+				// If the value is a command, run it, and use the output.
+				if pc.configValueExec && strings.HasPrefix(value, "!") {
+					switch {
+					case strings.HasPrefix(value, "!!"):
+						value = value[1:] // !!foo -> !foo
+					default:
+						output, err := pc.configValueExecFunc(value[1:])
+						if err != nil {
+							return fmt.Errorf("%s: exec %q: %w", name, value[1:], err)
 						}
+						value = strings.TrimSpace(output)
 					}
 				}
 
+				if err := setTransformedValue(target, value, pc.valueTransform); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+
 				return nil
 			}); err != nil {
-				return fmt.Errorf("parse environment: %w", err)
+				return false, fmt.Errorf("parse config file: %w", err)
 			}
-		}
+			return true, nil
 
-		markProvided()
+		case errors.Is(err, iofs.ErrNotExist) && pc.configAllowMissingFile:
+			return false, nil // no problem
+
+		default:
+			return false, err
+		}
 	}
 
-	// Third priority: the config file, i.e. the host.
-	{
-		// First, prefer an explicit filename string.
-		var configFile string
-		if pc.configFileName != "" {
-			configFile = pc.configFileName
+	// The config file, i.e. the host.
+	parseConfig := func() error {
+		// Until a config file is successfully parsed, none was used.
+		if pc.configFileUsed != nil {
+			*pc.configFileUsed = ""
+		}
+
+		// First, prefer explicit filename strings.
+		var configFiles []string
+		if len(pc.configFileNames) > 0 {
+			configFiles = pc.configFileNames
 		}
 
-		// Next, check the flag name.
-		if configFile == "" && pc.configFlagName != "" {
+		// Next, check the flag name, which may be a list of filenames.
+		if len(configFiles) == 0 && pc.configFlagName != "" {
 			if f, ok := fs.GetFlag(pc.configFlagName); ok {
-				configFile = f.GetValue()
+				if filenames, ok := GetAny(f).([]string); ok {
+					configFiles = filenames
+				} else if filename := f.GetValue(); filename != "" {
+					configFiles = []string{filename}
+				}
+			}
+		}
+
+		// Then, check the pointer, which was set by the args, if at all.
+		if len(configFiles) == 0 && pc.configFileVia != nil {
+			if filename := *pc.configFileVia; filename != "" {
+				configFiles = []string{filename}
+			}
+		}
+
+		// Finally, fall back to an environment variable.
+		if len(configFiles) == 0 && pc.configEnvVarKey != "" {
+			key := getEnvVarKey(pc.configEnvVarKey, pc.envVarPrefix)
+			if pc.envVarLowercase {
+				key = strings.ToLower(key)
+			}
+			if filename := os.Getenv(key); filename != "" {
+				configFiles = []string{filename}
 			}
 		}
 
@@ -141,67 +383,468 @@ func parse(fs Flags, args []string, options ...Option) error {
 			}
 		}
 
+		// If they didn't provide an exec func, set the default.
+		if pc.configValueExecFunc == nil {
+			pc.configValueExecFunc = func(command string) (string, error) {
+				output, err := exec.Command("sh", "-c", command).Output()
+				return string(output), err
+			}
+		}
+
+		// As a last resort, look in the standard XDG config locations.
+		if len(configFiles) == 0 && pc.xdgAppName != "" {
+			getenv := pc.xdgGetenv
+			if getenv == nil {
+				getenv = os.Getenv
+			}
+			if candidates := getXDGConfigFiles(pc.xdgAppName, getenv); len(candidates) > 0 {
+				configFiles = candidates[:1] // if none exist, the first is missing
+				for _, candidate := range candidates {
+					if f, err := pc.configOpenFunc(candidate); err == nil {
+						f.Close()
+						configFiles = []string{candidate}
+						break
+					}
+				}
+			}
+		}
+
+		// A config file parser with parse info takes precedence.
+		if pc.configParser != nil {
+			info := ParseInfo{EnvVarPrefix: pc.envVarPrefix, Flags: fs, BooleanPresenceTrue: pc.booleanPresenceTrue}
+			pc.configParseFunc = func(r io.Reader, set func(name, value string) error) error {
+				return pc.configParser.Parse(r, set, info)
+			}
+		}
+
 		// Config files require both a filename and a parser.
 		var (
-			haveConfigFile  = configFile != ""
-			haveParser      = pc.configParseFunc != nil
-			parseConfigFile = haveConfigFile && haveParser
+			haveConfigFile   = len(configFiles) > 0
+			haveParser       = pc.configParseFunc != nil
+			parseConfigFiles = haveConfigFile && haveParser
 		)
-		if parseConfigFile {
-			configFile, err := pc.configOpenFunc(configFile)
-			switch {
-			case err == nil:
-				defer configFile.Close()
-				if err := pc.configParseFunc(configFile, func(name, value string) error {
-					// The parser calls us with a name=value pair. We want to
-					// allow the name to be either the actual flag name, or its
-					// env var representation (to support .env files).
-					var (
-						setFlag, fromSet = fs.GetFlag(name)
-						envFlag, fromEnv = env2flag[name]
-						target           Flag
-					)
-					switch {
-					case fromSet:
-						target = setFlag
-					case !fromSet && fromEnv:
-						target = envFlag
-					case !fromSet && !fromEnv && pc.configIgnoreUndefinedFlags:
-						return nil
-					case !fromSet && !fromEnv && !pc.configIgnoreUndefinedFlags:
-						return fmt.Errorf("%s: %w", name, ErrUnknownFlag)
-					}
+		if !parseConfigFiles {
+			return nil
+		}
 
-					// If the flag was already provided by commandline args or
-					// env vars, then don't set it again. But be sure to allow
-					// config files to specify the same flag multiple times.
-					if provided.has(target) {
-						return nil
-					}
+		// Later files have higher priority than earlier files, so parse them
+		// in reverse order, and mark their flags as provided after each one.
+		for i := len(configFiles) - 1; i >= 0; i-- {
+			configFile := configFiles[i]
+			if configFile == "" {
+				continue
+			}
 
-					if err := target.SetValue(value); err != nil {
-						return fmt.Errorf("%s: %w", name, err)
-					}
+			used, err := parseConfigFile(configFile)
+			if err != nil {
+				return err
+			}
 
-					return nil
-				}); err != nil {
-					return fmt.Errorf("parse config file: %w", err)
+			if used && pc.configFileUsed != nil && *pc.configFileUsed == "" {
+				*pc.configFileUsed = configFile
+			}
+
+			markProvided()
+		}
+
+		return nil
+	}
+
+	// By default, env vars have second priority, and config files have third
+	// priority. WithConfigBeforeEnv swaps those priorities.
+	var (
+		envStage    = func() error { return timeStage(&timings.EnvDuration, parseEnv) }
+		configStage = func() error { return timeStage(&timings.ConfigDuration, parseConfig) }
+		stages      = []func() error{envStage, configStage}
+	)
+	if pc.configBeforeEnv {
+		stages = []func() error{configStage, envStage}
+	}
+	for _, stage := range stages {
+		if err := stage(); err != nil {
+			return err
+		}
+
+		markProvided()
+	}
+
+	// Flags with absent env vars which weren't set by any other source get
+	// their absent values.
+	for _, f := range envAbsent {
+		if provided.has(f) {
+			continue
+		}
+		absent := getEnvVarAbsentValue(f)
+		if err := setTransformedValue(f, absent, pc.valueTransform); err != nil {
+			return fmt.Errorf("absent env var value %q: %w", absent, newFlagError(f, err))
+		}
+	}
+	markProvided()
+
+	// Warn about deprecated flags and values set by the user. Flags set by the
+	// snapshot below are excluded, as the snapshot includes every flag.
+	warnDeprecated(pc.getDeprecationWriter(), fs, preset)
+
+	// Lowest priority: a snapshot of previously parsed flag values.
+	if pc.snapshot != nil {
+		if err := parseSnapshot(fs, pc, provided); err != nil {
+			return fmt.Errorf("parse snapshot: %w", err)
+		}
+
+		markProvided()
+		warnDeprecated(nil, fs, provided)
+	}
+
+	// After all sources have been applied, render any flag templates.
+	if pc.flagTemplates {
+		if err := renderFlagTemplates(fs); err != nil {
+			return fmt.Errorf("render flag templates: %w", err)
+		}
+	}
+
+	// Some values can only be validated once every source has been applied.
+	if err := fs.WalkFlags(func(f Flag) error {
+		if vf, ok := f.(interface{ ValidateParsed() error }); ok {
+			if err := vf.ValidateParsed(); err != nil {
+				return newFlagError(f, err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Every source has been applied, so required flags must be set by now.
+	if err := checkRequired(fs); err != nil {
+		if pc.requiredHelp {
+			err = helpError{err}
+		}
+		return err
+	}
+
+	// Groups of flags recorded by the flag set must also be satisfied.
+	if gf, ok := fs.(interface{ checkFlagGroups() error }); ok {
+		if err := gf.checkFlagGroups(); err != nil {
+			return err
+		}
+	}
+
+	// Finally, with every flag at its final value, call any after-parse funcs.
+	for _, fn := range pc.afterParse {
+		if err := fn(fs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkRequired returns an error naming every required flag which isn't set.
+func checkRequired(fs Flags) error {
+	var missing []string
+	if err := fs.WalkFlags(func(f Flag) error {
+		if rf, ok := f.(interface{ IsRequired() bool }); ok && rf.IsRequired() && !f.IsSet() {
+			missing = append(missing, getNameString(f))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingRequired, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// warnDeprecated writes a warning for every deprecated flag which is set, other
+// than those in preset, and for every deprecated value set since the previous
+// call. A nil writer discards the warnings.
+func warnDeprecated(w io.Writer, fs Flags, preset flagSetSlice) {
+	fs.WalkFlags(func(f Flag) error {
+		name := getNameString(f)
+		if long, ok := f.GetLongName(); ok {
+			name = "--" + long
+		}
+		if df, ok := f.(interface{ GetDeprecated() string }); ok && df.GetDeprecated() != "" && f.IsSet() && !preset.has(f) && w != nil {
+			fmt.Fprintf(w, "warning: flag %s is deprecated: %s\n", name, df.GetDeprecated())
+		}
+		if vf, ok := f.(interface{ takeDeprecatedValues() ([]string, []string) }); ok {
+			values, messages := vf.takeDeprecatedValues()
+			for i := range values {
+				if w != nil {
+					fmt.Fprintf(w, "warning: flag %s value %q is deprecated: %s\n", name, values[i], messages[i])
 				}
+			}
+		}
+		return nil
+	})
+}
 
-			case errors.Is(err, iofs.ErrNotExist) && pc.configAllowMissingFile:
-				// no problem
+// helpError wraps an error so that it also matches [ErrHelp].
+type helpError struct{ error }
 
-			default:
-				return err
+func (e helpError) Unwrap() error        { return e.error }
+func (e helpError) Is(target error) bool { return target == ErrHelp }
+
+// parseEnvVarList reads args from the env var named by WithEnvVarList, and sets
+// the corresponding flags, skipping any flags which have already been provided.
+// Args are matched to flags by the same parser as the commandline.
+func parseEnvVarList(fs Flags, pc ParseContext, provided flagSetSlice) error {
+	tokens, err := splitShellWords(os.Getenv(pc.envVarListName))
+	if err != nil {
+		return fmt.Errorf("%s: %w", pc.envVarListName, err)
+	}
+	if len(tokens) <= 0 {
+		return nil
+	}
+
+	ffs, ok := fs.(*FlagSet)
+	if !ok {
+		return fmt.Errorf("%s: unsupported flag set %T", pc.envVarListName, fs)
+	}
+
+	unknown, rest, err := ffs.parseArgsVia(tokens, func(f *coreFlag, value string) error {
+		if provided.has(f) {
+			return nil
+		}
+		return setTransformedValue(f, value, pc.valueTransform)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", pc.envVarListName, err)
+	}
+
+	if len(rest) > 0 {
+		return fmt.Errorf("%s: unexpected argument %q", pc.envVarListName, rest[0])
+	}
+
+	for _, arg := range unknown {
+		if !strings.HasPrefix(arg, "-") {
+			continue // the value of an unknown flag, e.g. --foo bar
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		err := fmt.Errorf("%s: %w %q", pc.envVarListName, ErrUnknownFlag, name)
+		if !pc.envVarIgnoreInvalid {
+			return err
+		}
+		if pc.envVarIgnoreInvalidWriter != nil {
+			fmt.Fprintf(pc.envVarIgnoreInvalidWriter, "ignoring invalid env var: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// parseSnapshot decodes the JSON object given via WithSnapshot, and sets every
+// flag which hasn't already been provided by a higher priority source, and
+// whose snapshot value differs from its current value.
+func parseSnapshot(fs Flags, pc ParseContext, provided flagSetSlice) error {
+	var values map[string]any
+	dec := json.NewDecoder(bytes.NewReader(pc.snapshot))
+	dec.UseNumber()
+	if err := dec.Decode(&values); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		f, ok := fs.GetFlag(key)
+		if !ok {
+			if pc.configIgnoreUndefinedFlags {
+				continue
 			}
+			return fmt.Errorf("%w %q", ErrUnknownFlag, key)
 		}
 
-		markProvided()
+		if provided.has(f) {
+			continue
+		}
+
+		// The snapshot includes every flag, set or not. Values equal to the
+		// flag's current value, e.g. the defaults of flags which weren't set
+		// when the snapshot was taken, are skipped, so those flags stay unset.
+		if snapshotUnchanged(f, values[key]) {
+			continue
+		}
+
+		strs, err := snapshotValues(values[key])
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+
+		for _, s := range strs {
+			if err := setTransformedValue(f, s, pc.valueTransform); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// snapshotUnchanged returns true if the snapshot value v serializes to the same
+// JSON as the current value of the flag.
+func snapshotUnchanged(f Flag, v any) bool {
+	current, err := json.Marshal(getJSONValue(f))
+	if err != nil {
+		return false
+	}
+	saved, err := json.Marshal(v)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(current, saved)
+}
+
+// snapshotValues converts a decoded JSON value to the strings which should be
+// passed to a flag's Set method, in order.
+func snapshotValues(v any) ([]string, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{x}, nil
+	case bool:
+		return []string{strconv.FormatBool(x)}, nil
+	case json.Number:
+		return []string{x.String()}, nil
+	case []any:
+		var strs []string
+		for _, elem := range x {
+			elemStrs, err := snapshotValues(elem)
+			if err != nil {
+				return nil, err
+			}
+			if len(elemStrs) > 1 {
+				return nil, fmt.Errorf("unsupported nested value")
+			}
+			strs = append(strs, elemStrs...)
+		}
+		return strs, nil
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		strs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			elemStrs, err := snapshotValues(x[k])
+			if err != nil {
+				return nil, err
+			}
+			if len(elemStrs) != 1 {
+				return nil, fmt.Errorf("%s: unsupported nested value", k)
+			}
+			strs = append(strs, k+"="+elemStrs[0])
+		}
+		return strs, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+// splitShellWords splits s into words on unquoted whitespace, with simple
+// shell-style quoting. Single quotes preserve every character literally. Double
+// quotes preserve every character, except that a backslash escapes a following
+// double quote or backslash. Outside of quotes, a backslash escapes any
+// following character.
+func splitShellWords(s string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// getXDGConfigFiles returns the standard XDG config file paths for the app, in
+// order of preference: $XDG_CONFIG_HOME/<app>/config, and then, as per the XDG
+// base directory spec, $HOME/.config/<app>/config. Relative paths are ignored.
+func getXDGConfigFiles(appName string, getenv func(string) string) []string {
+	var dirs []string
+	if dir := getenv("XDG_CONFIG_HOME"); filepath.IsAbs(dir) {
+		dirs = append(dirs, dir)
+	}
+	if home := getenv("HOME"); filepath.IsAbs(home) {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+
+	var files []string
+	for _, dir := range dirs {
+		file := filepath.Join(dir, appName, "config")
+		if len(files) > 0 && files[0] == file {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// getConfigFlag returns the flag corresponding to the given config file key.
+// Keys always match long names. Single-character keys only match short names
+// if matchShortNames is true.
+func getConfigFlag(fs Flags, name string, matchShortNames bool) (Flag, bool) {
+	f, ok := fs.GetFlag(name)
+	if !ok || matchShortNames {
+		return f, ok
+	}
+
+	if long, ok := f.GetLongName(); ok && long == name {
+		return f, true
+	}
+
+	return nil, false
+}
+
 //
 //
 //
@@ -264,6 +907,158 @@ func PlainParser(r io.Reader, set func(name, value string) error) error {
 	return s.Err()
 }
 
+// PropertiesParser is a parser for config files in the Java properties format,
+// which is useful for sharing config with JVM tools. Each logical line is
+// interpreted as a single key/value pair, where the key is the flag name.
+//
+// The key is separated from the value by the first unescaped "=", ":", or
+// whitespace, optionally surrounded by whitespace. A key with no separator and
+// no value is interpreted as a boolean, and the value is set to true, like
+// [PlainParser]. Lines whose first non-whitespace character is "#" or "!" are
+// comments. Comments are only supported on their own lines.
+//
+// A line ending in an unescaped backslash is continued on the next line, with
+// leading whitespace on the next line removed. Keys and values support the
+// escape sequences \t, \n, \r, \f, and \uXXXX, and any other character
+// preceded by a backslash, including "=", ":", "#", "!", space, and backslash
+// itself, is interpreted literally.
+//
+// An example config file follows. It sets timeout to `250ms`, foo to `abc def`,
+// bar to `first, second`, baz to "x\nyé" with a real newline, and verbose to
+// `true`.
+//
+//	# this is a comment
+//	! this is also a comment
+//	timeout = 250ms
+//	foo: abc def
+//	bar = first, \
+//	      second
+//	baz = x\ny\u00e9
+//	verbose
+func PropertiesParser(r io.Reader, set func(name, value string) error) error {
+	s := bufio.NewScanner(r)
+	for {
+		line, ok := nextPropertiesLine(s)
+		if !ok {
+			break
+		}
+
+		name, value, err := splitPropertiesLine(line)
+		if err != nil {
+			return err
+		}
+
+		if err := set(name, value); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+// nextPropertiesLine returns the next logical line from s, skipping blank and
+// comment lines, and joining lines which end in a backslash continuation.
+func nextPropertiesLine(s *bufio.Scanner) (string, bool) {
+	for s.Scan() {
+		line := strings.TrimLeft(s.Text(), " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+
+		for hasPropertiesContinuation(line) && s.Scan() {
+			line = line[:len(line)-1] + strings.TrimLeft(s.Text(), " \t\f")
+		}
+		if hasPropertiesContinuation(line) {
+			line = line[:len(line)-1] // continuation at EOF
+		}
+		return line, true
+	}
+	return "", false
+}
+
+// hasPropertiesContinuation returns true if line ends in an odd number of
+// backslashes, i.e. an unescaped backslash.
+func hasPropertiesContinuation(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// splitPropertiesLine splits a logical line into an unescaped key and value.
+func splitPropertiesLine(line string) (name, value string, _ error) {
+	index := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++ // skip the escaped character
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			index = i
+			break
+		}
+	}
+
+	rawName, rest := line[:index], line[index:]
+	rest = strings.TrimLeft(rest, " \t\f")
+	hasSeparator := rest != "" && (rest[0] == '=' || rest[0] == ':')
+	if hasSeparator {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	name, err := unescapeProperties(rawName)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", rawName, err)
+	}
+
+	if rest == "" && !hasSeparator {
+		return name, "true", nil // boolean option
+	}
+
+	value, err = unescapeProperties(rest)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", name, err)
+	}
+
+	return name, value, nil
+}
+
+// unescapeProperties evaluates the escape sequences in s.
+func unescapeProperties(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:i+5])
+			}
+			sb.WriteRune(rune(r))
+			i += 4
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
+
 //
 //
 //
@@ -274,6 +1069,22 @@ var envVarSeparators = strings.NewReplacer(
 	"/", "_",
 )
 
+// envVarPrefixFromName returns an env var prefix derived from the name, see
+// [WithEnvVarPrefixFromName] for details.
+func envVarPrefixFromName(name string) string {
+	prefix := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	return strings.Trim(prefix, "_")
+}
+
 func getEnvVarKey(flagName, envVarPrefix string) string {
 	var key string
 	key = flagName
@@ -284,6 +1095,142 @@ func getEnvVarKey(flagName, envVarPrefix string) string {
 	return key
 }
 
+// withValidValues annotates an invalid value error with the valid values of the
+// flag, if it has any, e.g. an enum flag.
+func withValidValues(f Flag, err error) error {
+	if !errors.Is(err, ffval.ErrInvalidValue) {
+		return err
+	}
+
+	vf, ok := f.(interface{ GetValid() []any })
+	if !ok {
+		return err
+	}
+
+	valid := vf.GetValid()
+	if len(valid) <= 0 {
+		return err
+	}
+
+	strs := make([]string, len(valid))
+	for i, v := range valid {
+		strs[i] = fmt.Sprint(v)
+	}
+	return fmt.Errorf("%w (valid: %s)", err, strings.Join(strs, ", "))
+}
+
+// setTransformedValue sets the flag to the value, after passing it through the
+// transform, if one was provided. It's used for every source other than the
+// commandline, so counter flags treat integers as absolute counts.
+func setTransformedValue(f Flag, value string, transform func(Flag, string) (string, error)) error {
+	if transform != nil {
+		transformed, err := transform(f, value)
+		if err != nil {
+			return fmt.Errorf("transform %q: %w", value, err)
+		}
+		value = transformed
+	}
+	if cf, ok := f.(*coreFlag); ok {
+		return cf.setAbsolute(value)
+	}
+	return f.SetValue(value)
+}
+
+// isFloatFlag returns true if the flag's value is a float, or a list of floats.
+func isFloatFlag(f Flag) bool {
+	cf, ok := f.(*coreFlag)
+	if !ok {
+		return false
+	}
+
+	v, ok := callGet(cf.flagValue)
+	if !ok {
+		return false
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// getEnvVarKeys returns the env var keys for every name of the flag. It's the
+// single source of env var keys for a flag, used both to read the environment
+// and to map env var keys back to flags, so the two can't disagree.
+func getEnvVarKeys(f Flag, envVarPrefix string) []string {
+	var keys []string
+	for _, name := range getNameStrings(f) {
+		keys = append(keys, getEnvVarKey(name, envVarPrefix))
+	}
+	return keys
+}
+
+// getConfigSuggestion returns the valid config file key closest to the given
+// unknown name, or the empty string if nothing is close enough. Valid keys are
+// flag long names and their env var representations.
+func getConfigSuggestion(fs Flags, env2flag map[string]Flag, name string) string {
+	var candidates []string
+	fs.WalkFlags(func(f Flag) error {
+		if long, ok := f.GetLongName(); ok {
+			candidates = append(candidates, long)
+		}
+		return nil
+	})
+	for key := range env2flag {
+		candidates = append(candidates, key)
+	}
+	sort.Strings(candidates) // deterministic tie-breaking
+
+	maxDist := defaultSuggestDistance(name)
+
+	var (
+		best     string
+		bestDist = -1
+	)
+	for _, candidate := range candidates {
+		dist := levenshtein(name, candidate)
+		if dist > maxDist {
+			continue
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+func getEnvSplit(f Flag, global string) string {
+	if sf, ok := f.(interface{ GetEnvSplit() string }); ok && sf.GetEnvSplit() != "" {
+		return sf.GetEnvSplit()
+	}
+	return global
+}
+
+func getEnvVarAbsentValue(f Flag) string {
+	if af, ok := f.(interface{ GetEnvVarAbsentValue() string }); ok {
+		return af.GetEnvVarAbsentValue()
+	}
+	return ""
+}
+
+func isNoEnvVar(f Flag) bool {
+	nf, ok := f.(interface{ IsNoEnvVar() bool })
+	return ok && nf.IsNoEnvVar()
+}
+
 func maybePrefix(key string, prefix string) string {
 	if prefix != "" {
 		key = strings.ToUpper(prefix) + "_" + key
@@ -1,15 +1,23 @@
 package ff_test
 
 import (
+	"bufio"
+	"bytes"
 	"embed"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/peterbourgon/ff/v4"
 	"github.com/peterbourgon/ff/v4/fftest"
+	"github.com/peterbourgon/ff/v4/ffval"
 )
 
 //go:embed testdata/*.conf
@@ -32,6 +40,7 @@ func TestParse(t *testing.T) {
 		{
 			Name:       "file only",
 			ConfigFile: "testdata/1.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "bar", I: 99, B: true, D: time.Hour},
 		},
 		{
@@ -43,6 +52,7 @@ func TestParse(t *testing.T) {
 		{
 			Name:       "file args",
 			ConfigFile: "testdata/2.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Args:       []string{"-s", "foo", "-i", "1234"},
 			Want:       fftest.Vars{S: "foo", I: 1234, D: 3 * time.Second},
 		},
@@ -57,15 +67,30 @@ func TestParse(t *testing.T) {
 			Name:        "file env",
 			ConfigFile:  "testdata/3.conf",
 			Environment: map[string]string{"TEST_PARSE_S": "env takes priority", "TEST_PARSE_B": "true"},
-			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE")},
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_PARSE")},
 			Want:        fftest.Vars{S: "env takes priority", I: 99, B: true, D: 34 * time.Second},
 		},
+		{
+			Name:        "WithConfigBeforeEnv file env",
+			ConfigFile:  "testdata/3.conf",
+			Environment: map[string]string{"TEST_PARSE_S": "config takes priority", "TEST_PARSE_B": "true"},
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_PARSE"), ff.WithConfigBeforeEnv()},
+			Want:        fftest.Vars{S: "bar", I: 99, B: true, D: 34 * time.Second},
+		},
+		{
+			Name:        "WithConfigBeforeEnv file env args",
+			ConfigFile:  "testdata/4.conf",
+			Environment: map[string]string{"TEST_PARSE_S": "from env", "TEST_PARSE_I": "300", "TEST_PARSE_F": "0.15", "TEST_PARSE_B": "true"},
+			Args:        []string{"-s", "from arg"},
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_PARSE"), ff.WithConfigBeforeEnv()},
+			Want:        fftest.Vars{S: "from arg", I: 200, F: 2.3, B: true, D: time.Minute},
+		},
 		{
 			Name:        "file env args",
 			ConfigFile:  "testdata/4.conf",
 			Environment: map[string]string{"TEST_PARSE_S": "from env", "TEST_PARSE_I": "300", "TEST_PARSE_F": "0.15", "TEST_PARSE_B": "true"},
 			Args:        []string{"-s", "from arg", "-i", "100"},
-			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE")},
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_PARSE")},
 			Want:        fftest.Vars{S: "from arg", I: 100, F: 0.15, B: true, D: time.Minute},
 		},
 		{
@@ -76,6 +101,7 @@ func TestParse(t *testing.T) {
 		{
 			Name:       "file repeats",
 			ConfigFile: "testdata/5.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "s.file.2", X: []string{"x.file.1", "x.file.2"}},
 		},
 		{
@@ -83,7 +109,7 @@ func TestParse(t *testing.T) {
 			ConfigFile:  "testdata/5.conf",
 			Environment: map[string]string{"TEST_PARSE_S": "s.env", "TEST_PARSE_X": "x.env.1"},
 			Args:        []string{"-s", "s.arg.1", "-s", "s.arg.2", "-x", "x.arg.1", "-x", "x.arg.2"},
-			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_PARSE")},
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_PARSE")},
 			Want:        fftest.Vars{S: "s.arg.2", X: []string{"x.arg.1", "x.arg.2"}}, // highest prio wins and no others are called
 		},
 		{
@@ -315,19 +341,34 @@ func TestParse_PlainParser(t *testing.T) {
 	t.Parallel()
 
 	testcases := fftest.TestCases{
+		{
+			Name:         "long names",
+			ConfigFile:   "testdata/long_names.conf",
+			Constructors: []fftest.Constructor{fftest.CoreConstructor},
+			Want:         fftest.Vars{S: "foo", I: 3},
+		},
+		{
+			Name:         "short names without WithConfigMatchShortNames",
+			ConfigFile:   "testdata/1.conf",
+			Constructors: []fftest.Constructor{fftest.CoreConstructor},
+			Want:         fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
 		{
 			Name:       "solo bool",
 			ConfigFile: "testdata/solo_bool.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "x", B: true},
 		},
 		{
 			Name:       "string with spaces",
 			ConfigFile: "testdata/spaces.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want:       fftest.Vars{S: "i am the very model of a modern major general"},
 		},
 		{
 			Name:       "comments",
 			ConfigFile: "testdata/comments.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want: fftest.Vars{X: []string{
 				"foo#bar",
 				"foo# bar",
@@ -341,11 +382,18 @@ func TestParse_PlainParser(t *testing.T) {
 		{
 			Name:       "newlines",
 			ConfigFile: "testdata/newlines.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
 			Want: fftest.Vars{X: []string{
 				`hello\nworld\n`,
 				`"hello\nworld\n"`,
 			}},
 		},
+		{
+			Name:       "WithConfigTrimSpace quoted",
+			ConfigFile: "testdata/quoted.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigTrimSpace()},
+			Want:       fftest.Vars{S: `"  quoted  "`},
+		},
 		{
 			Name:       "WithConfigIgnoreUndefined not set",
 			ConfigFile: "testdata/undefined.conf",
@@ -354,13 +402,13 @@ func TestParse_PlainParser(t *testing.T) {
 		{
 			Name:       "WithConfigIgnoreUndefined is set",
 			ConfigFile: "testdata/undefined.conf",
-			Options:    []ff.Option{ff.WithConfigIgnoreUndefinedFlags()},
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigIgnoreUndefinedFlags()},
 			Want:       fftest.Vars{S: "one"},
 		},
 		{
 			Name:       "WithFilesystem",
 			ConfigFile: "testdata/1.conf",
-			Options:    []ff.Option{ff.WithFilesystem(testdataConfigFS)},
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithFilesystem(testdataConfigFS)},
 			Want:       fftest.Vars{S: "bar", I: 99, B: true, D: 1 * time.Hour},
 		},
 	}
@@ -368,63 +416,1749 @@ func TestParse_PlainParser(t *testing.T) {
 	testcases.Run(t)
 }
 
-func TestParse_types(t *testing.T) {
+func TestParse_PropertiesParser(t *testing.T) {
 	t.Parallel()
 
-	t.Run("ff.FlagSet", func(t *testing.T) {
-		fs := ff.NewFlagSet(t.Name())
-		foo := fs.String('f', "foo", "default-value", "foo string")
-		if err := ff.Parse(fs, []string{"--foo=bar"}); err != nil {
-			t.Fatal(err)
+	testcases := fftest.TestCases{
+		{
+			Name:       "basic",
+			ConfigFile: "testdata/basic.properties",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
+			Want: fftest.Vars{
+				S: "hello world",
+				I: 42,
+				D: time.Minute,
+				B: true,
+				X: []string{
+					"tab\there",
+					"caf\u00e9",
+					"a=b:c # not a comment",
+					`back\slash`,
+					`ends with backslash\`,
+					"multi line value",
+				},
+			},
+		},
+		{
+			Name:       "WithConfigIgnoreUndefined not set",
+			ConfigFile: "testdata/undefined.properties",
+			Want:       fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+		{
+			Name:       "WithConfigIgnoreUndefined is set",
+			ConfigFile: "testdata/undefined.properties",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigIgnoreUndefinedFlags()},
+			Want:       fftest.Vars{S: "one"},
+		},
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_ConfigValueExec(t *testing.T) {
+	t.Parallel()
+
+	execFunc := func(command string) (string, error) {
+		switch command {
+		case "get secret":
+			return "  hunter2\n", nil
+		default:
+			return "", fmt.Errorf("unknown command %q", command)
 		}
-		if want, have := "bar", *foo; want != have {
-			t.Errorf("foo: want %q, have %q", want, have)
+	}
+
+	testcases := fftest.TestCases{
+		{
+			Name:       "disabled",
+			ConfigFile: "testdata/exec.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigValueExecFunc(execFunc)},
+			Want:       fftest.Vars{S: "!get secret", X: []string{"literal", "!!bang"}},
+		},
+		{
+			Name:       "enabled",
+			ConfigFile: "testdata/exec.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigValueExec(), ff.WithConfigValueExecFunc(execFunc)},
+			Want:       fftest.Vars{S: "hunter2", X: []string{"literal", "!bang"}},
+		},
+		{
+			Name:       "args take priority",
+			ConfigFile: "testdata/exec.conf",
+			Args:       []string{"-s", "!get secret"},
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigValueExec(), ff.WithConfigValueExecFunc(execFunc)},
+			Want:       fftest.Vars{S: "!get secret", X: []string{"literal", "!bang"}},
+		},
+		{
+			Name:       "exec error",
+			ConfigFile: "testdata/exec.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithConfigValueExec(), ff.WithConfigValueExecFunc(func(string) (string, error) { return "", fmt.Errorf("kaboom") })},
+			Want:       fftest.Vars{WantParseErrorString: "kaboom"},
+		},
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_EnvVarIgnoreInvalid(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{"TEST_IGNORE_INVALID_I": "not-a-number", "TEST_IGNORE_INVALID_S": "from env"}
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "default",
+			Environment: env,
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_IGNORE_INVALID")},
+			Want:        fftest.Vars{WantParseErrorString: `TEST_IGNORE_INVALID_I="not-a-number"`},
+		},
+		{
+			Name:        "ignore invalid",
+			Environment: env,
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_IGNORE_INVALID"), ff.WithEnvVarIgnoreInvalid(nil)},
+			Want:        fftest.Vars{S: "from env"},
+		},
+		{
+			Name:        "ignore invalid with config",
+			Environment: env,
+			ConfigFile:  "testdata/1.conf",
+			Options:     []ff.Option{ff.WithConfigMatchShortNames(), ff.WithEnvVarPrefix("TEST_IGNORE_INVALID"), ff.WithEnvVarIgnoreInvalid(nil)},
+			Want:        fftest.Vars{S: "from env", I: 99, B: true, D: time.Hour},
+		},
+	}
+
+	testcases.Run(t)
+
+	t.Run("writer", func(t *testing.T) {
+		defer func(old string) { os.Setenv("TEST_IGNORE_INVALID_W_I", old) }(os.Getenv("TEST_IGNORE_INVALID_W_I"))
+		os.Setenv("TEST_IGNORE_INVALID_W_I", "abc")
+
+		var buf strings.Builder
+		fs, vars := fftest.CoreConstructor.Make(fftest.Vars{})
+		if err := ff.Parse(fs, []string{},
+			ff.WithEnvVarPrefix("TEST_IGNORE_INVALID_W"),
+			ff.WithEnvVarIgnoreInvalid(&buf),
+		); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := 0, vars.I; want != have {
+			t.Errorf("I: want %d, have %d", want, have)
+		}
+		if want, have := `ignoring invalid env var: TEST_IGNORE_INVALID_W_I="abc"`, buf.String(); !strings.HasPrefix(have, want) {
+			t.Errorf("want prefix %q, have %q", want, have)
 		}
 	})
+}
 
-	t.Run("flag.FlagSet", func(t *testing.T) {
-		fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
-		foo := fs.String("foo", "default-value", "foo string")
-		if err := ff.Parse(fs, []string{"-foo", "bar"}); err != nil {
-			t.Fatal(err)
+func TestGetFlagByEnvKey(t *testing.T) {
+	t.Parallel()
+
+	defer func(old string) { os.Setenv("TEST_BY_KEY_S", old) }(os.Getenv("TEST_BY_KEY_S"))
+	os.Setenv("TEST_BY_KEY_S", "via short name")
+
+	fs, vars := fftest.CoreConstructor.Make(fftest.Vars{})
+	if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_BY_KEY")); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := "via short name", vars.S; want != have {
+		t.Errorf("S: want %q, have %q", want, have)
+	}
+
+	for _, key := range []string{"TEST_BY_KEY_S", "TEST_BY_KEY_STR"} {
+		f, ok := ff.GetFlagByEnvKey(fs, key, "TEST_BY_KEY")
+		if !ok {
+			t.Errorf("%s: flag not found", key)
+			continue
 		}
-		if want, have := "bar", *foo; want != have {
-			t.Errorf("foo: want %q, have %q", want, have)
+		if long, _ := f.GetLongName(); long != "str" {
+			t.Errorf("%s: want --str, have --%s", key, long)
+		}
+	}
+
+	if f, ok := ff.GetFlagByEnvKey(fs, "STR", "TEST_BY_KEY"); ok {
+		t.Errorf("STR: want no flag, have %v", f)
+	}
+	if _, ok := ff.GetFlagByEnvKey(fs, "STR", ""); !ok {
+		t.Errorf("STR without prefix: flag not found")
+	}
+}
+
+func TestParse_ConfigMatchShortNames(t *testing.T) {
+	t.Parallel()
+
+	t.Run("default", func(t *testing.T) {
+		fs, _ := fftest.CoreConstructor.Make(fftest.Vars{})
+		err := ff.Parse(fs, []string{},
+			ff.WithConfigFile("testdata/1.conf"),
+			ff.WithConfigFileParser(ff.PlainParser),
+		)
+		if !errors.Is(err, ff.ErrUnknownFlag) {
+			t.Errorf("want %v, have %v", ff.ErrUnknownFlag, err)
 		}
 	})
 
-	t.Run("invalid type", func(t *testing.T) {
-		fs := "xxx" // should compile, but Parse should return an error
-		if err := ff.Parse(fs, []string{"-foo", "bar"}); err == nil {
-			t.Errorf("Parse(%T): want error, have none", fs)
+	t.Run("long names", func(t *testing.T) {
+		fs, vars := fftest.CoreConstructor.Make(fftest.Vars{})
+		if err := ff.Parse(fs, []string{},
+			ff.WithConfigFile("testdata/long_names.conf"),
+			ff.WithConfigFileParser(ff.PlainParser),
+		); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "foo", vars.S; want != have {
+			t.Errorf("S: want %q, have %q", want, have)
+		}
+		if want, have := 3, vars.I; want != have {
+			t.Errorf("I: want %d, have %d", want, have)
+		}
+	})
+
+	t.Run("WithConfigMatchShortNames", func(t *testing.T) {
+		fs, vars := fftest.CoreConstructor.Make(fftest.Vars{})
+		if err := ff.Parse(fs, []string{},
+			ff.WithConfigFile("testdata/1.conf"),
+			ff.WithConfigFileParser(ff.PlainParser),
+			ff.WithConfigMatchShortNames(),
+		); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "bar", vars.S; want != have {
+			t.Errorf("S: want %q, have %q", want, have)
+		}
+		if want, have := 99, vars.I; want != have {
+			t.Errorf("I: want %d, have %d", want, have)
+		}
+	})
+
+	t.Run("std flag set", func(t *testing.T) {
+		fs, vars := fftest.StdConstructor.Make(fftest.Vars{})
+		if err := ff.Parse(fs, []string{},
+			ff.WithConfigFile("testdata/1.conf"),
+			ff.WithConfigFileParser(ff.PlainParser),
+		); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "bar", vars.S; want != have {
+			t.Errorf("S: want %q, have %q", want, have)
 		}
 	})
 }
 
-func TestParse_stdfs(t *testing.T) {
+func TestParse_ListDefault(t *testing.T) {
 	t.Parallel()
 
-	configFile := filepath.Join(t.TempDir(), "config.conf")
-	if err := os.WriteFile(configFile, []byte(`foo hello`), 0655); err != nil {
-		t.Fatalf("WriteFile: %v", err)
+	for _, test := range []struct {
+		name string
+		args []string
+		file string
+		want []string
+	}{
+		{name: "default", want: []string{"a"}},
+		{name: "args", args: []string{"--tag", "b"}, want: []string{"b"}},
+		{name: "config", file: "testdata/list_default.conf", want: []string{"c", "d"}},
+		{name: "args and config", args: []string{"--tag=b"}, file: "testdata/list_default.conf", want: []string{"b"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var tags []string
+			fs := ff.NewFlagSet(t.Name())
+			fs.ValueLong("tag", &ffval.List[string]{Pointer: &tags, Default: []string{"a"}}, "tags")
+
+			if err := ff.Parse(fs, test.args,
+				ff.WithConfigFile(test.file),
+				ff.WithConfigFileParser(ff.PlainParser),
+			); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, tags; !reflect.DeepEqual(want, have) {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
 	}
+}
 
-	args := []string{"-config", configFile}
+func TestParse_EnumCaseInsensitive(t *testing.T) {
+	t.Parallel()
 
-	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
-	var (
-		foo = fs.String("foo", "abc", "foo string")
-		_   = fs.String("config", "", "config file")
-	)
+	t.Run("default", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.StringEnum('l', "log", "log level", "info", "debug")
+		if err := ff.Parse(fs, []string{"--log", "DEBUG"}); !errors.Is(err, ffval.ErrInvalidValue) {
+			t.Errorf("want %v, have %v", ffval.ErrInvalidValue, err)
+		}
+	})
 
-	if err := ff.Parse(fs, args,
-		ff.WithConfigFileFlag("config"),
-		ff.WithConfigFileParser(ff.PlainParser),
+	t.Run("WithEnumCaseInsensitive", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		level := fs.StringEnum('l', "log", "log level", "info", "debug")
+		mode := fs.StringEnumLong("mode", "mode", "fast", "slow")
+		if err := ff.Parse(fs, []string{"--log", "DEBUG", "--mode=Slow"}, ff.WithEnumCaseInsensitive()); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "debug", *level; want != have {
+			t.Errorf("log: want %q, have %q", want, have)
+		}
+		if want, have := "slow", *mode; want != have {
+			t.Errorf("mode: want %q, have %q", want, have)
+		}
+
+		if err := fs.Reset(); err != nil {
+			t.Fatalf("Reset: %v", err)
+		}
+		if err := ff.Parse(fs, []string{"--log", "DEBUG"}); !errors.Is(err, ffval.ErrInvalidValue) {
+			t.Errorf("re-parse without option: want %v, have %v", ffval.ErrInvalidValue, err)
+		}
+	})
+}
+
+func TestParse_ConfigFileParserInfo(t *testing.T) {
+	t.Parallel()
+
+	fs, vars := fftest.CoreConstructor.Make(fftest.Vars{})
+	if err := ff.Parse(fs, []string{},
+		ff.WithEnvVarPrefix("TEST_PARSE_INFO"),
+		ff.WithConfigFile("testdata/parse_info.conf"),
+		ff.WithConfigFileParserInfo(prefixParser{}),
 	); err != nil {
 		t.Fatalf("Parse: %v", err)
 	}
+	if want, have := "hello", vars.S; want != have {
+		t.Errorf("S: want %q, have %q", want, have)
+	}
+	if want, have := 42, vars.I; want != have {
+		t.Errorf("I: want %d, have %d", want, have)
+	}
+}
 
-	if want, have := "hello", *foo; want != have {
-		t.Errorf("foo: want %q, have %q", want, have)
+// prefixParser parses KEY=value lines, considering only keys with the env var
+// prefix, and translating those keys to flag names.
+type prefixParser struct{}
+
+func (prefixParser) Parse(r io.Reader, set func(name, value string) error, info ff.ParseInfo) error {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		key, value, ok := strings.Cut(s.Text(), "=")
+		if !ok {
+			continue
+		}
+		name, ok := strings.CutPrefix(key, info.EnvVarPrefix+"_")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(name)
+		if _, ok := info.Flags.GetFlag(name); !ok {
+			continue
+		}
+		if err := set(name, value); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
+
+func TestParse_ConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	testcases := fftest.TestCases{
+		{
+			Name:    "later files win",
+			Options: []ff.Option{ff.WithConfigFiles("testdata/1.conf", "testdata/4.conf")},
+			Want:    fftest.Vars{S: "from file", I: 200, B: true, D: time.Minute, F: 2.3},
+		},
+		{
+			Name:    "reversed",
+			Options: []ff.Option{ff.WithConfigFiles("testdata/4.conf", "testdata/1.conf")},
+			Want:    fftest.Vars{S: "bar", I: 99, B: true, D: time.Hour, F: 2.3},
+		},
+		{
+			Name:    "args win",
+			Args:    []string{"--str=cli"},
+			Options: []ff.Option{ff.WithConfigFiles("testdata/1.conf", "testdata/4.conf")},
+			Want:    fftest.Vars{S: "cli", I: 200, B: true, D: time.Minute, F: 2.3},
+		},
+		{
+			Name:        "env vars win",
+			Environment: map[string]string{"TEST_CONFIG_FILES_INT": "7"},
+			Options:     []ff.Option{ff.WithConfigFiles("testdata/1.conf", "testdata/4.conf"), ff.WithEnvVarPrefix("TEST_CONFIG_FILES")},
+			Want:        fftest.Vars{S: "from file", I: 7, B: true, D: time.Minute, F: 2.3},
+		},
+		{
+			Name:    "lists are not merged",
+			Options: []ff.Option{ff.WithConfigFiles("testdata/5.conf", "testdata/layered.conf")},
+			Want:    fftest.Vars{S: "s.file.2", X: []string{"x.layered"}},
+		},
+		{
+			Name:    "missing file",
+			Options: []ff.Option{ff.WithConfigFiles("testdata/this_file_does_not_exist.conf", "testdata/1.conf")},
+			Want:    fftest.Vars{WantParseErrorIs: os.ErrNotExist},
+		},
+		{
+			Name:    "missing file allowed",
+			Options: []ff.Option{ff.WithConfigFiles("testdata/this_file_does_not_exist.conf", "testdata/1.conf"), ff.WithConfigAllowMissingFile()},
+			Want:    fftest.Vars{S: "bar", I: 99, B: true, D: time.Hour},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+		testcases[i].Options = append(testcases[i].Options, ff.WithConfigFileParser(ff.PlainParser), ff.WithConfigMatchShortNames())
+	}
+
+	testcases.Run(t)
+
+	t.Run("list flag", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.StringList('c', "config", "config files")
+		str := fs.StringLong("str", "", "string")
+		dur := fs.DurationLong("d", 0, "duration")
+
+		var used string
+		if err := ff.Parse(fs, []string{"-c", "testdata/3.conf", "-c", "testdata/long_names.conf"},
+			ff.WithConfigFileFlag("config"),
+			ff.WithConfigFileParser(ff.PlainParser),
+			ff.WithConfigFileUsed(&used),
+			ff.WithConfigIgnoreUndefinedFlags(),
+		); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		if want, have := "foo", *str; want != have {
+			t.Errorf("str: want %q, have %q", want, have)
+		}
+		if want, have := 34*time.Second, *dur; want != have {
+			t.Errorf("d: want %v, have %v", want, have)
+		}
+		if want, have := "testdata/long_names.conf", used; want != have {
+			t.Errorf("config file used: want %q, have %q", want, have)
+		}
+	})
+}
+
+func TestParse_XDGConfig(t *testing.T) {
+	t.Parallel()
+
+	var (
+		root     = t.TempDir()
+		xdgHome  = filepath.Join(root, "xdg")
+		userHome = filepath.Join(root, "home")
+		empty    = filepath.Join(root, "empty")
+	)
+	for file, content := range map[string]string{
+		filepath.Join(xdgHome, "myapp", "config"):             "str xdg\n",
+		filepath.Join(userHome, ".config", "myapp", "config"): "str home\nint 5\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getenv := func(env map[string]string) ff.Option {
+		return ff.WithXDGGetenv(func(key string) string { return env[key] })
+	}
+
+	testcases := fftest.TestCases{
+		{
+			Name:    "XDG_CONFIG_HOME",
+			Options: []ff.Option{getenv(map[string]string{"XDG_CONFIG_HOME": xdgHome, "HOME": userHome})},
+			Want:    fftest.Vars{S: "xdg"},
+		},
+		{
+			Name:    "HOME",
+			Options: []ff.Option{getenv(map[string]string{"HOME": userHome})},
+			Want:    fftest.Vars{S: "home", I: 5},
+		},
+		{
+			Name:    "fall back to HOME",
+			Options: []ff.Option{getenv(map[string]string{"XDG_CONFIG_HOME": empty, "HOME": userHome})},
+			Want:    fftest.Vars{S: "home", I: 5},
+		},
+		{
+			Name:    "missing",
+			Options: []ff.Option{getenv(map[string]string{"XDG_CONFIG_HOME": empty, "HOME": empty})},
+			Want:    fftest.Vars{WantParseErrorIs: os.ErrNotExist},
+		},
+		{
+			Name:    "missing allowed",
+			Options: []ff.Option{getenv(map[string]string{"XDG_CONFIG_HOME": empty, "HOME": empty}), ff.WithConfigAllowMissingFile()},
+			Want:    fftest.Vars{},
+		},
+		{
+			Name:    "explicit config file wins",
+			Options: []ff.Option{getenv(map[string]string{"XDG_CONFIG_HOME": xdgHome}), ff.WithConfigFile("testdata/long_names.conf")},
+			Want:    fftest.Vars{S: "foo", I: 3},
+		},
+		{
+			Name:    "args win",
+			Args:    []string{"--str=cli"},
+			Options: []ff.Option{getenv(map[string]string{"HOME": userHome})},
+			Want:    fftest.Vars{S: "cli", I: 5},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+		testcases[i].Options = append(testcases[i].Options, ff.WithXDGConfig("myapp", ff.PlainParser))
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_ConfigFileEnvVar(t *testing.T) {
+	t.Parallel()
+
+	opts := func(extra ...ff.Option) []ff.Option {
+		return append([]ff.Option{
+			ff.WithEnvVarPrefix("TEST_CONFIG_ENV_VAR"),
+			ff.WithConfigFileEnvVar("config"),
+			ff.WithConfigFileParser(ff.PlainParser),
+		}, extra...)
+	}
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "present",
+			Environment: map[string]string{"TEST_CONFIG_ENV_VAR_CONFIG": "testdata/long_names.conf"},
+			Options:     opts(),
+			Want:        fftest.Vars{S: "foo", I: 3},
+		},
+		{
+			Name:    "unset",
+			Options: opts(),
+			Want:    fftest.Vars{},
+		},
+		{
+			Name:        "missing",
+			Environment: map[string]string{"TEST_CONFIG_ENV_VAR_CONFIG": "testdata/this_file_does_not_exist.conf"},
+			Options:     opts(),
+			Want:        fftest.Vars{WantParseErrorIs: os.ErrNotExist},
+		},
+		{
+			Name:        "missing allowed",
+			Environment: map[string]string{"TEST_CONFIG_ENV_VAR_CONFIG": "testdata/this_file_does_not_exist.conf"},
+			Options:     opts(ff.WithConfigAllowMissingFile()),
+			Want:        fftest.Vars{},
+		},
+		{
+			Name:        "overridden by file",
+			Environment: map[string]string{"TEST_CONFIG_ENV_VAR_CONFIG": "testdata/this_file_does_not_exist.conf"},
+			Options:     opts(ff.WithConfigFile("testdata/long_names.conf")),
+			Want:        fftest.Vars{S: "foo", I: 3},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
 	}
+
+	testcases.Run(t)
+}
+
+func TestParse_ConfigFileVia(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		def     string
+		args    []string
+		options []ff.Option
+		wantStr string
+		wantErr error
+	}{
+		{
+			name:    "from args",
+			args:    []string{"--config", "testdata/long_names.conf"},
+			wantStr: "foo",
+		},
+		{
+			name:    "from default",
+			def:     "testdata/long_names.conf",
+			wantStr: "foo",
+		},
+		{
+			name:    "args take precedence",
+			args:    []string{"--config", "testdata/long_names.conf", "--str", "bar"},
+			wantStr: "bar",
+		},
+		{
+			name: "empty",
+		},
+		{
+			name:    "missing",
+			args:    []string{"--config", "testdata/this_file_does_not_exist.conf"},
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name:    "missing allowed",
+			args:    []string{"--config", "testdata/this_file_does_not_exist.conf"},
+			options: []ff.Option{ff.WithConfigAllowMissingFile()},
+		},
+		{
+			name:    "overridden by file",
+			args:    []string{"--config", "testdata/this_file_does_not_exist.conf"},
+			options: []ff.Option{ff.WithConfigFile("testdata/long_names.conf")},
+			wantStr: "foo",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name())
+			config := fs.StringLong("config", test.def, "config file")
+			str := fs.StringLong("str", "", "string")
+			fs.IntLong("int", 0, "int")
+
+			options := append([]ff.Option{
+				ff.WithConfigFileVia(config),
+				ff.WithConfigFileParser(ff.PlainParser),
+			}, test.options...)
+			err := ff.Parse(fs, test.args, options...)
+			switch {
+			case test.wantErr != nil && !errors.Is(err, test.wantErr):
+				t.Fatalf("Parse: want %v, have %v", test.wantErr, err)
+			case test.wantErr == nil && err != nil:
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.wantStr, *str; want != have {
+				t.Errorf("str: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestParse_EnvVarLowercase(t *testing.T) {
+	t.Parallel()
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "lowercase keys",
+			Environment: map[string]string{"test_lowercase_str": "foo", "test_lowercase_i": "5"},
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_LOWERCASE"), ff.WithEnvVarLowercase()},
+			Want:        fftest.Vars{S: "foo", I: 5},
+		},
+		{
+			Name:        "uppercase keys ignored",
+			Environment: map[string]string{"TEST_LOWERCASE_UPPER_STR": "foo"},
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_LOWERCASE_UPPER"), ff.WithEnvVarLowercase()},
+			Want:        fftest.Vars{},
+		},
+		{
+			Name:        "lowercase keys ignored by default",
+			Environment: map[string]string{"test_lowercase_default_str": "foo"},
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_LOWERCASE_DEFAULT")},
+			Want:        fftest.Vars{},
+		},
+		{
+			Name:        "no prefix",
+			Environment: map[string]string{"aflag": "true"},
+			Options:     []ff.Option{ff.WithEnvVarLowercase()},
+			Want:        fftest.Vars{A: true},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_EnvVarPrefixFromName(t *testing.T) {
+	t.Parallel()
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "derived prefix",
+			Environment: map[string]string{"FFTEST_STR": "foo", "FFTEST_I": "5"},
+			Options:     []ff.Option{ff.WithEnvVarPrefixFromName()},
+			Want:        fftest.Vars{S: "foo", I: 5},
+		},
+		{
+			Name:        "explicit prefix wins if later",
+			Environment: map[string]string{"FFTEST_FLT": "1.5", "TEST_PREFIX_FROM_NAME_FLT": "2.5"},
+			Options:     []ff.Option{ff.WithEnvVarPrefixFromName(), ff.WithEnvVarPrefix("TEST_PREFIX_FROM_NAME")},
+			Want:        fftest.Vars{F: 2.5},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+
+	t.Run("sanitized", func(t *testing.T) {
+		key := "MY_PROG_V2_TEST_SANITIZED_NAME_NAME"
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, "bar")
+
+		fs := ff.NewFlagSet("-my-prog.v2/test sanitized-name")
+		name := fs.StringLong("name", "", "name")
+		if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefixFromName()); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "bar", *name; want != have {
+			t.Errorf("name: want %q, have %q", want, have)
+		}
+	})
+}
+
+func TestParse_EnvVarInvalidEnum(t *testing.T) {
+	t.Parallel()
+
+	key := "TEST_INVALID_ENUM_LEVEL"
+	defer os.Setenv(key, os.Getenv(key))
+	os.Setenv(key, "loud")
+
+	newFlagSet := func() (*ff.FlagSet, *string) {
+		fs := ff.NewFlagSet(t.Name())
+		level := fs.StringEnum('l', "level", "log level", "debug", "info", "warn")
+		return fs, level
+	}
+
+	fs, _ := newFlagSet()
+	err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_INVALID_ENUM"))
+	if !errors.Is(err, ffval.ErrInvalidValue) {
+		t.Fatalf("want %v, have %v", ffval.ErrInvalidValue, err)
+	}
+	for _, want := range []string{key, `"loud"`, "--level", "valid: debug, info, warn"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't contain %q", err.Error(), want)
+		}
+	}
+
+	fs, level := newFlagSet()
+	if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_INVALID_ENUM"), ff.WithEnvVarIgnoreInvalid(io.Discard)); err != nil {
+		t.Fatalf("Parse with WithEnvVarIgnoreInvalid: %v", err)
+	}
+	if want, have := "debug", *level; want != have {
+		t.Errorf("level: want %q, have %q", want, have)
+	}
+}
+
+func TestParse_EnvVarList(t *testing.T) {
+	t.Parallel()
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "basic",
+			Environment: map[string]string{"TEST_ENV_LIST_BASIC": "--str=hello -i 3 --aflag"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_BASIC")},
+			Want:        fftest.Vars{S: "hello", I: 3, A: true},
+		},
+		{
+			Name:        "args have priority",
+			Environment: map[string]string{"TEST_ENV_LIST_ARGS": "--str=env --int=2"},
+			Args:        []string{"--str=cli"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_ARGS")},
+			Want:        fftest.Vars{S: "cli", I: 2},
+		},
+		{
+			Name:        "priority over env vars",
+			Environment: map[string]string{"TEST_ENV_LIST_PRIO": "--str=list", "TEST_ENV_LIST_PRIO_STR": "env", "TEST_ENV_LIST_PRIO_INT": "7"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_PRIO"), ff.WithEnvVarPrefix("TEST_ENV_LIST_PRIO")},
+			Want:        fftest.Vars{S: "list", I: 7},
+		},
+		{
+			Name:        "quoting",
+			Environment: map[string]string{"TEST_ENV_LIST_QUOTE": `--str 'hello world' --dur="1s" -x a\ b -x "say \"hi\""`},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_QUOTE")},
+			Want:        fftest.Vars{S: "hello world", D: time.Second, X: []string{"a b", `say "hi"`}},
+		},
+		{
+			Name:    "empty",
+			Options: []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_EMPTY")},
+			Want:    fftest.Vars{},
+		},
+		{
+			Name:        "clustered short flags",
+			Environment: map[string]string{"TEST_ENV_LIST_CLUSTER": "-ab -i4"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_CLUSTER")},
+			Want:        fftest.Vars{A: true, B: true, I: 4},
+		},
+		{
+			Name:        "clustered short flags with args",
+			Environment: map[string]string{"TEST_ENV_LIST_CLUSTER_ARGS": "-ab"},
+			Args:        []string{"--aflag=false"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_CLUSTER_ARGS")},
+			Want:        fftest.Vars{B: true},
+		},
+		{
+			Name:        "triple dash",
+			Environment: map[string]string{"TEST_ENV_LIST_TRIPLE": "---int=4"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_TRIPLE")},
+			Want:        fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+		{
+			Name:        "unknown flag",
+			Environment: map[string]string{"TEST_ENV_LIST_UNKNOWN": "--str=hello --nope=1"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_UNKNOWN")},
+			Want:        fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+		{
+			Name:        "unknown flag ignored",
+			Environment: map[string]string{"TEST_ENV_LIST_IGNORE": "--nope=1 --str=hello"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_IGNORE"), ff.WithEnvVarIgnoreInvalid(nil)},
+			Want:        fftest.Vars{S: "hello"},
+		},
+		{
+			Name:        "positional arg",
+			Environment: map[string]string{"TEST_ENV_LIST_POSITIONAL": "--str=hello extra"},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_POSITIONAL")},
+			Want:        fftest.Vars{WantParseErrorString: `unexpected argument "extra"`},
+		},
+		{
+			Name:        "unterminated quote",
+			Environment: map[string]string{"TEST_ENV_LIST_UNTERMINATED": `--str 'hello`},
+			Options:     []ff.Option{ff.WithEnvVarList("TEST_ENV_LIST_UNTERMINATED")},
+			Want:        fftest.Vars{WantParseErrorString: "unterminated"},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_AfterParse(t *testing.T) {
+	t.Parallel()
+
+	errInvalid := errors.New("invalid")
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "sees final values",
+			Args:        []string{"--str=cli"},
+			Environment: map[string]string{"TEST_AFTER_PARSE_INT": "7"},
+			ConfigFile:  "testdata/4.conf",
+			Options: []ff.Option{
+				ff.WithEnvVarPrefix("TEST_AFTER_PARSE"),
+				ff.WithConfigFileParser(ff.PlainParser),
+				ff.WithConfigMatchShortNames(),
+				ff.WithAfterParse(func(fs ff.Flags) error {
+					want := map[string]string{"str": "cli", "int": "7", "dur": "1m0s", "flt": "2.3"}
+					for name, value := range want {
+						f, ok := fs.GetFlag(name)
+						if !ok {
+							return fmt.Errorf("%s: %w", name, ff.ErrUnknownFlag)
+						}
+						if f.GetValue() != value {
+							return fmt.Errorf("%s: want %q, have %q", name, value, f.GetValue())
+						}
+					}
+					return nil
+				}),
+			},
+			Want: fftest.Vars{S: "cli", I: 7, D: time.Minute, F: 2.3},
+		},
+		{
+			Name: "error propagates",
+			Options: []ff.Option{
+				ff.WithAfterParse(func(ff.Flags) error { return nil }),
+				ff.WithAfterParse(func(ff.Flags) error { return errInvalid }),
+			},
+			Want: fftest.Vars{WantParseErrorIs: errInvalid},
+		},
+		{
+			Name: "not called on parse error",
+			Args: []string{"--nope"},
+			Options: []ff.Option{
+				ff.WithAfterParse(func(ff.Flags) error { return errInvalid }),
+			},
+			Want: fftest.Vars{WantParseErrorIs: ff.ErrUnknownFlag},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_BooleanPresenceTrue(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{"TEST_BOOL_PRESENCE_B": "", "TEST_BOOL_PRESENCE_S": "from env"}
+
+	testcases := fftest.TestCases{
+		{
+			Name:        "env without option",
+			Environment: env,
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_BOOL_PRESENCE")},
+			Want:        fftest.Vars{S: "from env"},
+		},
+		{
+			Name:        "env with option",
+			Environment: env,
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_BOOL_PRESENCE"), ff.WithBooleanPresenceTrue()},
+			Want:        fftest.Vars{S: "from env", B: true},
+		},
+		{
+			Name:       "plain without option",
+			ConfigFile: "testdata/solo_bool.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames()},
+			Want:       fftest.Vars{S: "x", B: true},
+		},
+		{
+			Name:       "plain with option",
+			ConfigFile: "testdata/solo_bool.conf",
+			Options:    []ff.Option{ff.WithConfigMatchShortNames(), ff.WithBooleanPresenceTrue()},
+			Want:       fftest.Vars{S: "x", B: true},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_ConfigFileUsed(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name string
+		file string
+		want string
+	}{
+		{name: "present", file: "testdata/long_names.conf", want: "testdata/long_names.conf"},
+		{name: "missing", file: "testdata/this_file_does_not_exist.conf", want: ""},
+		{name: "none", file: "", want: ""},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs, _ := fftest.CoreConstructor.Make(fftest.Vars{})
+			used := "should be overwritten"
+			if err := ff.Parse(fs, []string{},
+				ff.WithConfigFile(test.file),
+				ff.WithConfigFileParser(ff.PlainParser),
+				ff.WithConfigAllowMissingFile(),
+				ff.WithConfigFileUsed(&used),
+			); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.want, used; want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestParse_StageTimings(t *testing.T) {
+	t.Parallel()
+
+	fs, _ := fftest.CoreConstructor.Make(fftest.Vars{})
+	timings := ff.ParseTimings{ArgsDuration: time.Hour, EnvDuration: time.Hour, ConfigDuration: time.Hour}
+	if err := ff.Parse(fs, []string{"--str=x"},
+		ff.WithEnvVars(),
+		ff.WithConfigFile("testdata/long_names.conf"),
+		ff.WithConfigFileParser(ff.PlainParser),
+		ff.WithStageTimings(&timings),
+	); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for name, d := range map[string]time.Duration{
+		"args":   timings.ArgsDuration,
+		"env":    timings.EnvDuration,
+		"config": timings.ConfigDuration,
+	} {
+		if d < 0 || d >= time.Hour {
+			t.Errorf("%s: want non-negative fresh duration, have %v", name, d)
+		}
+	}
+	if timings.ConfigDuration <= 0 {
+		t.Errorf("config: want positive duration, have %v", timings.ConfigDuration)
+	}
+}
+
+func TestParse_ValueTransform(t *testing.T) {
+	t.Parallel()
+
+	expandHome := func(_ ff.Flag, value string) (string, error) {
+		if rest, ok := strings.CutPrefix(value, "~/"); ok {
+			return "/home/test/" + rest, nil
+		}
+		return value, nil
+	}
+
+	testcases := fftest.TestCases{
+		{
+			Name:    "args",
+			Args:    []string{"--str", "~/from/args"},
+			Options: []ff.Option{ff.WithValueTransform(expandHome)},
+			Want:    fftest.Vars{S: "/home/test/from/args"},
+		},
+		{
+			Name:    "args short",
+			Args:    []string{"-s", "~/from/args"},
+			Options: []ff.Option{ff.WithValueTransform(expandHome)},
+			Want:    fftest.Vars{S: "/home/test/from/args"},
+		},
+		{
+			Name:        "env",
+			Environment: map[string]string{"TEST_VALUE_TRANSFORM_STR": "~/from/env"},
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_VALUE_TRANSFORM"), ff.WithValueTransform(expandHome)},
+			Want:        fftest.Vars{S: "/home/test/from/env"},
+		},
+		{
+			Name:       "config",
+			ConfigFile: "testdata/value_transform.conf",
+			Options:    []ff.Option{ff.WithValueTransform(expandHome)},
+			Want:       fftest.Vars{S: "/home/test/from/config"},
+		},
+		{
+			Name:       "no transform",
+			ConfigFile: "testdata/value_transform.conf",
+			Want:       fftest.Vars{S: "~/from/config"},
+		},
+		{
+			Name:    "error",
+			Args:    []string{"--str=x"},
+			Options: []ff.Option{ff.WithValueTransform(func(ff.Flag, string) (string, error) { return "", errors.New("nope") })},
+			Want:    fftest.Vars{WantParseErrorString: `transform "x": nope`},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_DecimalSeparator(t *testing.T) {
+	t.Parallel()
+
+	upper := func(_ ff.Flag, value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}
+
+	testcases := fftest.TestCases{
+		{
+			Name:    "args",
+			Args:    []string{"--flt", "3,14"},
+			Options: []ff.Option{ff.WithDecimalSeparator(',')},
+			Want:    fftest.Vars{F: 3.14},
+		},
+		{
+			Name:        "env",
+			Environment: map[string]string{"TEST_DECIMAL_SEPARATOR_FLT": "2,5"},
+			Options:     []ff.Option{ff.WithEnvVarPrefix("TEST_DECIMAL_SEPARATOR"), ff.WithDecimalSeparator(',')},
+			Want:        fftest.Vars{F: 2.5},
+		},
+		{
+			Name:    "dot still works",
+			Args:    []string{"--flt", "3.14"},
+			Options: []ff.Option{ff.WithDecimalSeparator(',')},
+			Want:    fftest.Vars{F: 3.14},
+		},
+		{
+			Name:    "non-float unaffected",
+			Args:    []string{"--flt", "1,5", "--str", "a,b"},
+			Options: []ff.Option{ff.WithDecimalSeparator(','), ff.WithValueTransform(upper)},
+			Want:    fftest.Vars{F: 1.5, S: "A,B"},
+		},
+		{
+			Name: "default rejects",
+			Args: []string{"--flt", "3,14"},
+			Want: fftest.Vars{WantParseErrorString: `"3,14"`},
+		},
+	}
+
+	for i := range testcases {
+		testcases[i].Constructors = []fftest.Constructor{fftest.CoreConstructor}
+	}
+
+	testcases.Run(t)
+}
+
+func TestParse_ConfigSuggestion(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		file string
+		want string
+	}{
+		{file: "testdata/typo.conf", want: `strr: unknown flag (did you mean "str"?)`},
+		{file: "testdata/undefined.conf", want: `undef: unknown flag`},
+	} {
+		t.Run(test.file, func(t *testing.T) {
+			fs, _ := fftest.CoreConstructor.Make(fftest.Vars{})
+			err := ff.Parse(fs, []string{},
+				ff.WithConfigFile(test.file),
+				ff.WithConfigFileParser(ff.PlainParser),
+			)
+			if !errors.Is(err, ff.ErrUnknownFlag) {
+				t.Fatalf("want %v, have %v", ff.ErrUnknownFlag, err)
+			}
+			if want, have := "parse config file: "+test.want, err.Error(); want != have {
+				t.Errorf("want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestParse_FlagTemplates(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		name    string
+		args    []string
+		wantLog string
+		wantErr string
+	}{
+		{
+			name:    "simple reference",
+			args:    []string{"--data-dir=/var/lib/app", "--log-file={{.DataDir}}/app.log"},
+			wantLog: "/var/lib/app/app.log",
+		},
+		{
+			name:    "chained reference",
+			args:    []string{"--log-file={{.Base}}.log", "--base={{.DataDir}}/{{.Name}}"},
+			wantLog: "/data/myapp.log",
+		},
+		{
+			name:    "non-string reference",
+			args:    []string{"--log-file=app-{{.Port}}.log", "--port=8080"},
+			wantLog: "app-8080.log",
+		},
+		{
+			name:    "literal value",
+			args:    []string{"--log-file=/tmp/app.log"},
+			wantLog: "/tmp/app.log",
+		},
+		{
+			name:    "missing reference",
+			args:    []string{"--log-file={{.Nope}}/app.log"},
+			wantErr: `map has no entry for key "Nope"`,
+		},
+		{
+			name:    "cycle",
+			args:    []string{"--log-file={{.Base}}", "--base={{.LogFile}}"},
+			wantErr: "template cycle detected",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs := ff.NewFlagSet(t.Name())
+			fs.StringLong("data-dir", "/data", "data directory")
+			fs.StringLong("name", "myapp", "app name")
+			fs.StringLong("base", "", "base path")
+			fs.IntLong("port", 0, "port number")
+			logFile := fs.StringLong("log-file", "", "log file")
+
+			err := ff.Parse(fs, test.args, ff.WithFlagTemplates())
+			switch {
+			case test.wantErr == "" && err != nil:
+				t.Fatalf("Parse: %v", err)
+			case test.wantErr != "" && err == nil:
+				t.Fatalf("Parse: want error, have none")
+			case test.wantErr != "" && !strings.Contains(err.Error(), test.wantErr):
+				t.Fatalf("Parse: want error containing %q, have %v", test.wantErr, err)
+			case test.wantErr != "":
+				return
+			}
+
+			if want, have := test.wantLog, *logFile; want != have {
+				t.Errorf("log-file: want %q, have %q", want, have)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.StringLong("data-dir", "/data", "data directory")
+		logFile := fs.StringLong("log-file", "{{.DataDir}}/app.log", "log file")
+		if err := ff.Parse(fs, []string{}); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "{{.DataDir}}/app.log", *logFile; want != have {
+			t.Errorf("log-file: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("templated default is unset", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.StringLong("data-dir", "/data", "data directory")
+		logFile := fs.StringLong("log-file", "{{.DataDir}}/app.log", "log file")
+		fs.StringLong("syslog", "", "syslog address")
+		if err := fs.MarkMutuallyExclusive("log-file", "syslog"); err != nil {
+			t.Fatalf("MarkMutuallyExclusive: %v", err)
+		}
+		if err := ff.Parse(fs, []string{"--syslog=udp://x"}, ff.WithFlagTemplates()); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "/data/app.log", *logFile; want != have {
+			t.Errorf("log-file: want %q, have %q", want, have)
+		}
+		if f, _ := fs.GetFlag("log-file"); f.IsSet() {
+			t.Errorf("log-file: want unset, have set")
+		}
+	})
+
+	t.Run("templated default doesn't satisfy required", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		fs.StringLong("data-dir", "/data", "data directory")
+		if _, err := fs.AddFlag(ff.FlagConfig{
+			LongName: "log-file",
+			Value:    ffval.NewValueDefault(new(string), "{{.DataDir}}/app.log"),
+			Usage:    "log file",
+			Required: true,
+		}); err != nil {
+			t.Fatalf("AddFlag: %v", err)
+		}
+		if err := ff.Parse(fs, []string{}, ff.WithFlagTemplates()); !errors.Is(err, ff.ErrMissingRequired) {
+			t.Errorf("want %v, have %v", ff.ErrMissingRequired, err)
+		}
+	})
+}
+
+func TestParse_types(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ff.FlagSet", func(t *testing.T) {
+		fs := ff.NewFlagSet(t.Name())
+		foo := fs.String('f', "foo", "default-value", "foo string")
+		if err := ff.Parse(fs, []string{"--foo=bar"}); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "bar", *foo; want != have {
+			t.Errorf("foo: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("flag.FlagSet", func(t *testing.T) {
+		fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+		foo := fs.String("foo", "default-value", "foo string")
+		if err := ff.Parse(fs, []string{"-foo", "bar"}); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "bar", *foo; want != have {
+			t.Errorf("foo: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		fs := "xxx" // should compile, but Parse should return an error
+		if err := ff.Parse(fs, []string{"-foo", "bar"}); err == nil {
+			t.Errorf("Parse(%T): want error, have none", fs)
+		}
+	})
+}
+
+func TestParse_stdfs(t *testing.T) {
+	t.Parallel()
+
+	configFile := filepath.Join(t.TempDir(), "config.conf")
+	if err := os.WriteFile(configFile, []byte(`foo hello`), 0655); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	args := []string{"-config", configFile}
+
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	var (
+		foo = fs.String("foo", "abc", "foo string")
+		_   = fs.String("config", "", "config file")
+	)
+
+	if err := ff.Parse(fs, args,
+		ff.WithConfigFileFlag("config"),
+		ff.WithConfigFileParser(ff.PlainParser),
+	); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	if want, have := "hello", *foo; want != have {
+		t.Errorf("foo: want %q, have %q", want, have)
+	}
+}
+
+func TestParse_Required(t *testing.T) {
+	t.Parallel()
+
+	newFlagSet := func() *ff.FlagSet {
+		fs := ff.NewFlagSet(t.Name())
+		for _, cfg := range []ff.FlagConfig{
+			{ShortName: 'a', LongName: "alpha", Value: new(ffval.String), Required: true},
+			{LongName: "beta", Value: new(ffval.Int), Required: true},
+			{LongName: "gamma", Value: new(ffval.String)},
+		} {
+			if _, err := fs.AddFlag(cfg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fs
+	}
+
+	err := ff.Parse(newFlagSet(), []string{"--gamma=g"})
+	if !errors.Is(err, ff.ErrMissingRequired) {
+		t.Fatalf("want ErrMissingRequired, have %v", err)
+	}
+	if errors.Is(err, ff.ErrHelp) {
+		t.Errorf("want no ErrHelp without WithRequiredHelp, have %v", err)
+	}
+	if want, have := "missing required flag: -a, --alpha, --beta", err.Error(); want != have {
+		t.Errorf("error: want %q, have %q", want, have)
+	}
+
+	configFile := filepath.Join(t.TempDir(), "required.conf")
+	if err := os.WriteFile(configFile, []byte("beta 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ff.Parse(newFlagSet(), []string{"-a", "x"}, ff.WithConfigFile(configFile), ff.WithConfigFileParser(ff.PlainParser)); err != nil {
+		t.Errorf("want config file to satisfy required flag, have %v", err)
+	}
+
+	key := "TEST_REQUIRED_ALPHA"
+	defer os.Setenv(key, os.Getenv(key))
+	os.Setenv(key, "y")
+	if err := ff.Parse(newFlagSet(), []string{"--beta=2"}, ff.WithEnvVarPrefix("TEST_REQUIRED")); err != nil {
+		t.Errorf("want env var to satisfy required flag, have %v", err)
+	}
+
+	t.Run("struct tag", func(t *testing.T) {
+		var cfg struct {
+			Name string `ff:"long=name, required"`
+			Port int    `ff:"long=port, default=8080"`
+		}
+		fs := ff.NewFlagSet(t.Name())
+		if err := fs.AddStruct(&cfg); err != nil {
+			t.Fatalf("AddStruct: %v", err)
+		}
+		err := ff.Parse(fs, []string{"--port=1"})
+		if want, have := "missing required flag: --name", fmt.Sprint(err); want != have {
+			t.Errorf("error: want %q, have %q", want, have)
+		}
+	})
+}
+
+func TestParse_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	type vars struct {
+		name    *string
+		port    *int
+		debug   *bool
+		timeout *time.Duration
+		tags    *[]string
+	}
+
+	newFlagSet := func() (*ff.FlagSet, vars) {
+		fs := ff.NewFlagSet(t.Name())
+		return fs, vars{
+			name:    fs.String('n', "name", "", "name"),
+			port:    fs.IntLong("port", 8080, "port"),
+			debug:   fs.BoolShort('d', "debug"),
+			timeout: fs.DurationLong("timeout", time.Second, "timeout"),
+			tags:    fs.StringList('t', "tag", "tags"),
+		}
+	}
+
+	src, _ := newFlagSet()
+	if err := ff.Parse(src, []string{"-n", "foo", "--port=9090", "-d", "--timeout=5s", "-t", "a", "-t", "b"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	snapshot, err := src.MarshalJSON()
+	if err != nil {
+		t.Fatalf("MarshalJSON: %v", err)
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		fs, v := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithSnapshot(snapshot)); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "foo", *v.name; want != have {
+			t.Errorf("name: want %q, have %q", want, have)
+		}
+		if want, have := 9090, *v.port; want != have {
+			t.Errorf("port: want %d, have %d", want, have)
+		}
+		if want, have := true, *v.debug; want != have {
+			t.Errorf("debug: want %v, have %v", want, have)
+		}
+		if want, have := 5*time.Second, *v.timeout; want != have {
+			t.Errorf("timeout: want %s, have %s", want, have)
+		}
+		if want, have := []string{"a", "b"}, *v.tags; !reflect.DeepEqual(want, have) {
+			t.Errorf("tags: want %v, have %v", want, have)
+		}
+		reserialized, err := fs.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+		if want, have := string(snapshot), string(reserialized); want != have {
+			t.Errorf("snapshot: want %s, have %s", want, have)
+		}
+	})
+
+	t.Run("args and env win", func(t *testing.T) {
+		key := "TEST_SNAPSHOT_PORT"
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, "1234")
+
+		fs, v := newFlagSet()
+		if err := ff.Parse(fs, []string{"--name=bar"}, ff.WithSnapshot(snapshot), ff.WithEnvVarPrefix("TEST_SNAPSHOT")); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "bar", *v.name; want != have {
+			t.Errorf("name: want %q, have %q", want, have)
+		}
+		if want, have := 1234, *v.port; want != have {
+			t.Errorf("port: want %d, have %d", want, have)
+		}
+		if want, have := 5*time.Second, *v.timeout; want != have {
+			t.Errorf("timeout: want %s, have %s", want, have)
+		}
+	})
+
+	t.Run("undefined keys", func(t *testing.T) {
+		data := []byte(`{"name":"foo","bogus":1}`)
+
+		fs, _ := newFlagSet()
+		err := ff.Parse(fs, []string{}, ff.WithSnapshot(data))
+		if !errors.Is(err, ff.ErrUnknownFlag) {
+			t.Errorf("want ErrUnknownFlag, have %v", err)
+		}
+
+		fs, v := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithSnapshot(data), ff.WithConfigIgnoreUndefinedFlags()); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "foo", *v.name; want != have {
+			t.Errorf("name: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fs, _ := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithSnapshot([]byte(`["nope"]`))); err == nil {
+			t.Errorf("want error, have none")
+		}
+	})
+
+	t.Run("unset flags", func(t *testing.T) {
+		newFlagSet := func() (*ff.FlagSet, **bool, *[]ffval.Pair[string]) {
+			fs := ff.NewFlagSet(t.Name())
+			fs.StringLong("file", "", "read from file")
+			fs.StringLong("url", "", "read from URL")
+			if err := fs.MarkMutuallyExclusive("file", "url"); err != nil {
+				t.Fatalf("MarkMutuallyExclusive: %v", err)
+			}
+			return fs, fs.OptionalBoolLong("color", "colorize"), fs.StringOrderedMapLong("label", "labels")
+		}
+
+		src, _, _ := newFlagSet()
+		if err := ff.Parse(src, []string{"--url=http://x", "--label=z=1", "--label=a=2"}); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		snapshot, err := src.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+
+		fs, color, labels := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithSnapshot(snapshot)); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if *color != nil {
+			t.Errorf("color: want unset, have %v", **color)
+		}
+		if want, have := []ffval.Pair[string]{{Key: "z", Value: "1"}, {Key: "a", Value: "2"}}, *labels; !reflect.DeepEqual(want, have) {
+			t.Errorf("label: want %v, have %v", want, have)
+		}
+		for name, want := range map[string]bool{"file": false, "url": true, "color": false, "label": true} {
+			if f, _ := fs.GetFlag(name); f.IsSet() != want {
+				t.Errorf("%s: IsSet: want %v, have %v", name, want, f.IsSet())
+			}
+		}
+		reserialized, err := fs.MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON: %v", err)
+		}
+		if want, have := string(snapshot), string(reserialized); want != have {
+			t.Errorf("snapshot: want %s, have %s", want, have)
+		}
+	})
+}
+
+func TestParse_Deprecated(t *testing.T) {
+	t.Parallel()
+
+	newFlagSet := func() (*ff.FlagSet, *[]string) {
+		fs := ff.NewFlagSet(t.Name())
+		var tags []string
+		for _, cfg := range []ff.FlagConfig{
+			{LongName: "new", Value: new(ffval.String)},
+			{ShortName: 'o', LongName: "old", Value: new(ffval.String), Deprecated: "use --new"},
+			{ShortName: 't', Value: ffval.NewList(&tags), Deprecated: "tags are ignored"},
+		} {
+			if _, err := fs.AddFlag(cfg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fs, &tags
+	}
+
+	var buf bytes.Buffer
+	fs, tags := newFlagSet()
+	if err := ff.Parse(fs, []string{"-o", "x", "-t", "a", "-t", "b"}, ff.WithDeprecationWriter(&buf)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if want, have := "warning: flag --old is deprecated: use --new\nwarning: flag -t is deprecated: tags are ignored\n", buf.String(); want != have {
+		t.Errorf("warnings: want %q, have %q", want, have)
+	}
+	if f, _ := fs.GetFlag("old"); f.GetValue() != "x" {
+		t.Errorf("old: want %q, have %q", "x", f.GetValue())
+	}
+	if want, have := []string{"a", "b"}, *tags; !reflect.DeepEqual(want, have) {
+		t.Errorf("tags: want %v, have %v", want, have)
+	}
+
+	buf.Reset()
+	fs, _ = newFlagSet()
+	if err := ff.Parse(fs, []string{"--new=x"}, ff.WithDeprecationWriter(&buf)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("want no warnings, have %q", buf.String())
+	}
+
+	t.Run("env var", func(t *testing.T) {
+		key := "TEST_DEPRECATED_OLD"
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, "y")
+
+		var buf bytes.Buffer
+		fs, _ := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithEnvVarPrefix("TEST_DEPRECATED"), ff.WithDeprecationWriter(&buf)); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "warning: flag --old is deprecated: use --new\n", buf.String(); want != have {
+			t.Errorf("warnings: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("parent flags", func(t *testing.T) {
+		var buf bytes.Buffer
+		parent, _ := newFlagSet()
+		child := ff.NewFlagSet("child").SetParent(parent)
+		child.BoolLong("verbose", "verbose")
+		cmd := &ff.Command{
+			Name:        "parent",
+			Flags:       parent,
+			Subcommands: []*ff.Command{{Name: "child", Flags: child}},
+		}
+		if err := cmd.Parse([]string{"--old=x", "child", "--verbose"}, ff.WithDeprecationWriter(&buf)); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "warning: flag --old is deprecated: use --new\n", buf.String(); want != have {
+			t.Errorf("warnings: want %q, have %q", want, have)
+		}
+	})
+
+	t.Run("struct tag", func(t *testing.T) {
+		var cfg struct {
+			Old string `ff:"long=old, deprecated='use --new', hidden"`
+		}
+		fs := ff.NewFlagSet(t.Name())
+		if err := fs.AddStruct(&cfg); err != nil {
+			t.Fatalf("AddStruct: %v", err)
+		}
+		f, _ := fs.GetFlag("old")
+		if df, ok := f.(interface{ GetDeprecated() string }); !ok || df.GetDeprecated() != "use --new" {
+			t.Errorf("want deprecated message %q", "use --new")
+		}
+		if !f.IsHidden() {
+			t.Errorf("want hidden flag")
+		}
+	})
+}
+
+func TestParse_EnvSplit(t *testing.T) {
+	t.Parallel()
+
+	for key, val := range map[string]string{
+		"TEST_ENV_SPLIT_PATH": "/usr/bin:/bin\\:x",
+		"TEST_ENV_SPLIT_NAME": "a:b,c",
+	} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, val)
+	}
+
+	newFlagSet := func() (*ff.FlagSet, *[]string, *string) {
+		fs := ff.NewFlagSet(t.Name())
+		var path []string
+		if _, err := fs.AddFlag(ff.FlagConfig{LongName: "path", Value: ffval.NewList(&path), EnvSplit: ":"}); err != nil {
+			t.Fatal(err)
+		}
+		name := fs.StringLong("name", "", "name")
+		return fs, &path, name
+	}
+
+	for _, test := range []struct {
+		name    string
+		options []ff.Option
+		path    []string
+		nameVal string
+	}{
+		{
+			name:    "no global split",
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_SPLIT")},
+			path:    []string{"/usr/bin", "/bin:x"},
+			nameVal: "a:b,c",
+		},
+		{
+			name:    "flag split wins over global split",
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_SPLIT"), ff.WithEnvVarSplit(",")},
+			path:    []string{"/usr/bin", "/bin:x"},
+			nameVal: "c",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs, path, name := newFlagSet()
+			if err := ff.Parse(fs, []string{}, test.options...); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.path, *path; !reflect.DeepEqual(want, have) {
+				t.Errorf("path: want %q, have %q", want, have)
+			}
+			if want, have := test.nameVal, *name; want != have {
+				t.Errorf("name: want %q, have %q", want, have)
+			}
+		})
+	}
+}
+
+func TestParse_EnvVarAbsentValue(t *testing.T) {
+	t.Parallel()
+
+	defer os.Setenv("TEST_ENV_ABSENT_PRESENT", os.Getenv("TEST_ENV_ABSENT_PRESENT"))
+	os.Setenv("TEST_ENV_ABSENT_PRESENT", "true")
+	os.Unsetenv("TEST_ENV_ABSENT_FEATURE")
+
+	newFlagSet := func() (*ff.FlagSet, *bool, *bool, *bool) {
+		fs := ff.NewFlagSet(t.Name())
+		var feature, present, plain bool
+		for _, cfg := range []ff.FlagConfig{
+			{LongName: "feature", Value: ffval.NewValueDefault(&feature, true), EnvVarAbsentValue: "false"},
+			{LongName: "present", Value: ffval.NewValueDefault(&present, true), EnvVarAbsentValue: "false"},
+			{LongName: "plain", Value: ffval.NewValueDefault(&plain, true)},
+		} {
+			if _, err := fs.AddFlag(cfg); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return fs, &feature, &present, &plain
+	}
+
+	for _, test := range []struct {
+		name    string
+		args    []string
+		options []ff.Option
+		feature bool
+		present bool
+	}{
+		{
+			name:    "absent forces false",
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_ABSENT")},
+			feature: false,
+			present: true,
+		},
+		{
+			name:    "args take precedence",
+			args:    []string{"--feature"},
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_ABSENT")},
+			feature: true,
+			present: true,
+		},
+		{
+			name:    "config file takes precedence",
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_ABSENT"), ff.WithConfigFile("testdata/env_absent.conf"), ff.WithConfigFileParser(ff.PlainParser)},
+			feature: true,
+			present: true,
+		},
+		{
+			name:    "config file before env",
+			options: []ff.Option{ff.WithEnvVarPrefix("TEST_ENV_ABSENT"), ff.WithConfigFile("testdata/env_absent.conf"), ff.WithConfigFileParser(ff.PlainParser), ff.WithConfigBeforeEnv()},
+			feature: true,
+			present: true,
+		},
+		{
+			name:    "no env vars",
+			feature: true,
+			present: true,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fs, feature, present, plain := newFlagSet()
+			if err := ff.Parse(fs, test.args, test.options...); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if want, have := test.feature, *feature; want != have {
+				t.Errorf("feature: want %v, have %v", want, have)
+			}
+			if want, have := test.present, *present; want != have {
+				t.Errorf("present: want %v, have %v", want, have)
+			}
+			if want, have := true, *plain; want != have {
+				t.Errorf("plain: want %v, have %v", want, have)
+			}
+		})
+	}
+}
+
+func TestParse_DeprecatedValues(t *testing.T) {
+	t.Parallel()
+
+	newFlagSet := func() (*ff.FlagSet, *string) {
+		fs := ff.NewFlagSet(t.Name())
+		var mode string
+		if _, err := fs.AddFlag(ff.FlagConfig{
+			LongName:         "mode",
+			Value:            ffval.NewEnum(&mode, "modern", "legacy", "ancient"),
+			DeprecatedValues: map[string]string{"legacy": "use modern", "ancient": "use modern"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return fs, &mode
+	}
+
+	for _, test := range []struct {
+		args     []string
+		wantMode string
+		wantWarn string
+	}{
+		{[]string{"--mode=modern"}, "modern", ""},
+		{[]string{}, "modern", ""},
+		{[]string{"--mode=legacy"}, "legacy", "warning: flag --mode value \"legacy\" is deprecated: use modern\n"},
+	} {
+		var buf bytes.Buffer
+		fs, mode := newFlagSet()
+		if err := ff.Parse(fs, test.args, ff.WithDeprecationWriter(&buf)); err != nil {
+			t.Fatalf("%v: Parse: %v", test.args, err)
+		}
+		if want, have := test.wantMode, *mode; want != have {
+			t.Errorf("%v: mode: want %q, have %q", test.args, want, have)
+		}
+		if want, have := test.wantWarn, buf.String(); want != have {
+			t.Errorf("%v: warnings: want %q, have %q", test.args, want, have)
+		}
+	}
+
+	t.Run("config file", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "mode.conf")
+		if err := os.WriteFile(configFile, []byte("mode ancient\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		fs, _ := newFlagSet()
+		if err := ff.Parse(fs, []string{}, ff.WithConfigFile(configFile), ff.WithConfigFileParser(ff.PlainParser), ff.WithDeprecationWriter(&buf)); err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+		if want, have := "warning: flag --mode value \"ancient\" is deprecated: use modern\n", buf.String(); want != have {
+			t.Errorf("warnings: want %q, have %q", want, have)
+		}
+	})
 }
@@ -0,0 +1,192 @@
+package ff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	tparse "text/template/parse"
+)
+
+// renderFlagTemplates renders the values of string flags that contain
+// text/template actions, using the values of all flags as data. Templates may
+// reference other templated flags, so flags are rendered in dependency order,
+// and cycles produce an error.
+func renderFlagTemplates(fs Flags) error {
+	var (
+		data  = map[string]any{}
+		byKey = map[string]Flag{}
+		order []Flag
+	)
+	if err := fs.WalkFlags(func(f Flag) error {
+		for _, key := range getTemplateKeys(f) {
+			if _, ok := byKey[key]; ok {
+				continue
+			}
+			byKey[key] = f
+			data[key] = f.GetValue()
+		}
+		order = append(order, f)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	const (
+		visiting = 1
+		rendered = 2
+	)
+	state := map[Flag]int{}
+
+	var render func(f Flag) error
+	render = func(f Flag) error {
+		switch state[f] {
+		case visiting:
+			return newFlagError(f, fmt.Errorf("template cycle detected"))
+		case rendered:
+			return nil
+		}
+
+		value := f.GetValue()
+		if !isStringFlag(f) || !strings.Contains(value, "{{") {
+			state[f] = rendered
+			return nil
+		}
+
+		state[f] = visiting
+
+		tmpl, err := template.New(getNameString(f)).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return newFlagError(f, fmt.Errorf("parse template: %w", err))
+		}
+
+		refs := map[string]bool{}
+		walkTemplateRefs(tmpl.Tree.Root, refs)
+		for ref := range refs {
+			dep, ok := byKey[ref]
+			if !ok {
+				continue // caught by missingkey=error
+			}
+			if err := render(dep); err != nil {
+				return err
+			}
+		}
+
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, data); err != nil {
+			return newFlagError(f, fmt.Errorf("execute template: %w", err))
+		}
+
+		wasSet := f.IsSet()
+		if err := f.SetValue(sb.String()); err != nil {
+			return newFlagError(f, err)
+		}
+
+		// A rendered default is still a default, so the flag remains unset,
+		// and e.g. doesn't satisfy Required or conflict with other flags.
+		if cf, ok := f.(*coreFlag); ok && !wasSet {
+			cf.isSet = false
+		}
+
+		for _, key := range getTemplateKeys(f) {
+			if byKey[key] == f {
+				data[key] = f.GetValue()
+			}
+		}
+
+		state[f] = rendered
+		return nil
+	}
+
+	for _, f := range order {
+		if err := render(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getTemplateKeys returns the keys by which a flag can be referenced in a
+// template, which are the CamelCase forms of each flag name, e.g. DataDir for
+// data-dir, or V for v.
+func getTemplateKeys(f Flag) []string {
+	var keys []string
+	for _, name := range getNameStrings(f) {
+		keys = append(keys, getCamelCase(name))
+	}
+	return keys
+}
+
+func getCamelCase(name string) string {
+	var sb strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '-' || r == '_' || r == '.' || r == '/'
+	}) {
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return sb.String()
+}
+
+// isStringFlag returns true if the flag has an underlying string value, as
+// reported by the Get method of its flag value.
+func isStringFlag(f Flag) bool {
+	cf, ok := f.(*coreFlag)
+	if !ok {
+		return false
+	}
+
+	v, ok := callGet(cf.flagValue)
+	if !ok {
+		return false
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.String
+}
+
+func walkTemplateRefs(node tparse.Node, refs map[string]bool) {
+	switch n := node.(type) {
+	case *tparse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkTemplateRefs(c, refs)
+		}
+	case *tparse.ActionNode:
+		walkTemplateRefs(n.Pipe, refs)
+	case *tparse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			walkTemplateRefs(c, refs)
+		}
+	case *tparse.CommandNode:
+		for _, a := range n.Args {
+			walkTemplateRefs(a, refs)
+		}
+	case *tparse.ChainNode:
+		walkTemplateRefs(n.Node, refs)
+	case *tparse.FieldNode:
+		refs[n.Ident[0]] = true
+	case *tparse.IfNode:
+		walkTemplateRefs(n.Pipe, refs)
+		walkTemplateRefs(n.List, refs)
+		walkTemplateRefs(n.ElseList, refs)
+	case *tparse.RangeNode:
+		walkTemplateRefs(n.Pipe, refs)
+		walkTemplateRefs(n.List, refs)
+		walkTemplateRefs(n.ElseList, refs)
+	case *tparse.WithNode:
+		walkTemplateRefs(n.Pipe, refs)
+		walkTemplateRefs(n.List, refs)
+		walkTemplateRefs(n.ElseList, refs)
+	}
+}